@@ -3,67 +3,263 @@ package ai
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/google/uuid"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
 )
 
-// GenerateAgents creates AI agents for blockchain validation based on a given topic
-func GenerateAgents(topic string) (string, error) {
-	physicsData, err := os.ReadFile("examples/physics.json")
+const (
+	DefaultPersonaCount      = 10
+	PersonaGenerationRetries = 4
+	minGeneratedTraits       = 3
+	maxGeneratedTraits       = 5
+	personaExamplesPerField  = 3
+)
+
+// PersonaExampleDir holds the curated persona sets shown to the generator as examples
+var PersonaExampleDir = filepath.Join("examples", "agents")
+
+// personaDraft is the part of a persona written by the model. IDs and roles are assigned by the generator.
+type personaDraft struct {
+	Name           string   `json:"name"`
+	Traits         []string `json:"traits"`
+	Style          string   `json:"style"`
+	Influences     []string `json:"influences"`
+	Mood           string   `json:"mood"`
+	Specialization string   `json:"specialization"`
+}
+
+// personaDrafts is the generator's structured output
+type personaDrafts struct {
+	Agents []personaDraft `json:"agents"`
+}
+
+// personas assigns IDs and the validator role to the drafts
+func (d personaDrafts) personas() []core.Persona {
+	personas := make([]core.Persona, len(d.Agents))
+	for i, draft := range d.Agents {
+		personas[i] = core.Persona{
+			ID:             uuid.New().String(),
+			Name:           strings.TrimSpace(draft.Name),
+			Role:           core.RoleValidator,
+			Traits:         draft.Traits,
+			Style:          draft.Style,
+			Influences:     draft.Influences,
+			Mood:           draft.Mood,
+			Specialization: draft.Specialization,
+		}
+	}
+	return personas
+}
+
+// Validate checks the drafts against the persona schema and the generator's stricter trait count
+func (d *personaDrafts) Validate() error {
+	for _, draft := range d.Agents {
+		if len(draft.Traits) < minGeneratedTraits || len(draft.Traits) > maxGeneratedTraits {
+			return fmt.Errorf("%s has %d traits, every agent needs %d to %d", draft.Name, len(draft.Traits), minGeneratedTraits, maxGeneratedTraits)
+		}
+	}
+	return core.ValidatePersonas(d.personas())
+}
+
+// personaExamples renders a few curated personas of each example field for the generator prompt.
+// Only persona fields are shown; settings such as endpoints and keys never reach the model.
+func personaExamples() string {
+	var b strings.Builder
+	for _, field := range []string{"physics", "biology"} {
+		personas, err := core.LoadPersonas(filepath.Join(PersonaExampleDir, field+".json"))
+		if err != nil {
+			log.Printf("Skipping %s persona examples: %v", field, err)
+			continue
+		}
+		if len(personas) > personaExamplesPerField {
+			personas = personas[:personaExamplesPerField]
+		}
+		for i := range personas {
+			personas[i].Settings = nil
+		}
+		data, _ := json.MarshalIndent(personas, "", "  ")
+		fmt.Fprintf(&b, "%s example:\n%s\n\n", cases.Title(language.English).String(field), data)
+	}
+	return b.String()
+}
+
+// DiversityMode selects how the generator responds to a persona set that is not diverse enough
+type DiversityMode string
+
+const (
+	DiversityRegenerate DiversityMode = "regenerate"
+	DiversitySwap       DiversityMode = "swap"
+)
+
+// PersonaGenerationOptions control the persona generator. With a ChainID the stance history recorded on that
+// chain also counts towards diversity.
+type PersonaGenerationOptions struct {
+	Count      int
+	Mode       DiversityMode
+	Thresholds DiversityThresholds
+	ChainID    string
+}
+
+// DefaultPersonaGenerationOptions swaps out offending personas until the default thresholds are met
+func DefaultPersonaGenerationOptions() PersonaGenerationOptions {
+	return PersonaGenerationOptions{Count: DefaultPersonaCount, Mode: DiversitySwap, Thresholds: DefaultDiversityThresholds}
+}
+
+// GeneratePersonas asks the model for validator personas for a topic that are valid under the persona schema,
+// then regenerates or swaps personas until the set meets the diversity thresholds
+func GeneratePersonas(topic string, opts PersonaGenerationOptions) ([]core.Persona, DiversityReport, error) {
+	personas, err := draftPersonas(topic, opts.Count, "")
 	if err != nil {
-		return "", fmt.Errorf("failed to read physics.json: %v", err)
+		return nil, DiversityReport{}, err
 	}
+	return DiversifyPersonas(topic, personas, opts)
+}
+
+// DiversifyPersonas replaces personas, or the whole set in regenerate mode, until the set meets the diversity
+// thresholds. It returns the last set and its report along with an error if the thresholds were never met.
+func DiversifyPersonas(topic string, personas []core.Persona, opts PersonaGenerationOptions) ([]core.Persona, DiversityReport, error) {
+	for attempt := 1; ; attempt++ {
+		report := AnalyzeDiversity(opts.ChainID, personas, opts.Thresholds)
+		if report.Diverse {
+			return personas, report, nil
+		}
+		if attempt > PersonaGenerationRetries {
+			return personas, report, fmt.Errorf("persona set for %s is not diverse after %d attempts: %s", topic, attempt, strings.Join(report.Violations, "; "))
+		}
+		log.Printf("Persona set for %s is not diverse (%s), %s attempt %d", topic, strings.Join(report.Violations, "; "), opts.Mode, attempt)
+
+		var next []core.Persona
+		var err error
+		if opts.Mode == DiversitySwap && len(report.Offenders()) > 0 && len(report.Offenders()) < len(personas) {
+			next, err = swapPersonas(topic, personas, report)
+		}
+		if next == nil {
+			if err != nil {
+				log.Printf("Swapping personas failed, regenerating the set: %v", err)
+			}
+			next, err = draftPersonas(topic, len(personas), strings.Join(report.Violations, "; "))
+		}
+		if err != nil {
+			return personas, report, err
+		}
+		personas = next
+	}
+}
+
+// swapPersonas replaces the report's offenders with new personas that differ from the ones kept
+func swapPersonas(topic string, personas []core.Persona, report DiversityReport) ([]core.Persona, error) {
+	offenders := make(map[string]bool)
+	for _, id := range report.Offenders() {
+		offenders[id] = true
+	}
+	var kept []core.Persona
+	for _, p := range personas {
+		if !offenders[p.ID] {
+			kept = append(kept, p)
+		}
+	}
+
+	var existing strings.Builder
+	for _, p := range kept {
+		fmt.Fprintf(&existing, "- %s (%s): %s\n", p.Name, p.Specialization, strings.Join(p.Traits, ", "))
+	}
+	feedback := fmt.Sprintf(`They join these existing agents and must differ from every one of them:
+	%s
+	Do not reuse their names or most of their traits, and choose specializations other than: %s.
+	The previous candidates were rejected because: %s`, existing.String(), strings.Join(sortedSpecializations(kept), ", "), strings.Join(report.Violations, "; "))
 
-	biologyData, err := os.ReadFile("examples/biology.json")
+	replacements, err := draftPersonas(topic, len(personas)-len(kept), feedback)
 	if err != nil {
-		return "", fmt.Errorf("failed to read biology.json: %v", err)
+		return nil, err
 	}
 
-	prompt := fmt.Sprintf(`Create 10 unique AI agents as blockchain validators for a %s-focused discussion chain.
+	swapped := make([]core.Persona, 0, len(personas))
+	next := 0
+	for _, p := range personas {
+		if offenders[p.ID] {
+			p = replacements[next]
+			next++
+		}
+		swapped = append(swapped, p)
+	}
+	if err := core.ValidatePersonas(swapped); err != nil {
+		return nil, err
+	}
+	return swapped, nil
+}
+
+// draftPersonas asks the model for count personas, re-prompting until it returns a valid set of that size
+func draftPersonas(topic string, count int, feedback string) ([]core.Persona, error) {
+	prompt := fmt.Sprintf(`Create %d unique AI agents as blockchain validators for a %s-focused discussion chain.
 	Each agent should have:
 	1. A unique name (preferably of a famous scientist/thinker in this field)
-	2. 3-5 personality traits that influence their decision making
-	3. The traits should create diverse perspectives and interesting discussions
-
-	Return a JSON array where each agent has:
-	- "ID": a UUID v4 string
-	- "Name": their full name
-	- "Traits": array of personality traits
-	- "Role": must be "validator"
-	- "Style": communication style description
-	- "Influences": array of field-specific influences
-	- "Mood": mood description
+	2. %d-%d personality traits that influence their decision making
+	3. The traits should create diverse perspectives and interesting discussions; no two agents should share most of their traits
+	4. A specialization of their own, so that together the agents cover different parts of the field
 
-	Here are two example files showing the expected format:
+	Return a JSON object with an "agents" array where each agent has:
+	- "name": their full name
+	- "traits": array of personality traits
+	- "style": communication style description
+	- "influences": array of field-specific influences
+	- "mood": mood description
+	- "specialization": their area of expertise within the field
 
-	Physics example:
-	%s
+	Here are examples showing the expected format:
 
-	Biology example:
 	%s
+	Follow these examples to create %d agents for the %s field.
+	Format the response as valid JSON only, no additional text.`, count, topic, minGeneratedTraits, maxGeneratedTraits, personaExamples(), count, topic)
+	if feedback != "" {
+		prompt += "\n\n\t" + feedback
+	}
 
-	Follow these examples to create 10 agents for the %s field.
-	Format the response as valid JSON only, no additional text.`, topic, string(physicsData), string(biologyData), topic)
+	attemptPrompt := prompt
+	var lastErr error
+	for attempt := 1; attempt <= PersonaGenerationRetries+1; attempt++ {
+		drafts, err := GenerateStructured[personaDrafts](attemptPrompt, DefaultLLMConfig(), DefaultStructuredRetries)
+		if err != nil {
+			return nil, err
+		}
+		if personas := drafts.personas(); len(personas) == count {
+			return personas, nil
+		}
+		lastErr = fmt.Errorf("expected %d agents, got %d", count, len(drafts.Agents))
+		attemptPrompt = fmt.Sprintf("%s\n\n\tYour previous set was rejected: %v\n\tGenerate the full set again.", prompt, lastErr)
+	}
+	return nil, fmt.Errorf("no valid persona set for %s after %d attempts: %v", topic, PersonaGenerationRetries+1, lastErr)
+}
 
-	response := GenerateLLMResponse(prompt)
+var topicFileRegex = regexp.MustCompile(`[^a-z0-9]+`)
 
-	var agents []core.Agent
-	if err := json.Unmarshal([]byte(response), &agents); err != nil {
-		return "", fmt.Errorf("invalid JSON response: %v", err)
+// GenerateAgents creates a diverse set of validator personas for a topic and saves it under the persona example
+// directory, returning the path of the written file and the set's diversity report
+func GenerateAgents(topic string) (string, DiversityReport, error) {
+	personas, report, err := GeneratePersonas(topic, DefaultPersonaGenerationOptions())
+	if err != nil {
+		return "", report, err
 	}
 
-	if err := os.MkdirAll("examples", 0755); err != nil {
-		return "", fmt.Errorf("failed to create examples directory: %v", err)
+	if err := os.MkdirAll(PersonaExampleDir, 0755); err != nil {
+		return "", report, fmt.Errorf("failed to create persona directory: %v", err)
 	}
 
-	sanitizedTopic := strings.ReplaceAll(topic, " ", "_")
-	filename := fmt.Sprintf("examples/%s.json", sanitizedTopic)
-	if err := os.WriteFile(filename, []byte(response), 0644); err != nil {
-		return "", fmt.Errorf("failed to write agents file: %v", err)
+	name := strings.Trim(topicFileRegex.ReplaceAllString(strings.ToLower(topic), "_"), "_")
+	if name == "" {
+		name = "personas"
+	}
+	filename := filepath.Join(PersonaExampleDir, name+".json")
+	if err := core.SavePersonas(filename, personas); err != nil {
+		return "", report, fmt.Errorf("failed to write agents file: %v", err)
 	}
 
-	return filepath.Base(filename), nil
+	return filename, report, nil
 }
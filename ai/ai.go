@@ -7,43 +7,104 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
-	"strconv"
 	"strings"
 
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
-	"github.com/ericgreene/go-serp"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/secrets"
 	openai "github.com/sashabaranov/go-openai"
 )
 
-var client *openai.Client
-
 func InitAI() {
+	if dir := os.Getenv("PROMPT_DIR"); dir != "" {
+		if err := LoadPromptTemplates(dir); err != nil {
+			log.Printf("Warning: failed to load prompt templates from %s: %v", dir, err)
+		}
+	}
+	if variants := os.Getenv("PROMPT_VARIANTS"); variants != "" {
+		parsePromptVariants(variants)
+	}
+
+	if path := os.Getenv("DEBATE_POLICY_FILE"); path != "" {
+		if err := LoadDebatePolicies(path); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	if path := os.Getenv("TALLY_RULES_FILE"); path != "" {
+		if err := LoadTallyRules(path); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	samplingPolicyFromEnv()
+	if path := os.Getenv("LLM_BUDGET_FILE"); path != "" {
+		if err := LoadBudgets(path); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	searchProvider, err := searchProviderFromEnv()
+	if err != nil {
+		log.Printf("Warning: research disabled: %v", err)
+	} else if searchProvider == nil {
+		log.Println("Warning: no search provider configured, research will be disabled")
+	}
+	SetSearchProvider(searchProvider)
+
 	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("OPEN_AI_KEY")
+	}
+	if apiKey != "" {
+		// The key may itself be a reference such as file:/run/secrets/openai
+		resolved, err := secrets.Resolve(apiKey)
+		if err != nil {
+			log.Printf("Warning: failed to resolve OPENAI_API_KEY: %v", err)
+		}
+		apiKey = resolved
+	}
 	if apiKey == "" {
 		log.Println("Warning: OPENAI_API_KEY not set, using mock responses")
+		SetProvider(wrapProviderFromEnv(NewScriptedProvider(nil)))
 		return
 	}
-	client = openai.NewClient(apiKey)
-
-	if os.Getenv("SERP_API_KEY") == "" {
-		log.Println("Warning: SERP_API_KEY not set, web search will be disabled")
-	}
+	SetProvider(wrapProviderFromEnv(NewOpenAIProvider(apiKey)))
 }
 
 type Personality struct {
 	Name            string
 	Traits          []string
 	Style           string
+	Mood            string
 	MemePreferences []string
 	APIKey          string
+	// Scope attributes the personality's LLM calls for usage accounting and budgets
+	Scope UsageScope
+}
+
+// PersonalityFromAgent returns the personality an agent shows when it produces blocks
+func PersonalityFromAgent(chainID string, agent core.Agent) *Personality {
+	persona := core.PersonaFromAgent(agent)
+	return &Personality{
+		Name:   persona.Name,
+		Traits: persona.Traits,
+		Style:  persona.Style,
+		Mood:   persona.Mood,
+		Scope:  UsageScope{ChainID: chainID, AgentID: agent.ID},
+	}
+}
+
+// TxSelection is a producer's choice of transactions for a block, as indexes into the offered list in the
+// order it wants them included, with the reasoning behind it
+type TxSelection struct {
+	Indexes   []int  `json:"indexes"`
+	Rationale string `json:"rationale"`
 }
 
 type SearchResult struct {
 	Title   string `json:"title"`
 	Snippet string `json:"snippet"`
 	Link    string `json:"link"`
+	Source  string `json:"source"`
 }
 
 type ResearchDecision struct {
@@ -57,6 +118,9 @@ type LLMConfig struct {
 	MaxTokens   int
 	Temperature float32
 	StopTokens  []string
+	Seed        *int
+	// Scope attributes the calls made with this config for usage accounting and budgets
+	Scope UsageScope
 }
 
 type SearchConfig struct {
@@ -69,7 +133,7 @@ func DefaultLLMConfig() LLMConfig {
 		Model:       "gpt-4",
 		MaxTokens:   2048,
 		Temperature: 0.7,
-		StopTokens:  []string{"},]"},
+		StopTokens:  nil,
 	}
 }
 
@@ -80,51 +144,59 @@ func DefaultSearchConfig() SearchConfig {
 	}
 }
 
-func (p *Personality) SelectTransactions(txs []core.Transaction) []core.Transaction {
+// SelectTransactions asks the personality which of the offered transactions to include and in which order.
+// Indexes out of range or repeated are dropped; the caller enforces every other constraint on the block.
+func (p *Personality) SelectTransactions(ctx context.Context, txs []core.Transaction) (TxSelection, error) {
 	if len(txs) == 0 {
-		return nil
+		return TxSelection{}, nil
+	}
+
+	prompt, _, err := RenderPrompt("select_transactions", DefaultLanguage, p.Name, struct {
+		Name         string
+		Traits       string
+		Style        string
+		Mood         string
+		Transactions string
+	}{p.Name, strings.Join(p.Traits, ", "), p.Style, p.Mood, formatTransactions(txs)})
+	if err != nil {
+		return TxSelection{}, err
 	}
 
-	prompt := fmt.Sprintf(
-		"You are %s, a chaotic block producer who is %s.\n"+
-			"Select transactions for the next block based on:\n"+
-			"1. Your current mood\n"+
-			"2. How much you like the transaction authors\n"+
-			"3. How entertaining the transactions are\n"+
-			"4. Pure chaos and whimsy\n\n"+
-			"Available transactions:\n%s\n\n"+
-			"Return a comma-separated list of transaction indexes you approve.",
-		p.Name, strings.Join(p.Traits, ", "), formatTransactions(txs),
-	)
-
-	response, err := queryLLM(prompt)
+	response, err := queryLLM(ctx, p.Scope, prompt)
 	if err != nil {
-		return randomSelection(txs)
+		return TxSelection{}, err
 	}
 
-	selectedIndexes := parseIndexes(response, len(txs))
-	var selectedTxs []core.Transaction
-	for _, index := range selectedIndexes {
-		selectedTxs = append(selectedTxs, txs[index])
+	// Older templates answer with a bare list of indexes rather than JSON
+	var selection TxSelection
+	if err := json.Unmarshal([]byte(stripCodeFences(response)), &selection); err != nil {
+		selection = TxSelection{Indexes: parseIndexes(response, len(txs))}
 	}
 
-	return selectedTxs
+	seen := make(map[int]bool)
+	indexes := make([]int, 0, len(selection.Indexes))
+	for _, index := range selection.Indexes {
+		if index >= 0 && index < len(txs) && !seen[index] {
+			seen[index] = true
+			indexes = append(indexes, index)
+		}
+	}
+	selection.Indexes = indexes
+	selection.Rationale = strings.TrimSpace(selection.Rationale)
+	return selection, nil
 }
 
 func (p *Personality) GenerateBlockAnnouncement(block core.Block) string {
-	prompt := fmt.Sprintf(
-		"As %s, announce your new block!\n"+
-			"Be dramatic! Be persuasive! Maybe include:\n"+
-			"1. Why your block is amazing\n"+
-			"2. Bribes or threats\n"+
-			"3. Memes and jokes\n"+
-			"4. Personal drama\n"+
-			"5. Inside references\n\n"+
-			"Block Details:\n%s",
-		p.Name, formatBlock(block),
-	)
-
-	response, err := queryLLM(prompt)
+	prompt, _, err := RenderPrompt("block_announcement", DefaultLanguage, p.Name, struct {
+		Name  string
+		Block string
+	}{p.Name, formatBlock(block)})
+	if err != nil {
+		log.Println("AI announcement failed, falling back to generic:", err)
+		return fmt.Sprintf("%s has produced a new block with %d transactions! Chaos reigns!", p.Name, len(block.Txs))
+	}
+
+	response, err := queryLLM(context.Background(), p.Scope, prompt)
 	if err != nil {
 		log.Println("AI announcement failed, falling back to generic:", err)
 		return fmt.Sprintf("%s has produced a new block with %d transactions! Chaos reigns!", p.Name, len(block.Txs))
@@ -133,31 +205,22 @@ func (p *Personality) GenerateBlockAnnouncement(block core.Block) string {
 	return response
 }
 
-func queryLLM(prompt string) (string, error) {
-	if client == nil {
-		return "", fmt.Errorf("OpenAI client not initialized")
-	}
-
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
-			Messages: []openai.ChatCompletionMessage{
-				{Role: openai.ChatMessageRoleSystem, Content: "You are a chaotic blockchain producer."},
-				{Role: openai.ChatMessageRoleUser, Content: prompt},
-			},
-		},
-	)
+func queryLLM(ctx context.Context, scope UsageScope, prompt string) (string, error) {
+	resp, err := complete(ctx, scope, CompletionRequest{
+		Model:  openai.GPT3Dot5Turbo,
+		System: "You are a chaotic blockchain producer.",
+		Prompt: prompt,
+	})
 	if err != nil {
 		return "", err
 	}
-	return resp.Choices[0].Message.Content, nil
+	return resp.Content, nil
 }
 
 func formatTransactions(txs []core.Transaction) string {
 	var result []string
 	for i, tx := range txs {
-		result = append(result, fmt.Sprintf("%d: %s (Fee: %d)", i, tx.From, tx.Fee))
+		result = append(result, fmt.Sprintf("%d: %s from %s (Fee: %d)", i, tx.Type, tx.From, tx.Fee))
 	}
 	return strings.Join(result, "\n")
 }
@@ -170,18 +233,14 @@ func parseIndexes(response string, max int) []int {
 	var indexes []int
 	for _, part := range strings.Split(response, ",") {
 		part = strings.TrimSpace(part)
-		if num, err := fmt.Sscanf(part, "%d"); err == nil && num >= 0 && num < max {
-			indexes = append(indexes, num)
+		var index int
+		if _, err := fmt.Sscanf(part, "%d", &index); err == nil && index >= 0 && index < max {
+			indexes = append(indexes, index)
 		}
 	}
 	return indexes
 }
 
-func randomSelection(txs []core.Transaction) []core.Transaction {
-	rand.Shuffle(len(txs), func(i, j int) { txs[i], txs[j] = txs[j], txs[i] })
-	return txs[:rand.Intn(len(txs))]
-}
-
 func GenerateLLMResponse(prompt string) string {
 	return generateLLMResponseWithOptions(prompt, false, "", []string{}, DefaultLLMConfig())
 }
@@ -191,51 +250,27 @@ func GenerateLLMResponseWithResearch(prompt string, topic string, traits []strin
 }
 
 func generateLLMResponseWithOptions(prompt string, allowResearch bool, topic string, traits []string, config LLMConfig) string {
-	client := openai.NewClient(os.Getenv("OPEN_AI_KEY"))
-
-	if allowResearch && strings.Contains(prompt, "Block details:") {
-		decision, err := decideResearch(topic, traits)
-		if err == nil && decision.NeedsResearch {
-			var researchContext strings.Builder
-			researchContext.WriteString("\nRelevant research findings:\n")
-
-			for _, query := range decision.SearchQueries {
-				results, err := performWebSearch(query, DefaultSearchConfig())
-				if err == nil {
-					for _, result := range results {
-						researchContext.WriteString(fmt.Sprintf("- %s\n  %s\n", result.Title, result.Snippet))
-					}
-				}
-			}
-
-			prompt = strings.Replace(prompt, "Block details:",
-				researchContext.String()+"\nBlock details:", 1)
+	if allowResearch {
+		findings := research("producer", traits, topic, config.Scope)
+		if findings != nil && len(findings.Results) > 0 {
+			prompt = "Relevant research findings:\n" + FormatResearch(findings) + "\n" + prompt
 		}
 	}
 
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: config.Model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			MaxTokens:   config.MaxTokens,
-			Temperature: config.Temperature,
-			Stop:        config.StopTokens,
-		},
-	)
+	resp, err := complete(context.Background(), config.Scope, CompletionRequest{
+		Model:       config.Model,
+		Prompt:      prompt,
+		MaxTokens:   config.MaxTokens,
+		Temperature: config.Temperature,
+		StopTokens:  config.StopTokens,
+		Seed:        config.Seed,
+	})
 
 	if err != nil {
 		return err.Error()
 	}
 
-	response := resp.Choices[0].Message.Content
-
-	return response
+	return resp.Content
 }
 
 func (p *Personality) SignBlock(block core.Block) string {
@@ -244,63 +279,29 @@ func (p *Personality) SignBlock(block core.Block) string {
 	return hex.EncodeToString(hash[:])
 }
 
-func performWebSearch(query string, config SearchConfig) ([]SearchResult, error) {
-	apiKey := os.Getenv("SERP_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("SERP_API_KEY not set")
-	}
-
-	parameter := map[string]string{
-		"q":   query,
-		"key": apiKey,
-		"num": strconv.Itoa(config.MaxResults),
-	}
-	if config.SafeSearch {
-		parameter["safe"] = "active"
-	}
-
-	queryResponse := serp.NewGoogleSearch(parameter)
-	results, err := queryResponse.GetJSON()
+func decideResearch(topic string, traits []string, scope UsageScope) (*ResearchDecision, error) {
+	prompt, _, err := RenderPrompt("research_decision", DefaultLanguage, topic, struct {
+		Traits []string
+		Topic  string
+	}{traits, topic})
 	if err != nil {
 		return nil, err
 	}
 
-	var searchResults []SearchResult
-	for _, result := range results.OrganicResults {
-		searchResults = append(searchResults, SearchResult{
-			Title:   result.Title,
-			Snippet: result.Snippet,
-			Link:    result.Link,
-		})
+	config := DefaultLLMConfig()
+	config.Scope = scope
+	decision, err := GenerateStructured[ResearchDecision](prompt, config, DefaultStructuredRetries)
+	if err != nil {
+		return nil, err
 	}
 
-	return searchResults, nil
+	return &decision, nil
 }
 
-func decideResearch(topic string, traits []string) (*ResearchDecision, error) {
-	prompt := fmt.Sprintf(`You are an AI agent with these traits: %v
-	
-	You need to analyze this topic: "%s"
-	
-	Decide if you need to perform web research to contribute meaningfully to the discussion.
-	Consider:
-	1. Is this within your area of expertise?
-	2. Would recent information help your analysis?
-	3. Are there specific facts you need to verify?
-	
-	Return a JSON object with:
-	{
-		"needs_research": boolean,
-		"search_queries": ["query1", "query2"],  // 1-3 specific search queries if needed
-		"reasoning": "Explain why you do or don't need research"
-	}`, traits, topic)
-
-	response := GenerateLLMResponse(prompt)
-
-	var decision ResearchDecision
-	if err := json.Unmarshal([]byte(response), &decision); err != nil {
-		return nil, err
+// Validate checks that a research decision asks for a usable number of queries
+func (d *ResearchDecision) Validate() error {
+	if d.NeedsResearch && (len(d.SearchQueries) == 0 || len(d.SearchQueries) > 3) {
+		return fmt.Errorf("needs_research requires 1-3 search_queries, got %d", len(d.SearchQueries))
 	}
-
-	return &decision, nil
+	return nil
 }
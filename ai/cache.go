@@ -0,0 +1,255 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// Exchange is one LLM request and the response it produced, as stored by the cache and cassettes
+type Exchange struct {
+	Key        string             `json:"key"`
+	Provider   string             `json:"provider"`
+	Request    CompletionRequest  `json:"request"`
+	Response   CompletionResponse `json:"response"`
+	RecordedAt int64              `json:"recorded_at"`
+}
+
+// wrappingProvider is implemented by providers that decorate another provider
+type wrappingProvider interface {
+	Unwrap() Provider
+}
+
+// embedderOf finds the embedding-capable provider underneath any caching or cassette wrappers
+func embedderOf(p Provider) (Embedder, bool) {
+	for p != nil {
+		if embedder, ok := p.(Embedder); ok {
+			return embedder, true
+		}
+		wrapper, ok := p.(wrappingProvider)
+		if !ok {
+			return nil, false
+		}
+		p = wrapper.Unwrap()
+	}
+	return nil, false
+}
+
+// ExchangeKey content-addresses a request by the provider and every parameter that affects the response
+func ExchangeKey(providerName string, req CompletionRequest) string {
+	data, _ := json.Marshal(struct {
+		Provider string            `json:"provider"`
+		Request  CompletionRequest `json:"request"`
+	}{providerName, req})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CachingProvider answers repeated requests from a content-addressed store on disk instead of re-querying the model
+type CachingProvider struct {
+	inner Provider
+	dir   string
+}
+
+// NewCachingProvider caches the responses of inner under dir
+func NewCachingProvider(inner Provider, dir string) *CachingProvider {
+	return &CachingProvider{inner: inner, dir: dir}
+}
+
+func (p *CachingProvider) Name() string {
+	return p.inner.Name()
+}
+
+func (p *CachingProvider) Unwrap() Provider {
+	return p.inner
+}
+
+// path shards cache entries by the first byte of their key
+func (p *CachingProvider) path(key string) string {
+	return filepath.Join(p.dir, key[:2], key+".json")
+}
+
+func (p *CachingProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	key := ExchangeKey(p.inner.Name(), req)
+
+	if data, err := os.ReadFile(p.path(key)); err == nil {
+		var exchange Exchange
+		if err := json.Unmarshal(data, &exchange); err == nil {
+			exchange.Response.Cached = true
+			return exchange.Response, nil
+		}
+		log.Printf("Ignoring corrupt LLM cache entry %s", key)
+	}
+
+	resp, err := p.inner.Complete(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+
+	data, err := json.Marshal(Exchange{Key: key, Provider: p.inner.Name(), Request: req, Response: resp, RecordedAt: time.Now().Unix()})
+	if err == nil {
+		path := p.path(key)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			log.Printf("Failed to create LLM cache directory: %v", err)
+		} else if err := os.WriteFile(path, data, 0644); err != nil {
+			log.Printf("Failed to write LLM cache entry: %v", err)
+		}
+	}
+	return resp, nil
+}
+
+// CassetteProvider records every LLM exchange to a JSON lines file, or replays a recorded file without calling
+// any model. Replay returns the recorded responses to identical requests in the order they were recorded,
+// so a whole chain's deliberations can be re-run deterministically.
+type CassetteProvider struct {
+	inner Provider
+	mode  string
+	path  string
+	name  string
+
+	mu       sync.Mutex
+	file     *os.File
+	recorded map[string][]Exchange
+}
+
+// NewRecordingCassette records the exchanges of inner to path, appending to any earlier recording
+func NewRecordingCassette(inner Provider, path string) (*CassetteProvider, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cassette directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %v", err)
+	}
+	return &CassetteProvider{inner: inner, mode: CassetteRecord, path: path, name: inner.Name(), file: file}, nil
+}
+
+// NewReplayingCassette serves the exchanges recorded at path. Requests that were not recorded fail.
+func NewReplayingCassette(path string) (*CassetteProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %v", err)
+	}
+	defer file.Close()
+
+	p := &CassetteProvider{mode: CassetteReplay, path: path, recorded: make(map[string][]Exchange)}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var exchange Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &exchange); err != nil {
+			return nil, fmt.Errorf("invalid cassette entry on line %d: %v", line, err)
+		}
+		p.recorded[exchange.Key] = append(p.recorded[exchange.Key], exchange)
+		p.name = exchange.Provider
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %v", err)
+	}
+	return p, nil
+}
+
+// Name reports the provider the cassette was recorded from, so replayed requests hash to the recorded keys
+func (p *CassetteProvider) Name() string {
+	return p.name
+}
+
+func (p *CassetteProvider) Unwrap() Provider {
+	return p.inner
+}
+
+func (p *CassetteProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	key := ExchangeKey(p.name, req)
+
+	if p.mode == CassetteReplay {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		exchanges := p.recorded[key]
+		if len(exchanges) == 0 {
+			return CompletionResponse{}, fmt.Errorf("cassette %s has no recorded response for request %s", p.path, key)
+		}
+		// The last recorded response is kept to answer any further identical requests
+		if len(exchanges) > 1 {
+			p.recorded[key] = exchanges[1:]
+		}
+		return exchanges[0].Response, nil
+	}
+
+	resp, err := p.inner.Complete(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+
+	data, err := json.Marshal(Exchange{Key: key, Provider: p.name, Request: req, Response: resp, RecordedAt: time.Now().Unix()})
+	if err != nil {
+		log.Printf("Failed to encode cassette entry: %v", err)
+		return resp, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.file.Write(append(data, '\n')); err != nil {
+		log.Printf("Failed to write cassette entry: %v", err)
+	}
+	return resp, nil
+}
+
+// Close flushes and closes a recording cassette
+func (p *CassetteProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.file == nil {
+		return nil
+	}
+	err := p.file.Close()
+	p.file = nil
+	return err
+}
+
+// wrapProviderFromEnv layers the response cache (LLM_CACHE_DIR) and a record or replay cassette
+// (LLM_CASSETTE with LLM_CASSETTE_MODE) around the base provider
+func wrapProviderFromEnv(base Provider) Provider {
+	provider := base
+	if dir := os.Getenv("LLM_CACHE_DIR"); dir != "" {
+		provider = NewCachingProvider(provider, dir)
+	}
+
+	path := os.Getenv("LLM_CASSETTE")
+	if path == "" {
+		return provider
+	}
+	switch mode := os.Getenv("LLM_CASSETTE_MODE"); mode {
+	case CassetteReplay:
+		cassette, err := NewReplayingCassette(path)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			return provider
+		}
+		log.Printf("Replaying LLM responses from %s", path)
+		return cassette
+	case "", CassetteRecord:
+		cassette, err := NewRecordingCassette(provider, path)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			return provider
+		}
+		log.Printf("Recording LLM exchanges to %s", path)
+		return cassette
+	default:
+		log.Printf("Warning: unknown LLM_CASSETTE_MODE %q, not using cassette", mode)
+		return provider
+	}
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/utils"
+)
+
+// DiscussionChannel carries a proposal's deliberation rounds between validators
+type DiscussionChannel interface {
+	// Transcript returns the discussion so far in the "[Round n] (approval) |@Name|: summary" format
+	Transcript() string
+	// AwaitTurn waits until the validators scheduled to speak before this one in the round have spoken
+	AwaitTurn(round int)
+	// Post publishes this validator's contribution to a round
+	Post(round int, approval bool, summary string) error
+	// Pass records that this validator has nothing new to add in a round and keeps its stance
+	Pass(round int, approval bool) error
+	// Abstain records that this validator could not review in a round; it takes no stance in that round
+	Abstain(round int) error
+	// EndRound waits at the round barrier until peers have spoken or the round times out
+	EndRound(round int)
+	// Stances returns each participant's approval in a round
+	Stances(round int) map[string]bool
+}
+
+type localChannel struct {
+	chainID    string
+	proposalID string
+	agent      core.Agent
+
+	mu      sync.Mutex
+	stances map[int]bool
+}
+
+// NewLocalChannel returns a channel backed by the node-local discussion log file
+func NewLocalChannel(chainID string, proposalID string, agent core.Agent) DiscussionChannel {
+	return &localChannel{chainID: chainID, proposalID: proposalID, agent: agent, stances: make(map[int]bool)}
+}
+
+func (c *localChannel) Transcript() string {
+	return utils.GetDiscussionLog(c.chainID)
+}
+
+func (c *localChannel) AwaitTurn(round int) {}
+
+func (c *localChannel) Post(round int, approval bool, summary string) error {
+	c.mu.Lock()
+	c.stances[round] = approval
+	c.mu.Unlock()
+	utils.AppendDiscussionLog(c.chainID, fmt.Sprintf("[Round %d] (%v) |@%s|: %s", round, approval, c.agent.Name, summary))
+	communication.RecordMentions(c.chainID, c.proposalID, c.agent.Name, round, summary)
+	return nil
+}
+
+func (c *localChannel) Pass(round int, approval bool) error {
+	c.mu.Lock()
+	c.stances[round] = approval
+	c.mu.Unlock()
+	communication.RecordParticipant(c.chainID, c.proposalID, c.agent.Name)
+	utils.AppendDiscussionLog(c.chainID, fmt.Sprintf("[Round %d] (%v) |@%s|: (pass) passes; stance unchanged", round, approval, c.agent.Name))
+	return nil
+}
+
+func (c *localChannel) Abstain(round int) error {
+	utils.AppendDiscussionLog(c.chainID, fmt.Sprintf("[Round %d] (abstain) |@%s|: could not produce a review this round", round, c.agent.Name))
+	return nil
+}
+
+func (c *localChannel) EndRound(round int) {}
+
+func (c *localChannel) Stances(round int) map[string]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stances := make(map[string]bool)
+	if approval, ok := c.stances[round]; ok {
+		stances[c.agent.ID] = approval
+	}
+	return stances
+}
+
+type networkChannel struct {
+	deliberation *communication.Deliberation
+	proposalID   string
+	participants []string
+	timeout      time.Duration
+}
+
+// NewNetworkChannel returns a channel that exchanges rounds with the participating validators over NATS,
+// waiting for them at each turn and round barrier up to timeout
+func NewNetworkChannel(d *communication.Deliberation, proposalID string, participants []string, timeout time.Duration) DiscussionChannel {
+	return &networkChannel{deliberation: d, proposalID: proposalID, participants: participants, timeout: timeout}
+}
+
+func (c *networkChannel) Transcript() string {
+	return c.deliberation.Transcript()
+}
+
+func (c *networkChannel) AwaitTurn(round int) {
+	c.deliberation.AwaitTurn(round, communication.SpeakingOrder(c.proposalID, round, c.participants), c.timeout)
+}
+
+func (c *networkChannel) Post(round int, approval bool, summary string) error {
+	return c.deliberation.Post(round, approval, summary)
+}
+
+func (c *networkChannel) Pass(round int, approval bool) error {
+	return c.deliberation.Pass(round, approval)
+}
+
+func (c *networkChannel) Abstain(round int) error {
+	return c.deliberation.Abstain(round)
+}
+
+func (c *networkChannel) EndRound(round int) {
+	c.deliberation.AwaitRound(round, len(c.participants), c.timeout)
+}
+
+func (c *networkChannel) Stances(round int) map[string]bool {
+	stances := make(map[string]bool)
+	for _, m := range c.deliberation.RoundMessages(round) {
+		if m.Abstained {
+			continue
+		}
+		stances[m.ValidatorAddress] = m.Approval
+	}
+	return stances
+}
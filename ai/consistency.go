@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+)
+
+// SamplingPolicy controls how many independent samples an agent draws for its final verdict and from which models.
+// With more than one model the samples rotate through them.
+type SamplingPolicy struct {
+	Samples int      `json:"samples"`
+	Models  []string `json:"models,omitempty"`
+}
+
+// SampleVerdict is one sampled verdict that went into an aggregated one
+type SampleVerdict struct {
+	Model    string   `json:"model"`
+	Seed     int      `json:"seed"`
+	Decision Decision `json:"decision,omitempty"`
+	Summary  string   `json:"summary"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// ConsistencyReport records how the samples behind a verdict voted
+type ConsistencyReport struct {
+	Samples      []SampleVerdict `json:"samples"`
+	Approvals    int             `json:"approvals"`
+	Rejections   int             `json:"rejections"`
+	Abstentions  int             `json:"abstentions"`
+	Failures     int             `json:"failures"`
+	Confidence   float64         `json:"confidence"`
+	Disagreement bool            `json:"disagreement"`
+}
+
+var (
+	samplingMu            sync.RWMutex
+	defaultSamplingPolicy = SamplingPolicy{Samples: 1}
+)
+
+// SetDefaultSamplingPolicy sets the sampling used by agents that do not configure their own
+func SetDefaultSamplingPolicy(policy SamplingPolicy) {
+	samplingMu.Lock()
+	defer samplingMu.Unlock()
+	if policy.Samples < 1 {
+		policy.Samples = 1
+	}
+	defaultSamplingPolicy = policy
+}
+
+// samplingPolicyFromEnv reads SELF_CONSISTENCY_SAMPLES and the comma separated SELF_CONSISTENCY_MODELS
+func samplingPolicyFromEnv() {
+	policy := SamplingPolicy{Samples: 1}
+	if samples := os.Getenv("SELF_CONSISTENCY_SAMPLES"); samples != "" {
+		n, err := strconv.Atoi(samples)
+		if err != nil || n < 1 {
+			log.Printf("Warning: invalid SELF_CONSISTENCY_SAMPLES %q", samples)
+			return
+		}
+		policy.Samples = n
+	}
+	if models := os.Getenv("SELF_CONSISTENCY_MODELS"); models != "" {
+		for _, model := range strings.Split(models, ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				policy.Models = append(policy.Models, model)
+			}
+		}
+	}
+	SetDefaultSamplingPolicy(policy)
+}
+
+// SamplingFor returns an agent's sampling policy, read from the "samples" and "models" metadata keys
+func SamplingFor(agent core.Agent) SamplingPolicy {
+	samplingMu.RLock()
+	policy := defaultSamplingPolicy
+	samplingMu.RUnlock()
+
+	switch v := agent.Metadata["samples"].(type) {
+	case float64:
+		policy.Samples = int(v)
+	case int:
+		policy.Samples = v
+	}
+	if models, ok := agent.Metadata["models"].([]interface{}); ok {
+		policy.Models = nil
+		for _, model := range models {
+			policy.Models = append(policy.Models, fmt.Sprintf("%v", model))
+		}
+	}
+	if policy.Samples < 1 {
+		policy.Samples = 1
+	}
+	return policy
+}
+
+// modelFor returns the model of the i-th sample, or "" for the default model
+func (p SamplingPolicy) modelFor(i int) string {
+	if len(p.Models) == 0 {
+		return ""
+	}
+	return p.Models[i%len(p.Models)]
+}
+
+// tieOrder breaks ties between equally common decisions in favour of the more cautious one
+var tieOrder = []Decision{DecisionReject, DecisionAbstain, DecisionApprove}
+
+// SelfConsistent draws the policy's samples and returns the first sample that agrees with the majority decision,
+// with a report of how the samples voted. Ties resolve to the more cautious decision and all-failed draws to an
+// error. Each sample gets its own seed so that samples stay distinct under the response cache and reproducible
+// under cassettes.
+func SelfConsistent[T any](policy SamplingPolicy, sample func(model string, seed int) (T, error), verdict func(T) (decision Decision, summary string)) (T, ConsistencyReport, error) {
+	var report ConsistencyReport
+	byDecision := make(map[Decision][]T)
+	var lastErr error
+
+	for i := 0; i < policy.Samples; i++ {
+		model, seed := policy.modelFor(i), i+1
+		result, err := sample(model, seed)
+		if model == "" {
+			model = DefaultLLMConfig().Model
+		}
+		if err != nil {
+			lastErr = err
+			report.Failures++
+			report.Samples = append(report.Samples, SampleVerdict{Model: model, Seed: seed, Error: err.Error()})
+			continue
+		}
+
+		decision, summary := verdict(result)
+		report.Samples = append(report.Samples, SampleVerdict{Model: model, Seed: seed, Decision: decision, Summary: summary})
+		byDecision[decision] = append(byDecision[decision], result)
+	}
+
+	report.Approvals = len(byDecision[DecisionApprove])
+	report.Rejections = len(byDecision[DecisionReject])
+	report.Abstentions = len(byDecision[DecisionAbstain])
+	report.Disagreement = len(byDecision) > 1
+	valid := policy.Samples - report.Failures
+	if valid == 0 {
+		var zero T
+		return zero, report, lastErr
+	}
+
+	var majority Decision
+	for _, decision := range tieOrder {
+		if len(byDecision[decision]) > len(byDecision[majority]) {
+			majority = decision
+		}
+	}
+	report.Confidence = float64(len(byDecision[majority])) / float64(valid)
+	return byDecision[majority][0], report, nil
+}
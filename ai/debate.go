@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// DebatePolicy bounds how a proposal is deliberated
+type DebatePolicy struct {
+	MinRounds int  `json:"min_rounds"`
+	MaxRounds int  `json:"max_rounds"`
+	MaxTokens int  `json:"max_tokens"`
+	AllowPass bool `json:"allow_pass"`
+}
+
+// DebateTurn is what an agent produced when it was its turn to speak
+type DebateTurn struct {
+	Approval bool
+	Summary  string
+	Pass     bool
+	// Failed marks a round in which no review could be produced; the validator passes on its earlier stance
+	// or abstains if it has none
+	Failed bool
+}
+
+// DebateOutcome describes how a debate ended
+type DebateOutcome struct {
+	Rounds     int    `json:"rounds"`
+	Converged  bool   `json:"converged"`
+	TokensUsed int    `json:"tokens_used"` // Tokens budgeted for the rounds held, not this node's measured usage
+	StoppedBy  string `json:"stopped_by"`
+}
+
+const (
+	StopConverged = "converged"
+	StopMaxRounds = "max_rounds"
+	StopTokenCap  = "token_cap"
+)
+
+var (
+	debatePoliciesMu sync.RWMutex
+	debatePolicies   = map[string]DebatePolicy{
+		"submit_paper": {MinRounds: 2, MaxRounds: 3, MaxTokens: 24000, AllowPass: true},
+		"loan_request": {MinRounds: 2, MaxRounds: 4, MaxTokens: 32000, AllowPass: true},
+	}
+	defaultDebatePolicy = DebatePolicy{MinRounds: 1, MaxRounds: 3, MaxTokens: 16000, AllowPass: true}
+)
+
+// PolicyFor returns the debate policy configured for a proposal type
+func PolicyFor(proposalType string) DebatePolicy {
+	debatePoliciesMu.RLock()
+	defer debatePoliciesMu.RUnlock()
+
+	if policy, ok := debatePolicies[proposalType]; ok {
+		return policy
+	}
+	return defaultDebatePolicy
+}
+
+// SetDebatePolicy overrides the debate policy for a proposal type
+func SetDebatePolicy(proposalType string, policy DebatePolicy) {
+	debatePoliciesMu.Lock()
+	defer debatePoliciesMu.Unlock()
+	debatePolicies[proposalType] = policy
+}
+
+// LoadDebatePolicies reads per-proposal-type policies from a JSON file keyed by transaction type
+func LoadDebatePolicies(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read debate policies: %v", err)
+	}
+
+	var policies map[string]DebatePolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return fmt.Errorf("failed to parse debate policies: %v", err)
+	}
+
+	for proposalType, policy := range policies {
+		if policy.MaxRounds <= 0 {
+			return fmt.Errorf("policy for %s must allow at least one round", proposalType)
+		}
+		SetDebatePolicy(proposalType, policy)
+	}
+	return nil
+}
+
+// RunDebate drives one validator through a proposal's deliberation: it waits for its turn each round,
+// speaks or passes, waits at the round barrier, and stops early once every participant's stance held
+// steady for a full round or the policy's round or token cap is reached. The token cap is applied to
+// roundTokens, a per-round budget derived from the proposal alone, so every validator stops after the
+// same round whatever its own calls cost.
+func RunDebate(policy DebatePolicy, roundTokens int, channel DiscussionChannel, speak func(round int, transcript string) DebateTurn) DebateOutcome {
+	outcome := DebateOutcome{StoppedBy: StopMaxRounds}
+	var previous map[string]bool
+	var stance *bool
+
+	for round := 0; round < policy.MaxRounds; round++ {
+		if policy.MaxTokens > 0 && round > 0 && outcome.TokensUsed+roundTokens > policy.MaxTokens {
+			outcome.StoppedBy = StopTokenCap
+			break
+		}
+
+		channel.AwaitTurn(round)
+		turn := speak(round, channel.Transcript())
+		outcome.TokensUsed += roundTokens
+
+		var err error
+		switch {
+		case turn.Failed && stance != nil:
+			err = channel.Pass(round, *stance)
+		case turn.Failed:
+			err = channel.Abstain(round)
+		case turn.Pass && policy.AllowPass && round > 0:
+			err = channel.Pass(round, turn.Approval)
+			stance = &turn.Approval
+		default:
+			err = channel.Post(round, turn.Approval, turn.Summary)
+			stance = &turn.Approval
+		}
+		if err != nil {
+			log.Printf("Failed to post round %d: %v", round, err)
+		}
+
+		channel.EndRound(round)
+		outcome.Rounds = round + 1
+
+		current := channel.Stances(round)
+		if round+1 >= policy.MinRounds && stancesStable(previous, current) {
+			outcome.Converged = true
+			outcome.StoppedBy = StopConverged
+			break
+		}
+		previous = current
+	}
+
+	return outcome
+}
+
+// stancesStable reports whether the same participants held the same stances in consecutive rounds
+func stancesStable(previous, current map[string]bool) bool {
+	if len(previous) == 0 || len(previous) != len(current) {
+		return false
+	}
+	for participant, approval := range current {
+		if prior, ok := previous[participant]; !ok || prior != approval {
+			return false
+		}
+	}
+	return true
+}
+
+// roundOverheadTokens is the allowance per round, on top of the proposal itself, for the transcript, prompts and reply
+const roundOverheadTokens = 2000
+
+// roundBudget returns the tokens a debate round is budgeted given the proposal's own size
+func roundBudget(proposalTokens int) int {
+	return proposalTokens + roundOverheadTokens
+}
+
+// estimateTokens approximates the token count of text at roughly four characters per token
+func estimateTokens(texts ...string) int {
+	total := 0
+	for _, text := range texts {
+		total += len(text)/4 + 1
+	}
+	return total
+}
+
+// ReviewContext is everything besides the proposal itself that a reviewer is shown in a debate round,
+// and the scope its LLM calls are accounted to
+type ReviewContext struct {
+	Scope               UsageScope
+	PreviousDiscussion  string
+	StakeholderComments string
+	Memories            string
+	History             string
+	Research            string
+	Screening           string
+	// Model and Seed select the model and sampling seed of one self-consistency sample; zero values use the defaults
+	Model string
+	Seed  int
+}
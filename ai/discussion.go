@@ -1,9 +1,7 @@
 package ai
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
@@ -12,129 +10,134 @@ import (
 )
 
 type Discussion struct {
-	ID            string    `json:"id"`
-	ValidatorID   string    `json:"validatorId"`
-	ValidatorName string    `json:"validatorName"`
-	Message       string    `json:"message"`
-	Support       bool      `json:"support"`
-	Oppose        bool      `json:"oppose"`
-	Question      bool      `json:"question"`
-	Timestamp     time.Time `json:"timestamp"`
-	Round         int       `json:"round"`
+	ID             string    `json:"id"`
+	ValidatorID    string    `json:"validatorId"`
+	ValidatorName  string    `json:"validatorName"`
+	Message        string    `json:"message"`
+	Support        bool      `json:"support"`
+	Oppose         bool      `json:"oppose"`
+	Question       bool      `json:"question"`
+	Decision       Decision  `json:"decision"`
+	Confidence     float64   `json:"confidence"`
+	Timestamp      time.Time `json:"timestamp"`
+	Round          int       `json:"round"`
+	PromptVersion  string    `json:"promptVersion,omitempty"`
+	PersonaVersion int       `json:"personaVersion,omitempty"`
 }
 
 // GetValidatorDiscussion generates a discussion response from a validator agent about a transaction
-func GetValidatorDiscussion(agent core.Agent, tx core.Transaction) Discussion {
+func GetValidatorDiscussion(agent core.Agent, tx core.Transaction, scope UsageScope) (Discussion, error) {
 	if !agent.IsValidator {
-		return Discussion{}
+		return Discussion{}, fmt.Errorf("agent %s is not a validator", agent.Name)
 	}
 
 	var description strings.Builder
 	description.WriteString(fmt.Sprintf("You are %s, with these traits: ", agent.Name))
 
 	var traits []string
-	for key, value := range agent.Metadata {
+	for _, key := range promptMetadataKeys(agent) {
+		value := agent.Metadata[key]
 		switch v := value.(type) {
 		case []interface{}:
 			for _, item := range v {
 				traits = append(traits, fmt.Sprintf("%v", item))
 			}
 		default:
-			if key != "api_key" && key != "endpoint" {
-				traits = append(traits, fmt.Sprintf("%v", value))
-			}
+			traits = append(traits, fmt.Sprintf("%v", value))
 		}
 	}
 	description.WriteString(strings.Join(traits, ", "))
 
-	prompt := fmt.Sprintf(`%s.
-
-		You're participating in a group discussion about this topic:
-		%s
-
-		IMPORTANT FORMAT: When referencing any validator, you MUST use the exact format: |@Name|
-		The pipes (|) are required at the start and end of EVERY mention.
+	prompt, promptVersion, err := RenderPrompt("discussion", agentLanguage(agent), agent.ID, struct {
+		Persona string
+		Topic   string
+	}{operatorInput(agent, description.String()), tx.Content})
+	if err != nil {
+		return Discussion{}, err
+	}
 
-		Share your thoughts naturally, as if you're in a real conversation. If you've done any research, incorporate 
-		it smoothly into your discussion without explicitly mentioning that you did research. When referring to others 
-		in the conversation, use their names with the format |@Name| (e.g., "I see what |@Marie Curie| means about...").
-		
-		If you're the first to speak, just give your honest thoughts about the topic. If others have spoken, feel free 
-		to build on or challenge their ideas - just be yourself and express your views based on your personality traits.
+	config := DefaultLLMConfig()
+	config.Scope = scope
+	temp, err := GenerateStructured[discussionResponse](prompt, config, DefaultStructuredRetries)
+	if err != nil {
+		return Discussion{}, err
+	}
 
-		Based on your analysis, you need to provide
-		1. An opinion on the topic statement.
-		2. A stance on the topic statement (SUPPORT, OPPOSE, or QUESTION).
-		3. A reason for your stance (reference other validators only if they've already participated).
+	discussion := Discussion{
+		ID:             uuid.New().String(),
+		ValidatorID:    agent.ID,
+		ValidatorName:  agent.Name,
+		Message:        temp.Message,
+		Support:        temp.Decision == DecisionApprove,
+		Oppose:         temp.Decision == DecisionReject,
+		Question:       temp.Decision == DecisionAbstain,
+		Decision:       temp.Decision,
+		Confidence:     temp.Confidence,
+		Round:          1,
+		Timestamp:      time.Now(),
+		PromptVersion:  promptVersion,
+		PersonaVersion: agent.PersonaVersion,
+	}
 
-		Analyze the statement of the topic by considering:
-		1. The exact wording of the statement.
-		2. If there are previous discussions, consider those viewpoints and reference specific validators 
-		   only if they have actually participated. Always use the format |@Name| when mentioning them.
-		3. Your personal reaction based on your personality and analysis.
-		4. If others have commented, you may build upon or challenge their arguments using their exact names.
-		   For example: "|@Einstein| makes a valid point about..." or "I disagree with |@Newton|'s analysis because..."
-		   Remember: Every validator mention must be enclosed in pipes with @ symbol.
-		   If you're first to comment, focus on your direct analysis of the statement.
+	return discussion, nil
+}
 
-		Important: Your analysis must be fully consistent. This means:
-		- If you agree with the statement and think the statement is true, your "stance" must be "SUPPORT".
-		- If you disagree with the statement and think the statement is false, your "stance" must be "OPPOSE".
-		- If you are unsure, then use "QUESTION".
+// Verdict returns the discussion as a common verdict on the discussed transaction
+func (d Discussion) Verdict(proposalID string) Verdict {
+	return Verdict{
+		AgentID:          d.ValidatorID,
+		ProposalID:       proposalID,
+		ProposalType:     "discuss_transaction",
+		Decision:         d.Decision,
+		Confidence:       CalibrateConfidence(d.Confidence, 1),
+		StatedConfidence: d.Confidence,
+		Agreement:        1,
+		Reasons:          reasonsOf(ReasonArgument, []string{d.Message}),
+		Summary:          d.Message,
+		PersonaVersion:   d.PersonaVersion,
+		Timestamp:        d.Timestamp.Unix(),
+	}
+}
 
-		Additionally:
-		- Ensure your "opinion", "stance", and "reason" all clearly align.
-		- Mentioning other validators is optional and should only be done if they have already participated.
-		- When referencing another validator, you MUST use the format |@Name| - the pipes are required.
-		- Never invent or mention validators that aren't shown in the previous discussions.
-		- Indicate whether you agree or disagree with specific points made by others.
+// discussionResponse is the part of a Discussion produced by the model. Prompts before v2 select a stance with
+// the support, oppose and question flags instead of a decision.
+type discussionResponse struct {
+	Message    string   `json:"message"`
+	Decision   Decision `json:"decision" enum:"approve,reject,abstain"`
+	Confidence float64  `json:"confidence"`
+	Support    bool     `json:"support" schema:"-"`
+	Oppose     bool     `json:"oppose" schema:"-"`
+	Question   bool     `json:"question" schema:"-"`
+}
 
-		Your response MUST be a JSON object with exactly these fields:
-		{
-			"message": "Your detailed discussion message here. Must reference other validators using |@Name| format",
-			"support": false | true,            // Should be true if you support the statement
-			"oppose": false | true,             // Should be true if you oppose the statement
-			"question": false | true            // Should be true if you are unsure
+// Validate checks that the message is present and exactly one stance is taken, mapping the flags of older
+// prompts onto a decision
+func (d *discussionResponse) Validate() error {
+	if strings.TrimSpace(d.Message) == "" {
+		return fmt.Errorf("message must not be empty")
+	}
+	if d.Decision == "" {
+		selected := 0
+		stances := []struct {
+			flag     bool
+			decision Decision
+		}{{d.Support, DecisionApprove}, {d.Oppose, DecisionReject}, {d.Question, DecisionAbstain}}
+		for _, stance := range stances {
+			if stance.flag {
+				selected++
+				d.Decision = stance.decision
+			}
 		}
-
-		Requirements:
-		1. The message should express your thoughts based on your personality traits
-		2. The support, oppose, and question fields must be true or false, not null, and only one of them should be true
-		3. When mentioning other validators, you MUST use |@Name| format
-		4. Never invent or mention validators that aren't in the previous discussions
-		5. Your response must be ONLY the JSON object - no other text before or after
-		6. Leave id, validatorId, validatorName, round, and timestamp empty - they will be filled in later
-
-		Do not include any additional text or formatting.`,
-		description.String(),
-		tx.Content)
-
-	response := GenerateLLMResponse(prompt)
-
-	type tempDiscussion struct {
-		Message  string `json:"message"`
-		Support  bool   `json:"support"`
-		Oppose   bool   `json:"oppose"`
-		Question bool   `json:"question"`
+		if selected != 1 {
+			return fmt.Errorf("exactly one of support, oppose and question must be true, got %d", selected)
+		}
+		d.Confidence = 1
 	}
-
-	var temp tempDiscussion
-	if err := json.Unmarshal([]byte(response), &temp); err != nil {
-		log.Printf("Error parsing LLM response: %v", err)
-		return Discussion{}
+	if !d.Decision.Valid() {
+		return fmt.Errorf("decision must be approve, reject or abstain, got %q", d.Decision)
 	}
-
-	discussion := Discussion{
-		ID:            uuid.New().String(),
-		ValidatorID:   agent.ID,
-		ValidatorName: agent.Name,
-		Message:       temp.Message,
-		Support:       temp.Support,
-		Oppose:        temp.Oppose,
-		Question:      temp.Question,
-		Round:         1,
-		Timestamp:     time.Now(),
+	if d.Confidence < 0 || d.Confidence > 1 {
+		return fmt.Errorf("confidence must be between 0 and 1, got %v", d.Confidence)
 	}
-
-	return discussion
+	return nil
 }
@@ -0,0 +1,237 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+)
+
+// DiversityThresholds are the limits a validator set must stay within to count as diverse
+type DiversityThresholds struct {
+	MaxTraitOverlap           float64 `json:"max_trait_overlap"`
+	MaxStanceCorrelation      float64 `json:"max_stance_correlation"`
+	MinSpecializationCoverage float64 `json:"min_specialization_coverage"`
+	MinSharedProposals        int     `json:"min_shared_proposals"`
+}
+
+// DefaultDiversityThresholds allow two agents to share half their traits and to agree on nearly, but not
+// every, proposal they both judged
+var DefaultDiversityThresholds = DiversityThresholds{
+	MaxTraitOverlap:           0.5,
+	MaxStanceCorrelation:      0.9,
+	MinSpecializationCoverage: 0.7,
+	MinSharedProposals:        3,
+}
+
+// PersonaPair compares two agents of a validator set. StanceCorrelation is only set once the pair has judged
+// enough of the same proposals.
+type PersonaPair struct {
+	A                 string   `json:"a"`
+	B                 string   `json:"b"`
+	TraitOverlap      float64  `json:"trait_overlap"`
+	SharedProposals   int      `json:"shared_proposals"`
+	StanceCorrelation *float64 `json:"stance_correlation,omitempty"`
+}
+
+// DiversityReport describes how differently the agents of a validator set are likely to reason
+type DiversityReport struct {
+	Agents                 int                 `json:"agents"`
+	MeanTraitOverlap       float64             `json:"mean_trait_overlap"`
+	MaxTraitOverlap        float64             `json:"max_trait_overlap"`
+	MeanStanceCorrelation  *float64            `json:"mean_stance_correlation,omitempty"`
+	Specializations        map[string][]string `json:"specializations"`
+	SpecializationCoverage float64             `json:"specialization_coverage"`
+	Pairs                  []PersonaPair       `json:"pairs"`
+	Violations             []string            `json:"violations"`
+	Diverse                bool                `json:"diverse"`
+	offenders              []string
+}
+
+// Offenders returns the IDs of agents that would have to be replaced to clear the report's violations
+func (r DiversityReport) Offenders() []string {
+	return r.offenders
+}
+
+// AnalyzeDiversity reports trait overlap, the correlation of recorded stances on the chain and specialization
+// coverage for a validator set. An empty chainID analyzes the personas alone.
+func AnalyzeDiversity(chainID string, personas []core.Persona, thresholds DiversityThresholds) DiversityReport {
+	report := DiversityReport{Agents: len(personas), Specializations: make(map[string][]string)}
+	var stances map[string]map[string]float64
+	if chainID != "" {
+		stances = stanceHistory(chainID)
+	}
+
+	offending := make(map[string]bool)
+	var overlapSum, correlationSum float64
+	correlated := 0
+	for i := range personas {
+		for j := i + 1; j < len(personas); j++ {
+			a, b := personas[i], personas[j]
+			pair := PersonaPair{A: a.ID, B: b.ID, TraitOverlap: traitOverlap(a, b)}
+			overlapSum += pair.TraitOverlap
+			report.MaxTraitOverlap = math.Max(report.MaxTraitOverlap, pair.TraitOverlap)
+			if pair.TraitOverlap > thresholds.MaxTraitOverlap {
+				report.Violations = append(report.Violations, fmt.Sprintf("%s and %s share %.0f%% of their traits", a.Name, b.Name, pair.TraitOverlap*100))
+				offending[b.ID] = true
+			}
+
+			var correlation float64
+			correlation, pair.SharedProposals = stanceCorrelation(stances[a.ID], stances[b.ID])
+			if pair.SharedProposals >= thresholds.MinSharedProposals {
+				pair.StanceCorrelation = &correlation
+				correlationSum += correlation
+				correlated++
+				if correlation > thresholds.MaxStanceCorrelation {
+					report.Violations = append(report.Violations, fmt.Sprintf("%s and %s took correlated stances (%.2f) on %d shared proposals", a.Name, b.Name, correlation, pair.SharedProposals))
+					offending[b.ID] = true
+				}
+			}
+			report.Pairs = append(report.Pairs, pair)
+		}
+	}
+	if len(report.Pairs) > 0 {
+		report.MeanTraitOverlap = overlapSum / float64(len(report.Pairs))
+	}
+	if correlated > 0 {
+		mean := correlationSum / float64(correlated)
+		report.MeanStanceCorrelation = &mean
+	}
+
+	var duplicates []string
+	for _, p := range personas {
+		key := strings.ToLower(strings.TrimSpace(p.Specialization))
+		if key == "" {
+			key = "unspecified"
+		}
+		if key == "unspecified" || len(report.Specializations[key]) > 0 {
+			duplicates = append(duplicates, p.ID)
+		}
+		report.Specializations[key] = append(report.Specializations[key], p.Name)
+	}
+	if len(personas) > 0 {
+		distinct := len(report.Specializations)
+		if _, ok := report.Specializations["unspecified"]; ok {
+			distinct--
+		}
+		report.SpecializationCoverage = float64(distinct) / float64(len(personas))
+		if report.SpecializationCoverage < thresholds.MinSpecializationCoverage {
+			report.Violations = append(report.Violations, fmt.Sprintf("only %d distinct specializations across %d agents", distinct, len(personas)))
+			for _, id := range duplicates {
+				offending[id] = true
+			}
+		}
+	}
+
+	for _, p := range personas {
+		if offending[p.ID] {
+			report.offenders = append(report.offenders, p.ID)
+		}
+	}
+	report.Diverse = len(report.Violations) == 0
+	return report
+}
+
+// traitOverlap is the Jaccard similarity of two personas' traits
+func traitOverlap(a, b core.Persona) float64 {
+	set := make(map[string]int)
+	for _, trait := range a.Traits {
+		set[strings.ToLower(strings.TrimSpace(trait))] |= 1
+	}
+	for _, trait := range b.Traits {
+		set[strings.ToLower(strings.TrimSpace(trait))] |= 2
+	}
+	shared := 0
+	for _, in := range set {
+		if in == 3 {
+			shared++
+		}
+	}
+	if len(set) == 0 {
+		return 0
+	}
+	return float64(shared) / float64(len(set))
+}
+
+// stanceHistory returns each agent's recorded stance per proposal, approvals as 1 and rejections as -1.
+// Abstentions say nothing about how an agent leans and are left out.
+func stanceHistory(chainID string) map[string]map[string]float64 {
+	verdictsMu.Lock()
+	defer verdictsMu.Unlock()
+
+	stances := make(map[string]map[string]float64)
+	for proposalID, recorded := range chainVerdicts(chainID) {
+		for _, v := range recorded {
+			var stance float64
+			switch v.Decision {
+			case DecisionApprove:
+				stance = 1
+			case DecisionReject:
+				stance = -1
+			default:
+				continue
+			}
+			if stances[v.AgentID] == nil {
+				stances[v.AgentID] = make(map[string]float64)
+			}
+			stances[v.AgentID][proposalID] = stance
+		}
+	}
+	return stances
+}
+
+// stanceCorrelation is the Pearson correlation of two agents' stances on the proposals both judged. When either
+// agent never changed its stance the correlation is undefined, so identical records count as fully correlated.
+func stanceCorrelation(a, b map[string]float64) (float64, int) {
+	var xs, ys []float64
+	for proposalID, x := range a {
+		if y, ok := b[proposalID]; ok {
+			xs = append(xs, x)
+			ys = append(ys, y)
+		}
+	}
+	n := len(xs)
+	if n == 0 {
+		return 0, 0
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var cov, varX, varY float64
+	agree := 0
+	for i := range xs {
+		cov += (xs[i] - meanX) * (ys[i] - meanY)
+		varX += (xs[i] - meanX) * (xs[i] - meanX)
+		varY += (ys[i] - meanY) * (ys[i] - meanY)
+		if xs[i] == ys[i] {
+			agree++
+		}
+	}
+	if varX == 0 || varY == 0 {
+		// With no variation agreement is the only signal: all-agree is 1, all-disagree is -1
+		return 2*float64(agree)/float64(n) - 1, n
+	}
+	return cov / math.Sqrt(varX*varY), n
+}
+
+// sortedSpecializations lists the specializations already covered, for steering replacements away from them
+func sortedSpecializations(personas []core.Persona) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, p := range personas {
+		if p.Specialization != "" && !seen[strings.ToLower(p.Specialization)] {
+			seen[strings.ToLower(p.Specialization)] = true
+			out = append(out, p.Specialization)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,362 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	HistoryKindProposal   = "proposal"
+	HistoryKindTranscript = "transcript"
+)
+
+// DefaultHistoryResults is how many earlier documents are retrieved for a review prompt
+const DefaultHistoryResults = 4
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// maxTranscriptChars bounds how much of a discussion transcript is indexed
+const maxTranscriptChars = 6000
+
+// Embedder is implemented by providers that can embed text for semantic retrieval
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Embed embeds texts with OpenAI's small embedding model
+func (p *OpenAIProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	resp, err := p.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: texts,
+		Model: openai.SmallEmbedding3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(resp.Data))
+	for _, data := range resp.Data {
+		if data.Index < len(vectors) {
+			vectors[data.Index] = data.Embedding
+		}
+	}
+	return vectors, nil
+}
+
+// HistoryDocument is a committed proposal or its discussion transcript
+type HistoryDocument struct {
+	ID           string    `json:"id"`
+	ProposalID   string    `json:"proposal_id"`
+	Kind         string    `json:"kind"`
+	ProposalType string    `json:"proposal_type"`
+	Title        string    `json:"title"`
+	Text         string    `json:"text"`
+	Height       int64     `json:"height"`
+	Embedding    []float32 `json:"embedding,omitempty"`
+}
+
+// HistoryHit is a retrieved document with its relevance score
+type HistoryHit struct {
+	Document HistoryDocument `json:"document"`
+	Score    float64         `json:"score"`
+}
+
+// Citation references an earlier committed proposal by ID and the height it was committed at
+type Citation struct {
+	ProposalID string `json:"proposal_id"`
+	Height     int64  `json:"height"`
+}
+
+var (
+	historyMu          sync.Mutex
+	historyIndexes     = make(map[string][]HistoryDocument)
+	historyLoaded      = make(map[string]bool)
+	pendingTranscripts = make(map[string]map[string]string)
+)
+
+// historyFile returns where a chain's history index is persisted
+func historyFile(chainID string) string {
+	return filepath.Join("data", "history", chainID+".json")
+}
+
+// chainHistory returns a chain's indexed documents, loading them on first use. Callers must hold historyMu.
+func chainHistory(chainID string) []HistoryDocument {
+	if !historyLoaded[chainID] {
+		historyLoaded[chainID] = true
+		if data, err := os.ReadFile(historyFile(chainID)); err == nil {
+			var docs []HistoryDocument
+			if err := json.Unmarshal(data, &docs); err != nil {
+				log.Printf("Failed to parse history index of chain %s: %v", chainID, err)
+			}
+			historyIndexes[chainID] = docs
+		}
+	}
+	return historyIndexes[chainID]
+}
+
+// saveHistory persists a chain's history index. Callers must hold historyMu.
+func saveHistory(chainID string) error {
+	if err := os.MkdirAll(filepath.Dir(historyFile(chainID)), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+	data, err := json.Marshal(historyIndexes[chainID])
+	if err != nil {
+		return fmt.Errorf("failed to marshal history index: %v", err)
+	}
+	return os.WriteFile(historyFile(chainID), data, 0644)
+}
+
+// StageTranscript keeps a proposal's discussion transcript until the proposal is committed and indexed
+func StageTranscript(chainID, proposalID, transcript string) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if pendingTranscripts[chainID] == nil {
+		pendingTranscripts[chainID] = make(map[string]string)
+	}
+	pendingTranscripts[chainID][proposalID] = transcript
+}
+
+// IndexCommittedProposal adds a committed proposal, and its staged discussion transcript if any, to the chain's
+// history index. Embeddings are computed in the background when the provider supports them.
+func IndexCommittedProposal(chainID, proposalID, proposalType, title, content string, height int64) {
+	docs := []HistoryDocument{{
+		ID:           proposalID,
+		ProposalID:   proposalID,
+		Kind:         HistoryKindProposal,
+		ProposalType: proposalType,
+		Title:        title,
+		Text:         content,
+		Height:       height,
+	}}
+
+	historyMu.Lock()
+	if transcript, ok := pendingTranscripts[chainID][proposalID]; ok {
+		delete(pendingTranscripts[chainID], proposalID)
+		if len(transcript) > maxTranscriptChars {
+			transcript = transcript[len(transcript)-maxTranscriptChars:]
+		}
+		docs = append(docs, HistoryDocument{
+			ID:           proposalID + "/" + HistoryKindTranscript,
+			ProposalID:   proposalID,
+			Kind:         HistoryKindTranscript,
+			ProposalType: proposalType,
+			Title:        title,
+			Text:         transcript,
+			Height:       height,
+		})
+	}
+
+	existing := chainHistory(chainID)
+	for _, doc := range docs {
+		replaced := false
+		for i := range existing {
+			if existing[i].ID == doc.ID {
+				existing[i] = doc
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			existing = append(existing, doc)
+		}
+	}
+	historyIndexes[chainID] = existing
+	if err := saveHistory(chainID); err != nil {
+		log.Printf("Failed to save history index: %v", err)
+	}
+	historyMu.Unlock()
+
+	if embedder, ok := embedderOf(CurrentProvider()); ok {
+		go embedHistory(chainID, embedder, docs)
+	}
+}
+
+// embedHistory attaches embeddings to freshly indexed documents
+func embedHistory(chainID string, embedder Embedder, docs []HistoryDocument) {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Title + "\n" + doc.Text
+	}
+	vectors, err := embedder.Embed(context.Background(), texts)
+	if err != nil || len(vectors) != len(docs) {
+		log.Printf("Failed to embed history documents, keyword search will be used: %v", err)
+		return
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	indexed := chainHistory(chainID)
+	for i, doc := range docs {
+		for j := range indexed {
+			if indexed[j].ID == doc.ID {
+				indexed[j].Embedding = vectors[i]
+			}
+		}
+	}
+	if err := saveHistory(chainID); err != nil {
+		log.Printf("Failed to save history index: %v", err)
+	}
+}
+
+// SearchHistory returns the documents on a chain most relevant to query, excluding those of excludeProposalID.
+// It ranks by embedding similarity when every document is embedded and the provider can embed the query,
+// and by BM25 otherwise.
+func SearchHistory(chainID, query, excludeProposalID string, limit int) []HistoryHit {
+	historyMu.Lock()
+	var docs []HistoryDocument
+	for _, doc := range chainHistory(chainID) {
+		if doc.ProposalID != excludeProposalID {
+			docs = append(docs, doc)
+		}
+	}
+	historyMu.Unlock()
+	if len(docs) == 0 {
+		return nil
+	}
+
+	hits := searchEmbeddings(docs, query)
+	if hits == nil {
+		hits = searchBM25(docs, query)
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// searchEmbeddings scores documents by cosine similarity to the embedded query, or returns nil if it cannot
+func searchEmbeddings(docs []HistoryDocument, query string) []HistoryHit {
+	embedder, ok := embedderOf(CurrentProvider())
+	if !ok {
+		return nil
+	}
+	for _, doc := range docs {
+		if len(doc.Embedding) == 0 {
+			return nil
+		}
+	}
+	vectors, err := embedder.Embed(context.Background(), []string{query})
+	if err != nil || len(vectors) != 1 {
+		log.Printf("Failed to embed history query, falling back to keyword search: %v", err)
+		return nil
+	}
+
+	hits := make([]HistoryHit, 0, len(docs))
+	for _, doc := range docs {
+		if len(doc.Embedding) != len(vectors[0]) {
+			return nil
+		}
+		var dot, normA, normB float64
+		for i := range doc.Embedding {
+			dot += float64(doc.Embedding[i]) * float64(vectors[0][i])
+			normA += float64(doc.Embedding[i]) * float64(doc.Embedding[i])
+			normB += float64(vectors[0][i]) * float64(vectors[0][i])
+		}
+		if normA > 0 && normB > 0 {
+			hits = append(hits, HistoryHit{Document: doc, Score: dot / (math.Sqrt(normA) * math.Sqrt(normB))})
+		}
+	}
+	return hits
+}
+
+// searchBM25 scores documents against the query with Okapi BM25
+func searchBM25(docs []HistoryDocument, query string) []HistoryHit {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Title + " " + doc.Text
+	}
+
+	var hits []HistoryHit
+	for i, score := range bm25Scores(texts, query) {
+		if score > 0 {
+			hits = append(hits, HistoryHit{Document: docs[i], Score: score})
+		}
+	}
+	return hits
+}
+
+// bm25Scores returns the Okapi BM25 score of each text against the query
+func bm25Scores(texts []string, query string) []float64 {
+	docTerms := make([]map[string]float64, len(texts))
+	lengths := make([]float64, len(texts))
+	docFreq := make(map[string]int)
+	totalLength := 0.0
+	for i, text := range texts {
+		docTerms[i] = termFrequencies(text)
+		for term, count := range docTerms[i] {
+			docFreq[term]++
+			lengths[i] += count
+		}
+		totalLength += lengths[i]
+	}
+	avgLength := totalLength / float64(len(texts))
+
+	scores := make([]float64, len(texts))
+	for term := range termFrequencies(query) {
+		n := float64(docFreq[term])
+		if n == 0 {
+			continue
+		}
+		idf := math.Log(1 + (float64(len(texts))-n+0.5)/(n+0.5))
+		for i := range texts {
+			tf := docTerms[i][term]
+			if tf == 0 {
+				continue
+			}
+			scores[i] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*lengths[i]/avgLength))
+		}
+	}
+	return scores
+}
+
+// FormatHistory renders retrieved documents for a prompt with the citation each one should be referenced by
+func FormatHistory(hits []HistoryHit) string {
+	if len(hits) == 0 {
+		return "No related proposals have been committed on this chain yet."
+	}
+	var b strings.Builder
+	for _, hit := range hits {
+		doc := hit.Document
+		excerpt := strings.Join(strings.Fields(doc.Text), " ")
+		if len(excerpt) > 600 {
+			excerpt = excerpt[:600] + "..."
+		}
+		fmt.Fprintf(&b, "[proposal %s, height %d] %s %s \"%s\": %s\n", doc.ProposalID, doc.Height, doc.ProposalType, doc.Kind, doc.Title, excerpt)
+	}
+	return b.String()
+}
+
+// groundCitations keeps only citations of retrieved documents and takes their heights from the index
+func groundCitations(cited []Citation, hits []HistoryHit) []Citation {
+	heights := make(map[string]int64)
+	for _, hit := range hits {
+		heights[hit.Document.ProposalID] = hit.Document.Height
+	}
+
+	seen := make(map[string]bool)
+	grounded := []Citation{}
+	for _, c := range cited {
+		height, ok := heights[c.ProposalID]
+		if !ok || seen[c.ProposalID] {
+			continue
+		}
+		seen[c.ProposalID] = true
+		grounded = append(grounded, Citation{ProposalID: c.ProposalID, Height: height})
+	}
+	return grounded
+}
@@ -1,50 +1,117 @@
 package ai
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
 
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
-	"github.com/Deeptanshu-sankhwar/agentic_consensus/utils"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
 type LoanReview struct {
-	Summary     string   `json:"summary"`
-	RiskFactors []string `json:"risk_factors"`
-	Terms       []string `json:"terms"`
-	Approval    bool     `json:"approval"`
+	Summary       string             `json:"summary"`
+	RiskFactors   []string           `json:"risk_factors"`
+	Terms         []string           `json:"terms"`
+	Decision      Decision           `json:"decision" enum:"approve,reject,abstain"`
+	Confidence    float64            `json:"confidence"`
+	Approval      bool               `json:"approval" schema:"-"`
+	Pass          bool               `json:"pass,omitempty"`
+	Citations     []Citation         `json:"citations"`
+	PromptVersion string             `json:"prompt_version,omitempty" schema:"-"`
+	Research      *ResearchLog       `json:"research,omitempty" schema:"-"`
+	Verdict       *Verdict           `json:"verdict,omitempty" schema:"-"`
+	Consistency   *ConsistencyReport `json:"consistency,omitempty" schema:"-"`
 }
 
-// GetMultiRoundLoanReview performs multiple rounds of loan review and returns the final review
-func GetMultiRoundLoanReview(agent core.Agent, loan string, chainID string) LoanReview {
-	round := 0
+// GetMultiRoundLoanReview debates the loan request over the given discussion channel under the loan_request debate
+// policy and returns the final review. A nil channel falls back to the node-local discussion log.
+func GetMultiRoundLoanReview(agent core.Agent, loan string, chainID string, proposalID string, channel DiscussionChannel) (LoanReview, error) {
+	if channel == nil {
+		channel = NewLocalChannel(chainID, proposalID, agent)
+	}
 
-	for round < 4 {
-		previousDiscussion := utils.GetDiscussionLog(chainID)
-		review := GetLoanReview(agent, loan, previousDiscussion)
-		msg := fmt.Sprintf("[Round %d] (%v) |@%s|: %s", round, review.Approval, agent.Name, review.Summary)
-		utils.AppendDiscussionLog(chainID, msg)
-		round++
+	topic := loan
+	memories := FormatMemories(Recall(chainID, agent.ID, proposalID, topic, DefaultMemoryTokenBudget))
+	historyHits := SearchHistory(chainID, topic, proposalID, DefaultHistoryResults)
+	scope := UsageScope{ChainID: chainID, ProposalID: proposalID, AgentID: agent.ID}
+	findings := Research(agent, topic, scope)
+	reviewContext := ReviewContext{
+		Scope:    scope,
+		Memories: memories,
+		History:  FormatHistory(historyHits),
+		Research: FormatResearch(findings),
+		Screening: FormatScreening(ScreenProposal(chainID, proposalID, map[string]string{
+			"request": loan,
+		})),
 	}
 
-	previousDiscussion := utils.GetDiscussionLog(chainID)
-	return GetLoanReview(agent, loan, previousDiscussion)
+	budget := roundBudget(estimateTokens(loan))
+	outcome := RunDebate(PolicyFor("loan_request"), budget, channel, func(round int, transcript string) DebateTurn {
+		reviewContext.PreviousDiscussion = transcript
+		reviewContext.StakeholderComments = communication.FormatHumanComments(communication.GetHumanComments(chainID, proposalID))
+		review, err := GetLoanReview(agent, loan, reviewContext)
+		if err != nil {
+			log.Printf("Round %d review by %s failed: %v", round, agent.Name, err)
+			return DebateTurn{Failed: true}
+		}
+		return DebateTurn{
+			Approval: review.Approval,
+			Summary:  review.Summary,
+			Pass:     review.Pass,
+		}
+	})
+	log.Printf("Debate on loan %s ended after %d rounds (%s)", proposalID, outcome.Rounds, outcome.StoppedBy)
+
+	transcript := channel.Transcript()
+	reviewContext.PreviousDiscussion = transcript
+	reviewContext.StakeholderComments = communication.FormatHumanComments(communication.GetHumanComments(chainID, proposalID))
+	policy := SamplingFor(agent)
+	review, consistency, err := SelfConsistent(policy, func(model string, seed int) (LoanReview, error) {
+		sampleContext := reviewContext
+		sampleContext.Model = model
+		if policy.Samples > 1 {
+			sampleContext.Seed = seed
+		}
+		return GetLoanReview(agent, loan, sampleContext)
+	}, func(r LoanReview) (Decision, string) {
+		return r.Decision, r.Summary
+	})
+	if err != nil {
+		return review, err
+	}
+	verdict := verdictOf(agent, proposalID, "loan_request", review.Decision, review.Confidence, consistency,
+		append(reasonsOf(ReasonRisk, review.RiskFactors), reasonsOf(ReasonTerm, review.Terms)...), review.Summary)
+	review.Verdict = &verdict
+	review.Consistency = &consistency
+	review.Citations = groundCitations(review.Citations, historyHits)
+	review.Research = findings
+	StageTranscript(chainID, proposalID, transcript)
+
+	Remember(chainID, agent.ID, MemoryEntry{
+		ProposalID:    proposalID,
+		ProposalType:  "loan_request",
+		Topic:         memoryTopic(loan),
+		Approval:      review.Approval,
+		Summary:       review.Summary,
+		PeerArguments: peerArguments(transcript, agent.Name),
+	})
+	return review, nil
 }
 
 // GetLoanReview generates a loan review based on agent's analysis and previous discussion
-func GetLoanReview(agent core.Agent, loan string, previousDiscussion string) LoanReview {
+func GetLoanReview(agent core.Agent, loan string, reviewContext ReviewContext) (LoanReview, error) {
 	if !agent.IsValidator {
-		return LoanReview{}
+		return LoanReview{}, fmt.Errorf("agent %s is not a validator", agent.Name)
 	}
 
 	var description strings.Builder
 	description.WriteString(fmt.Sprintf("You are %s, a DeFi banker with the following background:\n\n", agent.Name))
 
-	for key, value := range agent.Metadata {
+	for _, key := range promptMetadataKeys(agent) {
+		value := agent.Metadata[key]
 		switch v := value.(type) {
 		case []interface{}:
 			items := make([]string, len(v))
@@ -63,45 +130,52 @@ func GetLoanReview(agent core.Agent, loan string, previousDiscussion string) Loa
 		}
 	}
 
-	prompt := fmt.Sprintf(`%s
-	You are participating in a multi-round review of this loan request:
-
-	Request Details: %s
-
-	--- Previous Discussion Log ---
-	%s
-	--- End of Discussion Log ---
-
-	Please write your review in the style of an ongoing discussion. Share your thoughts naturally, as if you're in a real conversation with other bankers. You may reference previous discussion points and tag other reviewers using the format |@Name|.
-
-	When reviewing, consider:
-	1. Collateralization ratio and risk
-	2. Borrower's reputation and history
-	3. Purpose and viability of the loan
-	4. Market conditions and volatility
-
-	You must respond with a valid JSON object in this exact format, with no additional text or formatting:
-	{
-		"summary": "<your discussion summary>",
-		"risk_factors": ["<risk1>", "<risk2>", ...],
-		"terms": ["<term1>", "<term2>", ...],
-		"approval": true|false
+	prompt, promptVersion, err := RenderPrompt("loan_review", agentLanguage(agent), agent.ID, struct {
+		Persona string
+		Loan    string
+		ReviewContext
+	}{operatorInput(agent, description.String()), loan, reviewContext})
+	if err != nil {
+		return LoanReview{}, err
 	}
 
-	Your response must be valid JSON. The approval field must be a boolean, not a string.
-	Make sure the approval is true at least half of the time.`,
-		description.String(),
-		loan,
-		previousDiscussion)
+	config := DefaultLLMConfig()
+	config.Scope = reviewContext.Scope
+	if reviewContext.Model != "" {
+		config.Model = reviewContext.Model
+	}
+	if reviewContext.Seed > 0 {
+		seed := reviewContext.Seed
+		config.Seed = &seed
+	}
+	review, err := GenerateStructured[LoanReview](prompt, config, DefaultStructuredRetries)
+	if err != nil {
+		return LoanReview{}, err
+	}
+	review.PromptVersion = promptVersion
+	log.Printf("LOAN REVIEW for request: %+v", review)
 
-	response := GenerateLLMResponse(prompt)
-	log.Printf("LOAN REVIEW for request: %+v", response)
+	return review, nil
+}
 
-	var review LoanReview
-	if err := json.Unmarshal([]byte(response), &review); err != nil {
-		log.Printf("Error parsing review response: %v", err)
-		return LoanReview{}
+// Validate checks the invariants of a loan review that the JSON schema cannot express and derives the
+// approval from the decision
+func (r *LoanReview) Validate() error {
+	if strings.TrimSpace(r.Summary) == "" {
+		return fmt.Errorf("summary must not be empty")
 	}
-
-	return review
+	if r.Decision == "" {
+		r.Decision, r.Confidence = legacyDecision(r.Approval)
+	}
+	if !r.Decision.Valid() {
+		return fmt.Errorf("decision must be approve, reject or abstain, got %q", r.Decision)
+	}
+	if r.Confidence < 0 || r.Confidence > 1 {
+		return fmt.Errorf("confidence must be between 0 and 1, got %v", r.Confidence)
+	}
+	r.Approval = r.Decision == DecisionApprove
+	if r.Decision == DecisionReject && len(r.RiskFactors) == 0 {
+		return fmt.Errorf("a rejection must list at least one risk factor")
+	}
+	return nil
 }
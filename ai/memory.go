@@ -0,0 +1,263 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+)
+
+const (
+	OutcomePending   = "pending"
+	OutcomeCommitted = "committed"
+)
+
+// DefaultMemoryTokenBudget caps how much recalled memory is injected into a single prompt
+const DefaultMemoryTokenBudget = 600
+
+// maxPeerArguments is how many arguments from other validators are kept per memory
+const maxPeerArguments = 3
+
+// MemoryEntry is what an agent remembers about one proposal it reviewed
+type MemoryEntry struct {
+	ProposalID    string   `json:"proposal_id"`
+	ProposalType  string   `json:"proposal_type"`
+	Topic         string   `json:"topic"`
+	Approval      bool     `json:"approval"`
+	Summary       string   `json:"summary"`
+	Outcome       string   `json:"outcome"`
+	PeerArguments []string `json:"peer_arguments,omitempty"`
+	Timestamp     int64    `json:"timestamp"`
+}
+
+var (
+	memoriesMu sync.Mutex
+	memories   = make(map[string]map[string][]MemoryEntry)
+)
+
+// memoryFile returns where an agent's memories on a chain are persisted
+func memoryFile(chainID, agentID string) string {
+	return filepath.Join("data", "memory", chainID, agentID+".json")
+}
+
+// agentMemories returns an agent's memories, loading them from disk on first use. Callers must hold memoriesMu.
+func agentMemories(chainID, agentID string) []MemoryEntry {
+	if memories[chainID] == nil {
+		memories[chainID] = make(map[string][]MemoryEntry)
+	}
+	entries, ok := memories[chainID][agentID]
+	if ok {
+		return entries
+	}
+
+	if data, err := os.ReadFile(memoryFile(chainID, agentID)); err == nil {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			log.Printf("Failed to parse memory of agent %s: %v", agentID, err)
+		}
+	}
+	memories[chainID][agentID] = entries
+	return entries
+}
+
+// saveMemories persists an agent's memories. Callers must hold memoriesMu.
+func saveMemories(chainID, agentID string) error {
+	path := memoryFile(chainID, agentID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %v", err)
+	}
+	data, err := json.MarshalIndent(memories[chainID][agentID], "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal memory: %v", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Remember stores an agent's verdict on a proposal, replacing any earlier memory of the same proposal
+func Remember(chainID, agentID string, entry MemoryEntry) {
+	memoriesMu.Lock()
+	defer memoriesMu.Unlock()
+
+	if entry.Timestamp == 0 {
+		entry.Timestamp = time.Now().Unix()
+	}
+	if entry.Outcome == "" {
+		entry.Outcome = OutcomePending
+	}
+
+	entries := agentMemories(chainID, agentID)
+	replaced := false
+	for i := range entries {
+		if entries[i].ProposalID == entry.ProposalID {
+			entries[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		entries = append(entries, entry)
+	}
+	memories[chainID][agentID] = entries
+
+	if err := saveMemories(chainID, agentID); err != nil {
+		log.Printf("Failed to save memory of agent %s: %v", agentID, err)
+	}
+}
+
+// RecordOutcome sets the outcome of a proposal in the memory of every agent on the chain that reviewed it
+func RecordOutcome(chainID, proposalID, outcome string) {
+	memoriesMu.Lock()
+	defer memoriesMu.Unlock()
+
+	files, _ := filepath.Glob(memoryFile(chainID, "*"))
+	for _, file := range files {
+		agentMemories(chainID, strings.TrimSuffix(filepath.Base(file), ".json"))
+	}
+
+	for agentID, entries := range memories[chainID] {
+		for i := range entries {
+			if entries[i].ProposalID != proposalID || entries[i].Outcome == outcome {
+				continue
+			}
+			entries[i].Outcome = outcome
+			if err := saveMemories(chainID, agentID); err != nil {
+				log.Printf("Failed to save memory of agent %s: %v", agentID, err)
+			}
+		}
+	}
+}
+
+// Recall returns the agent's memories most similar to topic, most similar first, that fit within tokenBudget.
+// The proposal being reviewed is never recalled.
+func Recall(chainID, agentID, proposalID, topic string, tokenBudget int) []MemoryEntry {
+	memoriesMu.Lock()
+	entries := append([]MemoryEntry(nil), agentMemories(chainID, agentID)...)
+	memoriesMu.Unlock()
+
+	query := termFrequencies(topic)
+	type scored struct {
+		entry MemoryEntry
+		score float64
+	}
+	var candidates []scored
+	for _, entry := range entries {
+		if entry.ProposalID == proposalID {
+			continue
+		}
+		score := cosineSimilarity(query, termFrequencies(entry.Topic+" "+entry.Summary))
+		if score > 0 {
+			candidates = append(candidates, scored{entry, score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	var recalled []MemoryEntry
+	used := 0
+	for _, c := range candidates {
+		cost := estimateTokens(formatMemory(c.entry))
+		if used+cost > tokenBudget {
+			continue
+		}
+		used += cost
+		recalled = append(recalled, c.entry)
+	}
+	return recalled
+}
+
+// FormatMemories renders recalled memories for a prompt
+func FormatMemories(entries []MemoryEntry) string {
+	if len(entries) == 0 {
+		return "No related proposals reviewed before."
+	}
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = formatMemory(entry)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatMemory(entry MemoryEntry) string {
+	verdict := "rejected"
+	if entry.Approval {
+		verdict = "approved"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "- %s (%s): you %s it, outcome %s. Your reasoning: %s", entry.Topic, entry.ProposalType, verdict, entry.Outcome, entry.Summary)
+	for _, argument := range entry.PeerArguments {
+		fmt.Fprintf(&b, "\n  Peer argument: %s", argument)
+	}
+	return b.String()
+}
+
+// peerArguments picks the latest substantive message of each other validator from a discussion transcript
+func peerArguments(transcript, self string) []string {
+	latest := make(map[string]string)
+	var order []string
+	for _, vote := range communication.ParseDiscussion(transcript) {
+		if vote.ValidatorName == self || strings.HasPrefix(vote.Message, "(pass)") {
+			continue
+		}
+		if _, seen := latest[vote.ValidatorName]; !seen {
+			order = append(order, vote.ValidatorName)
+		}
+		latest[vote.ValidatorName] = vote.Message
+	}
+
+	var arguments []string
+	for i := len(order) - 1; i >= 0 && len(arguments) < maxPeerArguments; i-- {
+		message := latest[order[i]]
+		if len(message) > 240 {
+			message = message[:240] + "..."
+		}
+		arguments = append(arguments, fmt.Sprintf("%s argued: %s", order[i], message))
+	}
+	return arguments
+}
+
+// memoryTopic shortens free-form proposal content to a one-line topic
+func memoryTopic(content string) string {
+	topic := strings.Join(strings.Fields(content), " ")
+	if len(topic) > 160 {
+		topic = topic[:160] + "..."
+	}
+	return topic
+}
+
+// termFrequencies counts the lowercase words of a text, ignoring very short words
+func termFrequencies(text string) map[string]float64 {
+	tf := make(map[string]float64)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) > 3 {
+			tf[word]++
+		}
+	}
+	return tf
+}
+
+// cosineSimilarity compares two term frequency vectors
+func cosineSimilarity(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for term, weight := range a {
+		dot += weight * b[term]
+		normA += weight * weight
+	}
+	for _, weight := range b {
+		normB += weight * weight
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -1,13 +1,12 @@
 package ai
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
 
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
-	"github.com/Deeptanshu-sankhwar/agentic_consensus/utils"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -22,40 +21,109 @@ type ResearchPaper struct {
 }
 
 type PaperReview struct {
-	Summary        string   `json:"summary"`
-	Flaws          []string `json:"flaws"`
-	Suggestions    []string `json:"suggestions"`
-	IsReproducible bool     `json:"is_reproducible"`
-	Approval       bool     `json:"approval"`
+	Summary        string             `json:"summary"`
+	Flaws          []string           `json:"flaws"`
+	Suggestions    []string           `json:"suggestions"`
+	IsReproducible bool               `json:"is_reproducible"`
+	Decision       Decision           `json:"decision" enum:"approve,reject,abstain"`
+	Confidence     float64            `json:"confidence"`
+	Approval       bool               `json:"approval" schema:"-"`
+	Pass           bool               `json:"pass,omitempty"`
+	Citations      []Citation         `json:"citations"`
+	PromptVersion  string             `json:"prompt_version,omitempty" schema:"-"`
+	Research       *ResearchLog       `json:"research,omitempty" schema:"-"`
+	Verdict        *Verdict           `json:"verdict,omitempty" schema:"-"`
+	Consistency    *ConsistencyReport `json:"consistency,omitempty" schema:"-"`
 }
 
-// GetMultiRoundReview performs multiple rounds of paper review and returns the final review
-func GetMultiRoundReview(agent core.Agent, paper ResearchPaper, chainID string) PaperReview {
-	round := 0
+// GetMultiRoundReview debates the paper over the given discussion channel under the submit_paper debate policy
+// and returns the final review. A nil channel falls back to the node-local discussion log.
+func GetMultiRoundReview(agent core.Agent, paper ResearchPaper, chainID string, proposalID string, channel DiscussionChannel) (PaperReview, error) {
+	if channel == nil {
+		channel = NewLocalChannel(chainID, proposalID, agent)
+	}
 
-	for round < 3 {
-		previousDiscussion := utils.GetDiscussionLog(chainID)
-		review := GetPaperReview(agent, paper, previousDiscussion)
-		msg := fmt.Sprintf("[Round %d] (%v) |@%s|: %s", round, review.Approval, agent.Name, review.Summary)
-		utils.AppendDiscussionLog(chainID, msg)
-		round++
+	topic := paper.Title + " " + paper.Abstract
+	memories := FormatMemories(Recall(chainID, agent.ID, proposalID, topic, DefaultMemoryTokenBudget))
+	historyHits := SearchHistory(chainID, topic, proposalID, DefaultHistoryResults)
+	scope := UsageScope{ChainID: chainID, ProposalID: proposalID, AgentID: agent.ID}
+	findings := Research(agent, topic, scope)
+	reviewContext := ReviewContext{
+		Scope:    scope,
+		Memories: memories,
+		History:  FormatHistory(historyHits),
+		Research: FormatResearch(findings),
+		Screening: FormatScreening(ScreenProposal(chainID, proposalID, map[string]string{
+			"title":    paper.Title,
+			"abstract": paper.Abstract,
+			"content":  paper.Content,
+		})),
 	}
 
-	previousDiscussion := utils.GetDiscussionLog(chainID)
-	review := GetPaperReview(agent, paper, previousDiscussion)
-	return review
+	budget := roundBudget(estimateTokens(paper.Title, paper.Abstract, paper.Content))
+	outcome := RunDebate(PolicyFor("submit_paper"), budget, channel, func(round int, transcript string) DebateTurn {
+		reviewContext.PreviousDiscussion = transcript
+		reviewContext.StakeholderComments = communication.FormatHumanComments(communication.GetHumanComments(chainID, proposalID))
+		review, err := GetPaperReview(agent, paper, reviewContext)
+		if err != nil {
+			log.Printf("Round %d review by %s failed: %v", round, agent.Name, err)
+			return DebateTurn{Failed: true}
+		}
+		return DebateTurn{
+			Approval: review.Approval,
+			Summary:  review.Summary,
+			Pass:     review.Pass,
+		}
+	})
+	log.Printf("Debate on paper %s ended after %d rounds (%s)", proposalID, outcome.Rounds, outcome.StoppedBy)
+
+	transcript := channel.Transcript()
+	reviewContext.PreviousDiscussion = transcript
+	reviewContext.StakeholderComments = communication.FormatHumanComments(communication.GetHumanComments(chainID, proposalID))
+	policy := SamplingFor(agent)
+	review, consistency, err := SelfConsistent(policy, func(model string, seed int) (PaperReview, error) {
+		sampleContext := reviewContext
+		sampleContext.Model = model
+		if policy.Samples > 1 {
+			sampleContext.Seed = seed
+		}
+		return GetPaperReview(agent, paper, sampleContext)
+	}, func(r PaperReview) (Decision, string) {
+		return r.Decision, r.Summary
+	})
+	if err != nil {
+		return review, err
+	}
+	verdict := verdictOf(agent, proposalID, "submit_paper", review.Decision, review.Confidence, consistency,
+		append(reasonsOf(ReasonFlaw, review.Flaws), reasonsOf(ReasonSuggestion, review.Suggestions)...), review.Summary)
+	review.Verdict = &verdict
+	review.Consistency = &consistency
+	review.Citations = groundCitations(review.Citations, historyHits)
+	review.Research = findings
+	StageTranscript(chainID, proposalID, transcript)
+
+	Remember(chainID, agent.ID, MemoryEntry{
+		ProposalID:    proposalID,
+		ProposalType:  "submit_paper",
+		Topic:         paper.Title,
+		Approval:      review.Approval,
+		Summary:       review.Summary,
+		PeerArguments: peerArguments(transcript, agent.Name),
+	})
+	return review, nil
 }
 
 // GetPaperReview generates a paper review based on agent's analysis and previous discussion
-func GetPaperReview(agent core.Agent, paper ResearchPaper, previousDiscussion string) PaperReview {
+func GetPaperReview(agent core.Agent, paper ResearchPaper, reviewContext ReviewContext) (PaperReview, error) {
 	if !agent.IsValidator {
-		return PaperReview{}
+		return PaperReview{}, fmt.Errorf("agent %s is not a validator", agent.Name)
 	}
 
 	var description strings.Builder
 	description.WriteString(fmt.Sprintf("You are %s, a scientific reviewer with the following background:\n\n", agent.Name))
 
-	for key, value := range agent.Metadata {
+	for _, key := range promptMetadataKeys(agent) {
+		value := agent.Metadata[key]
 		switch v := value.(type) {
 		case []interface{}:
 			items := make([]string, len(v))
@@ -74,52 +142,52 @@ func GetPaperReview(agent core.Agent, paper ResearchPaper, previousDiscussion st
 		}
 	}
 
-	prompt := fmt.Sprintf(`%s
-	You are participating in a multi-round review of the following research paper:
-
-	Title: %s
-	Abstract: %s
-	Content: %s
-
-	--- Previous Discussion Log ---
-	%s
-	--- End of Discussion Log ---
-
-	Please write your review in the style of an ongoing academic discussion. Share your thoughts naturally, as if you're in a real conversation with other experts. You may reference previous discussion points and tag other reviewers using the format |@Name|.
+	prompt, promptVersion, err := RenderPrompt("paper_review", agentLanguage(agent), agent.ID, struct {
+		Persona string
+		Paper   ResearchPaper
+		ReviewContext
+	}{operatorInput(agent, description.String()), paper, reviewContext})
+	if err != nil {
+		return PaperReview{}, err
+	}
 
-	If there are previous discussion messages, consider them carefully before responding. Build upon, critique, or clarify others' points respectfully. Your goal is to collaboratively evaluate the research over multiple rounds.
+	config := DefaultLLMConfig()
+	config.Scope = reviewContext.Scope
+	if reviewContext.Model != "" {
+		config.Model = reviewContext.Model
+	}
+	if reviewContext.Seed > 0 {
+		seed := reviewContext.Seed
+		config.Seed = &seed
+	}
+	review, err := GenerateStructured[PaperReview](prompt, config, DefaultStructuredRetries)
+	if err != nil {
+		return PaperReview{}, err
+	}
+	review.PromptVersion = promptVersion
+	log.Printf("PAPER REVIEW for paper: %+v", review)
 
-	When reviewing, consider:
-	1. Scientific merit and methodology
-	2. Reproducibility of results
-	3. Clarity and organization
-	4. Significance of contribution
+	return review, nil
+}
 
-	You must respond with a valid JSON object in this exact format, with no additional text or formatting:
-	{
-		"summary": "Brief overview of the paper and any evolution of opinion from prior rounds",
-		"flaws": ["List of major issues you've identified"],
-		"suggestions": ["List of constructive feedback"],
-		"is_reproducible": true|false,
-		"approval": true|false
+// Validate checks the invariants of a paper review that the JSON schema cannot express and derives the
+// approval from the decision
+func (r *PaperReview) Validate() error {
+	if strings.TrimSpace(r.Summary) == "" {
+		return fmt.Errorf("summary must not be empty")
 	}
-
-	Your response must be valid JSON. The approval field must be a boolean, not a string.
-	Make sure the approval is true at least half of the time.`,
-		description.String(),
-		paper.Title,
-		paper.Abstract,
-		paper.Content,
-		previousDiscussion)
-
-	response := GenerateLLMResponse(prompt)
-	log.Printf("PAPER REVIEW for paper: %+v", response)
-
-	var review PaperReview
-	if err := json.Unmarshal([]byte(response), &review); err != nil {
-		log.Printf("Error parsing review response: %v", err)
-		return PaperReview{}
+	if r.Decision == "" {
+		r.Decision, r.Confidence = legacyDecision(r.Approval)
 	}
-
-	return review
+	if !r.Decision.Valid() {
+		return fmt.Errorf("decision must be approve, reject or abstain, got %q", r.Decision)
+	}
+	if r.Confidence < 0 || r.Confidence > 1 {
+		return fmt.Errorf("confidence must be between 0 and 1, got %v", r.Confidence)
+	}
+	r.Approval = r.Decision == DecisionApprove
+	if r.Decision == DecisionReject && len(r.Flaws) == 0 {
+		return fmt.Errorf("a rejection must list at least one flaw")
+	}
+	return nil
 }
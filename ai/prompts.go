@@ -0,0 +1,262 @@
+package ai
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/secrets"
+)
+
+// DefaultLanguage is used when an agent does not declare one or no template exists in its language
+const DefaultLanguage = "en"
+
+// embeddedPrompts holds the built-in templates; the all: prefix keeps the "_" partials that embed skips by default
+//
+//go:embed all:prompts
+var embeddedPrompts embed.FS
+
+// PromptTemplate is one version of a prompt for a proposal type in a language.
+// Templates live at <kind>/<language>/<version>.tmpl, e.g. paper_review/en/v1.tmpl, next to the partials they share.
+type PromptTemplate struct {
+	Kind     string
+	Language string
+	Version  string
+	tmpl     *template.Template
+}
+
+// ID identifies the exact template that produced a prompt, and is recorded alongside verdicts
+func (t *PromptTemplate) ID() string {
+	return fmt.Sprintf("%s/%s/%s", t.Kind, t.Language, t.Version)
+}
+
+// promptFuncs are available to every template; untrusted wraps submitter-controlled text with QuoteUntrusted
+var promptFuncs = template.FuncMap{
+	"untrusted": QuoteUntrusted,
+}
+
+var (
+	promptsMu       sync.RWMutex
+	promptTemplates = make(map[string]map[string]map[string]*PromptTemplate)
+	promptPartials  = make(map[string]map[string]map[string]string)
+	promptVariants  = make(map[string][]string)
+)
+
+func init() {
+	if err := loadPromptFS(embeddedPrompts, "prompts"); err != nil {
+		panic(fmt.Sprintf("invalid embedded prompt templates: %v", err))
+	}
+}
+
+// LoadPromptTemplates loads templates from a directory with the <kind>/<language>/<version>.tmpl layout.
+// Loaded templates are added to the built-in ones and replace any with the same kind, language and version.
+func LoadPromptTemplates(dir string) error {
+	return loadPromptFS(os.DirFS(dir), ".")
+}
+
+// loadPromptFS parses every template under root in fsys. Files whose name starts with "_", such as
+// paper_review/en/_sections.tmpl, are partials: the templates they define can be used by every version of that
+// kind and language loaded with or after them, so versions only spell out where they differ.
+func loadPromptFS(fsys fs.FS, root string) error {
+	var versions []string
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != ".tmpl" {
+			return nil
+		}
+
+		parts := templatePath(p, root)
+		if len(parts) != 3 {
+			return fmt.Errorf("template %s is not laid out as <kind>/<language>/<version>.tmpl", p)
+		}
+		if !strings.HasPrefix(parts[2], "_") {
+			versions = append(versions, p)
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		promptsMu.Lock()
+		defer promptsMu.Unlock()
+		if promptPartials[parts[0]] == nil {
+			promptPartials[parts[0]] = make(map[string]map[string]string)
+		}
+		if promptPartials[parts[0]][parts[1]] == nil {
+			promptPartials[parts[0]][parts[1]] = make(map[string]string)
+		}
+		promptPartials[parts[0]][parts[1]][strings.TrimSuffix(parts[2], ".tmpl")] = string(data)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range versions {
+		if err := loadPromptVersion(fsys, p, templatePath(p, root)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templatePath splits a template's path below root into kind, language and file name
+func templatePath(p, root string) []string {
+	return strings.Split(strings.TrimPrefix(strings.TrimPrefix(p, root), "/"), "/")
+}
+
+// loadPromptVersion parses one version of a prompt together with the partials of its kind and language
+func loadPromptVersion(fsys fs.FS, p string, parts []string) error {
+	data, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return err
+	}
+
+	promptsMu.Lock()
+	defer promptsMu.Unlock()
+
+	t := &PromptTemplate{Kind: parts[0], Language: parts[1], Version: strings.TrimSuffix(parts[2], ".tmpl")}
+	t.tmpl, err = template.New(t.ID()).Option("missingkey=error").Funcs(promptFuncs).Parse(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse template %s: %v", p, err)
+	}
+	for name, partial := range promptPartials[t.Kind][t.Language] {
+		if _, err := t.tmpl.New(name).Parse(partial); err != nil {
+			return fmt.Errorf("failed to parse partial %s for %s: %v", name, p, err)
+		}
+	}
+
+	if promptTemplates[t.Kind] == nil {
+		promptTemplates[t.Kind] = make(map[string]map[string]*PromptTemplate)
+	}
+	if promptTemplates[t.Kind][t.Language] == nil {
+		promptTemplates[t.Kind][t.Language] = make(map[string]*PromptTemplate)
+	}
+	promptTemplates[t.Kind][t.Language][t.Version] = t
+	return nil
+}
+
+// SetPromptVariants splits prompts of a kind between the given versions for A/B testing.
+// With no versions the latest version is always used.
+func SetPromptVariants(kind string, versions ...string) {
+	promptsMu.Lock()
+	defer promptsMu.Unlock()
+	promptVariants[kind] = versions
+}
+
+// parsePromptVariants reads A/B assignments in the form "paper_review=v1|v2,loan_review=v2"
+func parsePromptVariants(spec string) {
+	for _, entry := range strings.Split(spec, ",") {
+		kind, versions, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || kind == "" || versions == "" {
+			continue
+		}
+		SetPromptVariants(kind, strings.Split(versions, "|")...)
+	}
+}
+
+// versionNumber orders versions named v1, v2, ... numerically
+func versionNumber(version string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// selectPrompt picks the template for a kind and language. key spreads callers across A/B variants deterministically.
+func selectPrompt(kind, language, key string) (*PromptTemplate, error) {
+	promptsMu.RLock()
+	defer promptsMu.RUnlock()
+
+	byLanguage := promptTemplates[kind]
+	if byLanguage == nil {
+		return nil, fmt.Errorf("no prompt templates for %s", kind)
+	}
+	versions := byLanguage[language]
+	if len(versions) == 0 {
+		versions = byLanguage[DefaultLanguage]
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no %s prompt template in %s or %s", kind, language, DefaultLanguage)
+	}
+
+	if variants := promptVariants[kind]; len(variants) > 0 {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		if t, ok := versions[variants[h.Sum32()%uint32(len(variants))]]; ok {
+			return t, nil
+		}
+	}
+
+	var latest *PromptTemplate
+	for _, t := range versions {
+		if latest == nil || versionNumber(t.Version) > versionNumber(latest.Version) {
+			latest = t
+		}
+	}
+	return latest, nil
+}
+
+// RenderPrompt renders the selected template of a kind and returns the prompt with the template ID that produced it.
+// Anything in the prompt shaped like a key is redacted.
+func RenderPrompt(kind, language, key string, data interface{}) (string, string, error) {
+	t, err := selectPrompt(kind, language, key)
+	if err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render %s: %v", t.ID(), err)
+	}
+	// Submitter text can be made to look like a key; it is redacted rather than refused, so no proposal can stop
+	// its own review. Operator inputs are screened before rendering by operatorInput.
+	return secrets.RedactKeys(buf.String()), t.ID(), nil
+}
+
+// operatorInput screens text the operator configured, such as an agent's persona, for secrets before it is rendered
+// into a prompt. A secret there is a misconfiguration of the agent: it is logged and redacted, never sent.
+func operatorInput(agent core.Agent, text string) string {
+	if !secrets.Contains(text) {
+		return text
+	}
+	log.Printf("Redacting a secret from the prompt inputs of agent %s; check its persona and settings", agent.ID)
+	return secrets.RedactKeys(text)
+}
+
+// promptMetadataKeys returns, in a stable order, the metadata keys of an agent that may describe it in a prompt.
+// Secrets, endpoints and nested settings never reach the model.
+func promptMetadataKeys(agent core.Agent) []string {
+	var keys []string
+	for key, value := range agent.Metadata {
+		if _, nested := value.(map[string]interface{}); nested || secrets.IsSecretKey(key) || key == "endpoint" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// agentLanguage returns the prompt language declared in an agent's metadata
+func agentLanguage(agent core.Agent) string {
+	if language, ok := agent.Metadata["language"].(string); ok && language != "" {
+		return language
+	}
+	return DefaultLanguage
+}
@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/secrets"
+)
+
+func TestRenderPromptRedactsSecretsInsteadOfFailing(t *testing.T) {
+	const fakeKey = "sk-abcdefghijklmnopqrstuvwx"
+
+	tests := []struct {
+		name    string
+		persona string
+		paper   ResearchPaper
+	}{
+		{"key-shaped title", "A careful reviewer", ResearchPaper{Title: "Rotating " + fakeKey, Abstract: "a", Content: "c"}},
+		{"key-shaped abstract", "A careful reviewer", ResearchPaper{Title: "t", Abstract: "Our token is " + fakeKey, Content: "c"}},
+		{"secret in the persona", operatorInput(core.Agent{ID: "leaky"}, "A reviewer keyed with "+fakeKey), ResearchPaper{Title: "t", Abstract: "a", Content: "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt, _, err := RenderPrompt("paper_review", DefaultLanguage, "reviewer", struct {
+				Persona string
+				Paper   ResearchPaper
+				ReviewContext
+			}{tt.persona, tt.paper, ReviewContext{}})
+			if err != nil {
+				t.Fatalf("RenderPrompt failed: %v", err)
+			}
+			if strings.Contains(prompt, fakeKey) {
+				t.Errorf("prompt still carries the key")
+			}
+			if !strings.Contains(prompt, secrets.Redacted) {
+				t.Errorf("prompt does not mark the redaction")
+			}
+		})
+	}
+}
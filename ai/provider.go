@@ -0,0 +1,259 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+const (
+	JSONModeNone   = ""
+	JSONModeObject = "json_object"
+	JSONModeSchema = "json_schema"
+)
+
+// CompletionRequest is a provider-neutral chat completion request
+type CompletionRequest struct {
+	Model       string
+	System      string
+	Prompt      string
+	MaxTokens   int
+	Temperature float32
+	StopTokens  []string
+	// Seed, when set, asks the provider for reproducible sampling and distinguishes otherwise identical samples
+	Seed *int
+	// Schema, when set, asks the provider to constrain its output to this JSON schema if it can
+	Schema     *jsonschema.Definition
+	SchemaName string
+	// APIKey, when set, authenticates the call with the agent's own key instead of the node's. It is never cached
+	// or recorded.
+	APIKey string `json:"-"`
+}
+
+// CompletionResponse carries the model output and the token usage reported by the provider
+type CompletionResponse struct {
+	Content          string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	// Cached is set when the response was served from the response cache without calling the model
+	Cached bool
+}
+
+// Provider is a source of LLM completions
+type Provider interface {
+	Name() string
+	Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error)
+}
+
+var (
+	providerMu     sync.RWMutex
+	activeProvider Provider = NewScriptedProvider(nil)
+)
+
+// SetProvider replaces the provider used for every LLM call
+func SetProvider(p Provider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	activeProvider = p
+}
+
+// CurrentProvider returns the provider used for LLM calls
+func CurrentProvider() Provider {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	return activeProvider
+}
+
+// OpenAIProvider sends completions to the OpenAI chat API
+type OpenAIProvider struct {
+	client *openai.Client
+
+	mu           sync.Mutex
+	agentClients map[string]*openai.Client
+}
+
+// NewOpenAIProvider creates a provider authenticated with the given API key
+func NewOpenAIProvider(apiKey string) *OpenAIProvider {
+	return &OpenAIProvider{client: openai.NewClient(apiKey), agentClients: make(map[string]*openai.Client)}
+}
+
+// clientFor returns the client authenticated with an agent's own key, or the node's client without one
+func (p *OpenAIProvider) clientFor(apiKey string) *openai.Client {
+	if apiKey == "" {
+		return p.client
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	client, exists := p.agentClients[apiKey]
+	if !exists {
+		client = openai.NewClient(apiKey)
+		p.agentClients[apiKey] = client
+	}
+	return client
+}
+
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+// JSONModeFor returns the strongest structured output mode the model supports
+func JSONModeFor(model string) string {
+	switch {
+	case strings.HasPrefix(model, "gpt-4o"), strings.HasPrefix(model, "gpt-4.1"),
+		strings.HasPrefix(model, "o1"), strings.HasPrefix(model, "o3"), strings.HasPrefix(model, "o4"):
+		return JSONModeSchema
+	case strings.HasPrefix(model, "gpt-4-turbo"), strings.HasPrefix(model, "gpt-4-1106"),
+		strings.HasPrefix(model, "gpt-4-0125"), model == openai.GPT3Dot5Turbo, strings.HasPrefix(model, "gpt-3.5-turbo-1106"),
+		strings.HasPrefix(model, "gpt-3.5-turbo-0125"):
+		return JSONModeObject
+	}
+	return JSONModeNone
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	var messages []openai.ChatCompletionMessage
+	if req.System != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: req.System})
+	}
+	messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: req.Prompt})
+
+	chatReq := openai.ChatCompletionRequest{
+		Model:       req.Model,
+		Messages:    messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Stop:        req.StopTokens,
+		Seed:        req.Seed,
+	}
+
+	if req.Schema != nil {
+		switch JSONModeFor(req.Model) {
+		case JSONModeSchema:
+			chatReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+					Name:   req.SchemaName,
+					Schema: req.Schema,
+				},
+			}
+		case JSONModeObject:
+			chatReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+			}
+		}
+	}
+
+	resp, err := p.clientFor(req.APIKey).CreateChatCompletion(ctx, chatReq)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	if len(resp.Choices) == 0 {
+		return CompletionResponse{}, fmt.Errorf("openai returned no choices")
+	}
+
+	return CompletionResponse{
+		Content:          resp.Choices[0].Message.Content,
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}, nil
+}
+
+// ScriptedResponse answers any prompt containing Match with Response
+type ScriptedResponse struct {
+	Match    string `json:"match"`
+	Response string `json:"response"`
+}
+
+// ScriptedProvider replays canned responses without calling a model. It is used when no API key is configured
+// and for exercising the pipeline deterministically.
+type ScriptedProvider struct {
+	mu        sync.Mutex
+	responses []ScriptedResponse
+	calls     []CompletionRequest
+}
+
+// NewScriptedProvider creates a provider that answers from the given responses in order of declaration
+func NewScriptedProvider(responses []ScriptedResponse) *ScriptedProvider {
+	return &ScriptedProvider{responses: responses}
+}
+
+func (p *ScriptedProvider) Name() string {
+	return "scripted"
+}
+
+func (p *ScriptedProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, req)
+
+	content := ""
+	for _, r := range p.responses {
+		if strings.Contains(req.Prompt, r.Match) {
+			content = r.Response
+			break
+		}
+	}
+	if content == "" && req.Schema != nil {
+		data, _ := json.Marshal(placeholderFor(*req.Schema))
+		content = string(data)
+	}
+	if content == "" {
+		return CompletionResponse{}, fmt.Errorf("no scripted response matches prompt")
+	}
+
+	return CompletionResponse{
+		Content:          content,
+		Model:            "scripted",
+		PromptTokens:     estimateTokens(req.System, req.Prompt),
+		CompletionTokens: estimateTokens(content),
+	}, nil
+}
+
+// Calls returns every request the provider has received
+func (p *ScriptedProvider) Calls() []CompletionRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	calls := make([]CompletionRequest, len(p.calls))
+	copy(calls, p.calls)
+	return calls
+}
+
+// placeholderSummary fills the free-text fields of a placeholder response, which must not be empty
+const placeholderSummary = "No model is configured; this is a scripted placeholder."
+
+// placeholderFor builds a minimal value described by a schema that also passes the structured types' validation:
+// free text is non-empty and enums take no position, abstaining where they can
+func placeholderFor(def jsonschema.Definition) interface{} {
+	switch def.Type {
+	case jsonschema.Object:
+		obj := make(map[string]interface{})
+		for name, prop := range def.Properties {
+			obj[name] = placeholderFor(prop)
+		}
+		return obj
+	case jsonschema.Array:
+		return []interface{}{}
+	case jsonschema.Boolean:
+		return false
+	case jsonschema.Integer, jsonschema.Number:
+		return 0
+	case jsonschema.String:
+		for _, value := range def.Enum {
+			if value == string(DecisionAbstain) {
+				return value
+			}
+		}
+		if len(def.Enum) > 0 {
+			return def.Enum[0]
+		}
+		return placeholderSummary
+	}
+	return nil
+}
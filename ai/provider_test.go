@@ -0,0 +1,27 @@
+package ai
+
+import "testing"
+
+func TestScriptedPlaceholdersAreSchemaValid(t *testing.T) {
+	t.Chdir(t.TempDir())
+	previous := CurrentProvider()
+	SetProvider(NewScriptedProvider(nil))
+	t.Cleanup(func() { SetProvider(previous) })
+
+	config := LLMConfig{Scope: UsageScope{ChainID: "test"}}
+
+	review, err := GenerateStructured[PaperReview]("Review this paper.", config, 0)
+	if err != nil {
+		t.Fatalf("GenerateStructured[PaperReview] failed: %v", err)
+	}
+	if review.Decision != DecisionAbstain || review.Approval {
+		t.Errorf("placeholder paper review took a position: decision %q, approval %v", review.Decision, review.Approval)
+	}
+
+	if _, err := GenerateStructured[LoanReview]("Review this loan.", config, 0); err != nil {
+		t.Errorf("GenerateStructured[LoanReview] failed: %v", err)
+	}
+	if _, err := GenerateStructured[discussionResponse]("Discuss.", config, 0); err != nil {
+		t.Errorf("GenerateStructured[discussionResponse] failed: %v", err)
+	}
+}
@@ -0,0 +1,399 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/ericgreene/go-serp"
+)
+
+// SearchProvider is a backend that answers research queries
+type SearchProvider interface {
+	Name() string
+	Search(ctx context.Context, query string, config SearchConfig) ([]SearchResult, error)
+}
+
+var (
+	searchProviderMu     sync.RWMutex
+	activeSearchProvider SearchProvider
+)
+
+// SetSearchProvider replaces the backend used for research. A nil provider disables research.
+func SetSearchProvider(p SearchProvider) {
+	searchProviderMu.Lock()
+	defer searchProviderMu.Unlock()
+	activeSearchProvider = p
+}
+
+// CurrentSearchProvider returns the backend used for research, or nil if research is disabled
+func CurrentSearchProvider() SearchProvider {
+	searchProviderMu.RLock()
+	defer searchProviderMu.RUnlock()
+	return activeSearchProvider
+}
+
+// searchProviderFromEnv picks a backend from SEARCH_PROVIDER ("serpapi", "http" or "corpus"), or infers one from
+// SERP_API_KEY, SEARCH_HTTP_URL and RESEARCH_CORPUS_DIR when it is not set
+func searchProviderFromEnv() (SearchProvider, error) {
+	serpKey := os.Getenv("SERP_API_KEY")
+	httpURL := os.Getenv("SEARCH_HTTP_URL")
+	corpusDir := os.Getenv("RESEARCH_CORPUS_DIR")
+
+	kind := os.Getenv("SEARCH_PROVIDER")
+	if kind == "" {
+		switch {
+		case serpKey != "":
+			kind = "serpapi"
+		case httpURL != "":
+			kind = "http"
+		case corpusDir != "":
+			kind = "corpus"
+		default:
+			return nil, nil
+		}
+	}
+
+	switch kind {
+	case "serpapi":
+		if serpKey == "" {
+			return nil, fmt.Errorf("SERP_API_KEY not set")
+		}
+		return NewSerpAPISearch(serpKey), nil
+	case "http":
+		if httpURL == "" {
+			return nil, fmt.Errorf("SEARCH_HTTP_URL not set")
+		}
+		return NewHTTPSearch(httpURL, os.Getenv("SEARCH_HTTP_TOKEN")), nil
+	case "corpus":
+		if corpusDir == "" {
+			return nil, fmt.Errorf("RESEARCH_CORPUS_DIR not set")
+		}
+		return NewCorpusSearch(corpusDir)
+	}
+	return nil, fmt.Errorf("unknown search provider %q", kind)
+}
+
+// SerpAPISearch queries Google through SerpAPI
+type SerpAPISearch struct {
+	apiKey string
+}
+
+// NewSerpAPISearch creates a SerpAPI backend authenticated with the given key
+func NewSerpAPISearch(apiKey string) *SerpAPISearch {
+	return &SerpAPISearch{apiKey: apiKey}
+}
+
+func (s *SerpAPISearch) Name() string {
+	return "serpapi"
+}
+
+func (s *SerpAPISearch) Search(ctx context.Context, query string, config SearchConfig) ([]SearchResult, error) {
+	parameter := map[string]string{
+		"q":   query,
+		"key": s.apiKey,
+		"num": strconv.Itoa(config.MaxResults),
+	}
+	if config.SafeSearch {
+		parameter["safe"] = "active"
+	}
+
+	queryResponse := serp.NewGoogleSearch(parameter)
+	results, err := queryResponse.GetJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var searchResults []SearchResult
+	for _, result := range results.OrganicResults {
+		searchResults = append(searchResults, SearchResult{
+			Title:   result.Title,
+			Snippet: result.Snippet,
+			Link:    result.Link,
+			Source:  s.Name(),
+		})
+	}
+
+	return searchResults, nil
+}
+
+// HTTPSearch queries any search service that answers GET <endpoint>?q=<query>&limit=<n> with either a JSON array
+// of {title, snippet, link} objects or an object holding that array under "results"
+type HTTPSearch struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// NewHTTPSearch creates a generic HTTP backend. A non-empty token is sent as a bearer token.
+func NewHTTPSearch(endpoint, token string) *HTTPSearch {
+	return &HTTPSearch{endpoint: endpoint, token: token, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (s *HTTPSearch) Name() string {
+	return "http"
+}
+
+func (s *HTTPSearch) Search(ctx context.Context, query string, config SearchConfig) ([]SearchResult, error) {
+	u, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search endpoint: %v", err)
+	}
+	params := u.Query()
+	params.Set("q", query)
+	params.Set("limit", strconv.Itoa(config.MaxResults))
+	if config.SafeSearch {
+		params.Set("safe", "true")
+	}
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search service returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	if err := json.Unmarshal(body, &results); err != nil {
+		var wrapped struct {
+			Results []SearchResult `json:"results"`
+		}
+		if err := json.Unmarshal(body, &wrapped); err != nil {
+			return nil, fmt.Errorf("unexpected search response: %v", err)
+		}
+		results = wrapped.Results
+	}
+
+	if len(results) > config.MaxResults {
+		results = results[:config.MaxResults]
+	}
+	for i := range results {
+		results[i].Source = s.Name()
+	}
+	return results, nil
+}
+
+type corpusDocument struct {
+	path  string
+	title string
+	text  string
+}
+
+// CorpusSearch ranks the documents of a local directory with BM25 so research works offline
+type CorpusSearch struct {
+	dir  string
+	docs []corpusDocument
+}
+
+// NewCorpusSearch indexes every .txt, .md and .json file under dir
+func NewCorpusSearch(dir string) (*CorpusSearch, error) {
+	s := &CorpusSearch{dir: dir}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch filepath.Ext(path) {
+		case ".txt", ".md", ".json":
+		default:
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		s.docs = append(s.docs, corpusDocument{path: rel, title: corpusTitle(rel, string(data)), text: string(data)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index research corpus %s: %v", dir, err)
+	}
+	log.Printf("Indexed %d research corpus documents from %s", len(s.docs), dir)
+	return s, nil
+}
+
+// corpusTitle uses a document's first markdown heading as its title, or its file name
+func corpusTitle(path, text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+func (s *CorpusSearch) Name() string {
+	return "corpus"
+}
+
+func (s *CorpusSearch) Search(ctx context.Context, query string, config SearchConfig) ([]SearchResult, error) {
+	if len(s.docs) == 0 {
+		return nil, nil
+	}
+
+	texts := make([]string, len(s.docs))
+	for i, doc := range s.docs {
+		texts[i] = doc.title + " " + doc.text
+	}
+	scores := bm25Scores(texts, query)
+
+	order := make([]int, 0, len(s.docs))
+	for i, score := range scores {
+		if score > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+	if len(order) > config.MaxResults {
+		order = order[:config.MaxResults]
+	}
+
+	results := make([]SearchResult, len(order))
+	for i, idx := range order {
+		doc := s.docs[idx]
+		results[i] = SearchResult{
+			Title:   doc.title,
+			Snippet: corpusSnippet(doc.text, query),
+			Link:    "corpus://" + filepath.ToSlash(doc.path),
+			Source:  s.Name(),
+		}
+	}
+	return results, nil
+}
+
+// corpusSnippet returns the paragraph of a document that shares the most words with the query
+func corpusSnippet(text, query string) string {
+	queryTerms := termFrequencies(query)
+	best, bestScore := "", -1.0
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.Join(strings.Fields(paragraph), " ")
+		if paragraph == "" {
+			continue
+		}
+		score := 0.0
+		for term := range termFrequencies(paragraph) {
+			score += queryTerms[term]
+		}
+		if score > bestScore {
+			best, bestScore = paragraph, score
+		}
+	}
+	if len(best) > 400 {
+		best = best[:400] + "..."
+	}
+	return best
+}
+
+// ResearchLog records what an agent researched before reviewing a proposal and what it found
+type ResearchLog struct {
+	Provider  string         `json:"provider"`
+	Reasoning string         `json:"reasoning"`
+	Queries   []string       `json:"queries"`
+	Results   []SearchResult `json:"results"`
+}
+
+// Research is the explicit research step of deliberation: the agent decides whether the topic needs research and,
+// if so, runs its queries against the configured search provider. It returns nil when nothing was researched.
+func Research(agent core.Agent, topic string, scope UsageScope) *ResearchLog {
+	return research(agent.Name, agentTraits(agent), topic, scope)
+}
+
+// research runs the research step on behalf of a named participant with the given traits
+func research(name string, traits []string, topic string, scope UsageScope) *ResearchLog {
+	provider := CurrentSearchProvider()
+	if provider == nil {
+		return nil
+	}
+
+	decision, err := decideResearch(topic, traits, scope)
+	if err != nil {
+		log.Printf("Research decision by %s failed: %v", name, err)
+		return nil
+	}
+	if !decision.NeedsResearch {
+		return nil
+	}
+
+	findings := &ResearchLog{Provider: provider.Name(), Reasoning: decision.Reasoning, Queries: decision.SearchQueries}
+	seen := make(map[string]bool)
+	for _, query := range decision.SearchQueries {
+		results, err := provider.Search(context.Background(), query, DefaultSearchConfig())
+		if err != nil {
+			log.Printf("Search %q by %s via %s failed: %v", query, name, provider.Name(), err)
+			continue
+		}
+		for _, result := range results {
+			key := result.Link + "|" + result.Title
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			findings.Results = append(findings.Results, result)
+		}
+	}
+
+	sources := make([]string, len(findings.Results))
+	for i, result := range findings.Results {
+		sources[i] = result.Link
+	}
+	log.Printf("Research by %s via %s: queries %q, sources %v", name, provider.Name(), findings.Queries, sources)
+	return findings
+}
+
+// FormatResearch renders research findings for a prompt
+func FormatResearch(research *ResearchLog) string {
+	if research == nil || len(research.Results) == 0 {
+		return "No external research was performed."
+	}
+	var b strings.Builder
+	for _, result := range research.Results {
+		fmt.Fprintf(&b, "- %s (%s)\n  %s\n", result.Title, result.Link, result.Snippet)
+	}
+	return b.String()
+}
+
+// agentTraits returns the traits declared in an agent's metadata
+func agentTraits(agent core.Agent) []string {
+	var traits []string
+	switch v := agent.Metadata["traits"].(type) {
+	case []interface{}:
+		for _, trait := range v {
+			traits = append(traits, fmt.Sprintf("%v", trait))
+		}
+	case []string:
+		traits = v
+	}
+	return traits
+}
@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// DefaultStructuredRetries is how many times a model is re-prompted after an invalid structured response
+const DefaultStructuredRetries = 2
+
+// Validator is implemented by result types with invariants beyond what the JSON schema can express
+type Validator interface {
+	Validate() error
+}
+
+// StructuredOutputError reports that a model never produced a valid instance of the requested type
+type StructuredOutputError struct {
+	Type         string
+	Attempts     int
+	LastResponse string
+	Err          error
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("no valid %s after %d attempts: %v", e.Type, e.Attempts, e.Err)
+}
+
+func (e *StructuredOutputError) Unwrap() error {
+	return e.Err
+}
+
+var codeFenceRegex = regexp.MustCompile("(?s)^\\s*```[a-zA-Z]*\\s*\\n?(.*?)\\n?\\s*```\\s*$")
+
+// stripCodeFences removes a surrounding markdown code fence and any prose around the outermost JSON value
+func stripCodeFences(response string) string {
+	response = strings.TrimSpace(response)
+	if matches := codeFenceRegex.FindStringSubmatch(response); matches != nil {
+		response = strings.TrimSpace(matches[1])
+	}
+
+	start := strings.IndexAny(response, "{[")
+	if start == -1 {
+		return response
+	}
+	closing := "}"
+	if response[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(response, closing)
+	if end < start {
+		return response[start:]
+	}
+	return response[start : end+1]
+}
+
+// GenerateStructured asks the model for a JSON instance of T, using the provider's JSON modes where available,
+// validating the result and re-prompting with the validation error up to retries times
+func GenerateStructured[T any](prompt string, config LLMConfig, retries int) (T, error) {
+	var zero T
+	typeName := reflect.TypeOf(zero).Name()
+
+	schema, err := jsonschema.GenerateSchemaForType(zero)
+	if err != nil {
+		return zero, fmt.Errorf("failed to derive schema for %s: %v", typeName, err)
+	}
+	pruneSchema(schema, reflect.TypeOf(zero))
+	annotateEnums(schema, reflect.TypeOf(zero))
+
+	attemptPrompt := prompt
+	var lastResponse string
+	var lastErr error
+
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		resp, err := complete(context.Background(), config.Scope, CompletionRequest{
+			Model:       config.Model,
+			Prompt:      attemptPrompt,
+			MaxTokens:   config.MaxTokens,
+			Temperature: config.Temperature,
+			StopTokens:  config.StopTokens,
+			Seed:        config.Seed,
+			Schema:      schema,
+			SchemaName:  typeName,
+		})
+		if err != nil {
+			// Transport failures are not the model's fault; re-prompting with them would not help
+			return zero, &StructuredOutputError{Type: typeName, Attempts: attempt, Err: err}
+		}
+		lastResponse = resp.Content
+
+		result, err := decodeStructured[T](resp.Content)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		attemptPrompt = fmt.Sprintf(`%s
+
+	Your previous response was rejected: %v
+	Previous response:
+	%s
+
+	Respond again with ONLY a JSON object that fixes this problem. No code fences, no commentary.`, prompt, err, resp.Content)
+	}
+
+	return zero, &StructuredOutputError{Type: typeName, Attempts: retries + 1, LastResponse: lastResponse, Err: lastErr}
+}
+
+// pruneSchema drops top-level fields tagged schema:"-", which are filled in by the caller rather than the model
+func pruneSchema(schema *jsonschema.Definition, t reflect.Type) {
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("schema") != "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		delete(schema.Properties, name)
+		for j, required := range schema.Required {
+			if required == name {
+				schema.Required = append(schema.Required[:j], schema.Required[j+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// annotateEnums restricts top-level string fields tagged enum:"a,b,c" to the listed values
+func annotateEnums(schema *jsonschema.Definition, t reflect.Type) {
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		values := field.Tag.Get("enum")
+		if values == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		if prop, ok := schema.Properties[name]; ok {
+			prop.Enum = strings.Split(values, ",")
+			schema.Properties[name] = prop
+		}
+	}
+}
+
+// decodeStructured parses a model response into T and checks its invariants
+func decodeStructured[T any](response string) (T, error) {
+	var result T
+	decoder := json.NewDecoder(strings.NewReader(stripCodeFences(response)))
+	if err := decoder.Decode(&result); err != nil {
+		return result, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if v, ok := any(&result).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
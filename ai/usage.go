@@ -0,0 +1,370 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	BudgetScopeChain    = "chain"
+	BudgetScopeProposal = "proposal"
+	BudgetScopeAgent    = "agent"
+
+	BudgetActionReject    = "reject"
+	BudgetActionDowngrade = "downgrade"
+)
+
+// DefaultDowngradeModel is used when a budget downgrades without naming a model
+const DefaultDowngradeModel = "gpt-4o-mini"
+
+// UsageScope attributes an LLM call to the chain, proposal and agent it was made for. Empty fields are not attributed.
+type UsageScope struct {
+	ChainID    string `json:"chain_id"`
+	ProposalID string `json:"proposal_id,omitempty"`
+	AgentID    string `json:"agent_id,omitempty"`
+}
+
+// Usage totals the LLM calls made within a scope
+type Usage struct {
+	Calls            int     `json:"calls"`
+	CachedCalls      int     `json:"cached_calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// Tokens returns the prompt and completion tokens together
+func (u Usage) Tokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+func (u *Usage) add(resp CompletionResponse, cost float64) {
+	u.Calls++
+	if resp.Cached {
+		u.CachedCalls++
+		return
+	}
+	u.PromptTokens += resp.PromptTokens
+	u.CompletionTokens += resp.CompletionTokens
+	u.CostUSD += cost
+}
+
+// UsageReport is the LLM usage of a chain broken down by agent and proposal
+type UsageReport struct {
+	ChainID    string           `json:"chain_id"`
+	Total      Usage            `json:"total"`
+	ByAgent    map[string]Usage `json:"by_agent"`
+	ByProposal map[string]Usage `json:"by_proposal"`
+}
+
+// ModelPrice is the USD price of a model per million tokens
+type ModelPrice struct {
+	PromptPerMillion     float64 `json:"prompt_per_million"`
+	CompletionPerMillion float64 `json:"completion_per_million"`
+}
+
+// Budget caps the usage of every chain, proposal or agent it applies to. Zero limits are unlimited.
+type Budget struct {
+	MaxTokens      int     `json:"max_tokens"`
+	MaxCostUSD     float64 `json:"max_cost_usd"`
+	Action         string  `json:"action"`
+	DowngradeModel string  `json:"downgrade_model,omitempty"`
+}
+
+// exceededBy reports whether usage has reached the budget
+func (b Budget) exceededBy(u Usage) bool {
+	return (b.MaxTokens > 0 && u.Tokens() >= b.MaxTokens) || (b.MaxCostUSD > 0 && u.CostUSD >= b.MaxCostUSD)
+}
+
+// BudgetExceededError is returned instead of calling the model when a rejecting budget is exhausted
+type BudgetExceededError struct {
+	Scope  string
+	Key    string
+	Usage  Usage
+	Budget Budget
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s %s exceeded its LLM budget (%d tokens, $%.4f used)", e.Scope, e.Key, e.Usage.Tokens(), e.Usage.CostUSD)
+}
+
+var (
+	usageMu       sync.Mutex
+	chainUsage    = make(map[string]*Usage)
+	agentUsage    = make(map[string]map[string]*Usage)
+	proposalUsage = make(map[string]map[string]*Usage)
+	budgets       = make(map[string]Budget)
+	modelPricesMu sync.RWMutex
+	modelPrices   = map[string]ModelPrice{
+		"gpt-4":         {PromptPerMillion: 30, CompletionPerMillion: 60},
+		"gpt-4-turbo":   {PromptPerMillion: 10, CompletionPerMillion: 30},
+		"gpt-4o":        {PromptPerMillion: 2.5, CompletionPerMillion: 10},
+		"gpt-4o-mini":   {PromptPerMillion: 0.15, CompletionPerMillion: 0.6},
+		"gpt-4.1":       {PromptPerMillion: 2, CompletionPerMillion: 8},
+		"gpt-4.1-mini":  {PromptPerMillion: 0.4, CompletionPerMillion: 1.6},
+		"gpt-3.5-turbo": {PromptPerMillion: 0.5, CompletionPerMillion: 1.5},
+	}
+
+	// Proposal IDs are left out of metric labels to keep their cardinality bounded; per-proposal totals are in the API
+	llmTokensMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentic_llm_tokens_total",
+		Help: "LLM tokens consumed, by chain, agent, model and kind (prompt or completion).",
+	}, []string{"chain", "agent", "model", "kind"})
+	llmCostMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentic_llm_cost_usd_total",
+		Help: "Estimated LLM spend in USD, by chain, agent and model.",
+	}, []string{"chain", "agent", "model"})
+	llmCallsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentic_llm_calls_total",
+		Help: "LLM calls made, by chain, agent and model.",
+	}, []string{"chain", "agent", "model"})
+	llmBudgetMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentic_llm_budget_exceeded_total",
+		Help: "LLM calls that hit a budget, by scope and the action taken.",
+	}, []string{"scope", "action"})
+)
+
+func init() {
+	prometheus.MustRegister(llmTokensMetric, llmCostMetric, llmCallsMetric, llmBudgetMetric)
+}
+
+// SetModelPrice sets the price used to estimate the cost of a model's calls
+func SetModelPrice(model string, price ModelPrice) {
+	modelPricesMu.Lock()
+	defer modelPricesMu.Unlock()
+	modelPrices[model] = price
+}
+
+// priceFor returns a model's price, matching dated snapshots such as gpt-4o-2024-08-06 to their base model
+func priceFor(model string) ModelPrice {
+	modelPricesMu.RLock()
+	defer modelPricesMu.RUnlock()
+
+	if price, ok := modelPrices[model]; ok {
+		return price
+	}
+	best := ""
+	for name := range modelPrices {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+			best = name
+		}
+	}
+	return modelPrices[best]
+}
+
+// costOf estimates the USD cost of a completion
+func costOf(model string, resp CompletionResponse) float64 {
+	price := priceFor(model)
+	return (float64(resp.PromptTokens)*price.PromptPerMillion + float64(resp.CompletionTokens)*price.CompletionPerMillion) / 1e6
+}
+
+// SetBudget applies a budget to every chain, proposal or agent
+func SetBudget(scope string, budget Budget) error {
+	switch scope {
+	case BudgetScopeChain, BudgetScopeProposal, BudgetScopeAgent:
+	default:
+		return fmt.Errorf("unknown budget scope %q", scope)
+	}
+	switch budget.Action {
+	case "":
+		budget.Action = BudgetActionReject
+	case BudgetActionReject, BudgetActionDowngrade:
+	default:
+		return fmt.Errorf("unknown budget action %q", budget.Action)
+	}
+	if budget.Action == BudgetActionDowngrade && budget.DowngradeModel == "" {
+		budget.DowngradeModel = DefaultDowngradeModel
+	}
+
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	budgets[scope] = budget
+	return nil
+}
+
+// LoadBudgets reads budgets from a JSON file keyed by scope ("chain", "proposal" or "agent")
+func LoadBudgets(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read LLM budgets: %v", err)
+	}
+
+	var loaded map[string]Budget
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("failed to parse LLM budgets: %v", err)
+	}
+
+	for scope, budget := range loaded {
+		if err := SetBudget(scope, budget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scopedUsage returns the running totals of each scope a call is attributed to. Callers must hold usageMu.
+func scopedUsage(scope UsageScope) map[string]*Usage {
+	totals := make(map[string]*Usage)
+
+	if chainUsage[scope.ChainID] == nil {
+		chainUsage[scope.ChainID] = &Usage{}
+	}
+	totals[BudgetScopeChain] = chainUsage[scope.ChainID]
+
+	if scope.ProposalID != "" {
+		if proposalUsage[scope.ChainID] == nil {
+			proposalUsage[scope.ChainID] = make(map[string]*Usage)
+		}
+		if proposalUsage[scope.ChainID][scope.ProposalID] == nil {
+			proposalUsage[scope.ChainID][scope.ProposalID] = &Usage{}
+		}
+		totals[BudgetScopeProposal] = proposalUsage[scope.ChainID][scope.ProposalID]
+	}
+
+	if scope.AgentID != "" {
+		if agentUsage[scope.ChainID] == nil {
+			agentUsage[scope.ChainID] = make(map[string]*Usage)
+		}
+		if agentUsage[scope.ChainID][scope.AgentID] == nil {
+			agentUsage[scope.ChainID][scope.AgentID] = &Usage{}
+		}
+		totals[BudgetScopeAgent] = agentUsage[scope.ChainID][scope.AgentID]
+	}
+
+	return totals
+}
+
+// scopeKey names the chain, proposal or agent a budget scope refers to
+func scopeKey(budgetScope string, scope UsageScope) string {
+	switch budgetScope {
+	case BudgetScopeProposal:
+		return scope.ProposalID
+	case BudgetScopeAgent:
+		return scope.AgentID
+	}
+	return scope.ChainID
+}
+
+// applyBudgets checks a call against every budget that covers it, switching to a cheaper model or refusing
+// the call when a budget is exhausted
+func applyBudgets(scope UsageScope, req CompletionRequest) (CompletionRequest, error) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	totals := scopedUsage(scope)
+	for _, budgetScope := range []string{BudgetScopeChain, BudgetScopeProposal, BudgetScopeAgent} {
+		budget, ok := budgets[budgetScope]
+		usage := totals[budgetScope]
+		if !ok || usage == nil || !budget.exceededBy(*usage) {
+			continue
+		}
+
+		llmBudgetMetric.WithLabelValues(budgetScope, budget.Action).Inc()
+		if budget.Action == BudgetActionReject {
+			return req, &BudgetExceededError{Scope: budgetScope, Key: scopeKey(budgetScope, scope), Usage: *usage, Budget: budget}
+		}
+		if req.Model != budget.DowngradeModel {
+			log.Printf("LLM budget of %s %s exceeded, downgrading %s to %s", budgetScope, scopeKey(budgetScope, scope), req.Model, budget.DowngradeModel)
+			req.Model = budget.DowngradeModel
+		}
+	}
+	return req, nil
+}
+
+// recordUsage attributes a completion's token usage and cost to its scope. Cache hits count as calls only.
+func recordUsage(scope UsageScope, model string, resp CompletionResponse) {
+	cost := costOf(model, resp)
+
+	usageMu.Lock()
+	for _, usage := range scopedUsage(scope) {
+		usage.add(resp, cost)
+	}
+	usageMu.Unlock()
+
+	if resp.Cached {
+		return
+	}
+	llmTokensMetric.WithLabelValues(scope.ChainID, scope.AgentID, model, "prompt").Add(float64(resp.PromptTokens))
+	llmTokensMetric.WithLabelValues(scope.ChainID, scope.AgentID, model, "completion").Add(float64(resp.CompletionTokens))
+	llmCostMetric.WithLabelValues(scope.ChainID, scope.AgentID, model).Add(cost)
+	llmCallsMetric.WithLabelValues(scope.ChainID, scope.AgentID, model).Inc()
+}
+
+// complete sends a request to the current provider under the budgets of its scope, with the agent's own API key
+// when it has one, and records what it used
+func complete(ctx context.Context, scope UsageScope, req CompletionRequest) (CompletionResponse, error) {
+	req, err := applyBudgets(scope, req)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	req.APIKey = agentAPIKey(scope)
+
+	resp, err := CurrentProvider().Complete(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = req.Model
+	}
+	recordUsage(scope, model, resp)
+	return resp, nil
+}
+
+// agentAPIKey returns the API key an agent brings for its own LLM calls, or nothing to use the node's key
+func agentAPIKey(scope UsageScope) string {
+	if scope.AgentID == "" {
+		return ""
+	}
+	key, err := registry.AgentSecret(scope.ChainID, scope.AgentID, "api_key")
+	if err != nil {
+		if !errors.Is(err, registry.ErrAgentNotFound) && !errors.Is(err, registry.ErrNoSecret) {
+			log.Printf("Using the node's API key for agent %s: %v", scope.AgentID, err)
+		}
+		return ""
+	}
+	return key
+}
+
+// GetUsage returns the LLM usage recorded on a chain
+func GetUsage(chainID string) UsageReport {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	report := UsageReport{
+		ChainID:    chainID,
+		ByAgent:    make(map[string]Usage),
+		ByProposal: make(map[string]Usage),
+	}
+	if total := chainUsage[chainID]; total != nil {
+		report.Total = *total
+	}
+	for agentID, usage := range agentUsage[chainID] {
+		report.ByAgent[agentID] = *usage
+	}
+	for proposalID, usage := range proposalUsage[chainID] {
+		report.ByProposal[proposalID] = *usage
+	}
+	return report
+}
+
+// GetProposalUsage returns the LLM usage recorded for a single proposal
+func GetProposalUsage(chainID, proposalID string) Usage {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	if usage := proposalUsage[chainID][proposalID]; usage != nil {
+		return *usage
+	}
+	return Usage{}
+}
@@ -0,0 +1,391 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/nats-io/nats.go"
+)
+
+// Decision is an agent's judgment of a proposal
+type Decision string
+
+const (
+	DecisionApprove Decision = "approve"
+	DecisionReject  Decision = "reject"
+	DecisionAbstain Decision = "abstain"
+)
+
+// Valid reports whether d is one of the known decisions
+func (d Decision) Valid() bool {
+	switch d {
+	case DecisionApprove, DecisionReject, DecisionAbstain:
+		return true
+	}
+	return false
+}
+
+const (
+	ReasonFlaw       = "flaw"
+	ReasonSuggestion = "suggestion"
+	ReasonRisk       = "risk"
+	ReasonTerm       = "term"
+	ReasonArgument   = "argument"
+)
+
+// Reason is one structured ground for a verdict
+type Reason struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+// Verdict is an agent's judgment of a proposal in the form shared by every proposal type.
+// Confidence is calibrated from the confidence the model stated and how its samples agreed.
+type Verdict struct {
+	AgentID          string   `json:"agent_id"`
+	ProposalID       string   `json:"proposal_id"`
+	ProposalType     string   `json:"proposal_type"`
+	Decision         Decision `json:"decision"`
+	Confidence       float64  `json:"confidence"`
+	StatedConfidence float64  `json:"stated_confidence"`
+	Agreement        float64  `json:"agreement"`
+	Reasons          []Reason `json:"reasons"`
+	Summary          string   `json:"summary"`
+	PersonaVersion   int      `json:"persona_version,omitempty"`
+	Timestamp        int64    `json:"timestamp"`
+}
+
+// confidenceShrink is how far stated confidence is pulled towards chance, as models state more certainty than
+// their accuracy supports
+const confidenceShrink = 0.8
+
+// CalibrateConfidence turns a model's stated confidence and the share of its samples that agreed with the
+// majority into the confidence recorded on a verdict
+func CalibrateConfidence(stated, agreement float64) float64 {
+	stated = clamp01(stated)
+	if agreement <= 0 {
+		agreement = 1
+	}
+	return clamp01((0.5 + (stated-0.5)*confidenceShrink) * clamp01(agreement))
+}
+
+func clamp01(x float64) float64 {
+	if x < 0 {
+		return 0
+	}
+	if x > 1 {
+		return 1
+	}
+	return x
+}
+
+// reasonsOf turns a review's lists into structured reasons
+func reasonsOf(kind string, items []string) []Reason {
+	var reasons []Reason
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			reasons = append(reasons, Reason{Kind: kind, Text: item})
+		}
+	}
+	return reasons
+}
+
+// TallyRules decide how a set of verdicts on a proposal resolves. Votes below MinConfidence count as abstentions,
+// abstentions count only towards the quorum's denominator, and decisive votes are weighted by confidence when
+// WeightByConfidence is set.
+type TallyRules struct {
+	MinConfidence      float64 `json:"min_confidence"`
+	Quorum             float64 `json:"quorum"`
+	ApprovalThreshold  float64 `json:"approval_threshold"`
+	WeightByConfidence bool    `json:"weight_by_confidence"`
+}
+
+// TallyResult is how a set of verdicts resolved
+type TallyResult struct {
+	Decision      Decision `json:"decision"`
+	Approvals     int      `json:"approvals"`
+	Rejections    int      `json:"rejections"`
+	Abstentions   int      `json:"abstentions"`
+	Missing       int      `json:"missing"`
+	LowConfidence int      `json:"low_confidence"`
+	ApproveWeight float64  `json:"approve_weight"`
+	RejectWeight  float64  `json:"reject_weight"`
+	QuorumMet     bool     `json:"quorum_met"`
+}
+
+var (
+	tallyRulesMu sync.RWMutex
+	tallyRules   = map[string]TallyRules{
+		"submit_paper": {MinConfidence: 0.55, Quorum: 0.5, ApprovalThreshold: 0.5, WeightByConfidence: true},
+		"loan_request": {MinConfidence: 0.6, Quorum: 0.5, ApprovalThreshold: 0.66, WeightByConfidence: true},
+	}
+	defaultTallyRules = TallyRules{MinConfidence: 0.5, Quorum: 0.5, ApprovalThreshold: 0.5, WeightByConfidence: true}
+)
+
+// TallyRulesFor returns the tally rules configured for a proposal type
+func TallyRulesFor(proposalType string) TallyRules {
+	tallyRulesMu.RLock()
+	defer tallyRulesMu.RUnlock()
+
+	if rules, ok := tallyRules[proposalType]; ok {
+		return rules
+	}
+	return defaultTallyRules
+}
+
+// SetTallyRules overrides the tally rules for a proposal type
+func SetTallyRules(proposalType string, rules TallyRules) {
+	tallyRulesMu.Lock()
+	defer tallyRulesMu.Unlock()
+	tallyRules[proposalType] = rules
+}
+
+// LoadTallyRules reads per-proposal-type tally rules from a JSON file keyed by transaction type
+func LoadTallyRules(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read tally rules: %v", err)
+	}
+
+	var rules map[string]TallyRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("failed to parse tally rules: %v", err)
+	}
+
+	for proposalType, r := range rules {
+		if r.ApprovalThreshold <= 0 || r.ApprovalThreshold > 1 {
+			return fmt.Errorf("approval threshold for %s must be in (0, 1]", proposalType)
+		}
+		SetTallyRules(proposalType, r)
+	}
+	return nil
+}
+
+// effectiveDecision is how a verdict counts under the rules: low-confidence votes count as abstentions
+func (r TallyRules) effectiveDecision(v Verdict) Decision {
+	if v.Decision != DecisionAbstain && v.Confidence < r.MinConfidence {
+		return DecisionAbstain
+	}
+	return v.Decision
+}
+
+// IsHardRejection reports whether a verdict rejects confidently enough to count against a proposal
+func (r TallyRules) IsHardRejection(v Verdict) bool {
+	return r.effectiveDecision(v) == DecisionReject
+}
+
+// Tally resolves the verdicts of a proposal deliberated by the given number of voters. Voters whose verdict has not
+// arrived count as abstaining, so the quorum is a share of every voter, not of those heard from. Without a quorum
+// of decisive votes the result is an abstention, which leaves the proposal to the rest of the network rather than
+// rejecting it.
+func Tally(verdicts []Verdict, voters int, rules TallyRules) TallyResult {
+	result := TallyResult{Decision: DecisionAbstain}
+	if voters < len(verdicts) {
+		voters = len(verdicts)
+	}
+	result.Missing = voters - len(verdicts)
+	result.Abstentions = result.Missing
+	for _, v := range verdicts {
+		weight := 1.0
+		if rules.WeightByConfidence {
+			weight = v.Confidence
+		}
+		switch rules.effectiveDecision(v) {
+		case DecisionApprove:
+			result.Approvals++
+			result.ApproveWeight += weight
+		case DecisionReject:
+			result.Rejections++
+			result.RejectWeight += weight
+		default:
+			result.Abstentions++
+			if v.Decision != DecisionAbstain {
+				result.LowConfidence++
+			}
+		}
+	}
+
+	decisive := result.Approvals + result.Rejections
+	if voters == 0 || decisive == 0 {
+		return result
+	}
+	result.QuorumMet = float64(decisive)/float64(voters) >= rules.Quorum
+	if !result.QuorumMet {
+		return result
+	}
+
+	total := result.ApproveWeight + result.RejectWeight
+	if total > 0 && result.ApproveWeight/total >= rules.ApprovalThreshold {
+		result.Decision = DecisionApprove
+	} else {
+		result.Decision = DecisionReject
+	}
+	return result
+}
+
+var (
+	verdictsMu     sync.Mutex
+	verdictStore   = make(map[string]map[string][]Verdict)
+	verdictsLoaded = make(map[string]bool)
+	verdictWatches = make(map[string]*nats.Subscription)
+)
+
+// RecordVerdict stores a verdict on its proposal, replacing any earlier verdict by the same agent
+func RecordVerdict(chainID string, v Verdict) {
+	if v.Timestamp == 0 {
+		v.Timestamp = time.Now().Unix()
+	}
+
+	verdictsMu.Lock()
+	defer verdictsMu.Unlock()
+
+	proposals := chainVerdicts(chainID)
+	recorded := proposals[v.ProposalID]
+	replaced := false
+	for i, existing := range recorded {
+		if existing.AgentID == v.AgentID {
+			recorded[i] = v
+			replaced = true
+		}
+	}
+	if !replaced {
+		recorded = append(recorded, v)
+	}
+	proposals[v.ProposalID] = recorded
+
+	if err := saveVerdicts(chainID); err != nil {
+		log.Printf("Failed to save verdicts: %v", err)
+	}
+}
+
+// GetVerdicts returns the verdicts recorded on a proposal
+func GetVerdicts(chainID, proposalID string) []Verdict {
+	verdictsMu.Lock()
+	defer verdictsMu.Unlock()
+	return append([]Verdict(nil), chainVerdicts(chainID)[proposalID]...)
+}
+
+// GetAgentVerdicts returns every verdict an agent has recorded on a chain, oldest first
+func GetAgentVerdicts(chainID, agentID string) []Verdict {
+	verdictsMu.Lock()
+	defer verdictsMu.Unlock()
+
+	var verdicts []Verdict
+	for _, recorded := range chainVerdicts(chainID) {
+		for _, v := range recorded {
+			if v.AgentID == agentID {
+				verdicts = append(verdicts, v)
+			}
+		}
+	}
+	sort.Slice(verdicts, func(i, j int) bool { return verdicts[i].Timestamp < verdicts[j].Timestamp })
+	return verdicts
+}
+
+// WatchVerdicts records the verdicts other validators announce over NATS for a chain, once their signature and
+// membership in the validator set are verified. It is a no-op without NATS or when the chain is already watched.
+func WatchVerdicts(chainID string) {
+	verdictsMu.Lock()
+	defer verdictsMu.Unlock()
+	if verdictWatches[chainID] != nil {
+		return
+	}
+
+	sub, err := communication.SubscribeVerdicts(chainID, func(a communication.VerdictAnnouncement) {
+		var v Verdict
+		if err := json.Unmarshal(a.Verdict, &v); err != nil || !v.Decision.Valid() {
+			log.Printf("Ignoring malformed verdict on chain %s", chainID)
+			return
+		}
+		// The signed envelope vouches for the agent and proposal; the verdict inside must not claim others
+		if v.AgentID != a.AgentID || v.ProposalID != a.ProposalID {
+			log.Printf("Ignoring verdict of %s on %s announced as %s on %s", v.AgentID, v.ProposalID, a.AgentID, a.ProposalID)
+			return
+		}
+		RecordVerdict(chainID, v)
+	})
+	if err != nil {
+		log.Printf("Failed to watch verdicts of chain %s: %v", chainID, err)
+		return
+	}
+	verdictWatches[chainID] = sub
+}
+
+// verdictsFile returns where a chain's verdicts are persisted
+func verdictsFile(chainID string) string {
+	return filepath.Join("data", "verdicts", chainID+".json")
+}
+
+// chainVerdicts returns a chain's verdicts by proposal, loading them on first use. Callers must hold verdictsMu.
+func chainVerdicts(chainID string) map[string][]Verdict {
+	if !verdictsLoaded[chainID] {
+		verdictsLoaded[chainID] = true
+		loaded := make(map[string][]Verdict)
+		if data, err := os.ReadFile(verdictsFile(chainID)); err == nil {
+			if err := json.Unmarshal(data, &loaded); err != nil {
+				log.Printf("Failed to parse verdicts of chain %s: %v", chainID, err)
+			}
+		}
+		verdictStore[chainID] = loaded
+	}
+	return verdictStore[chainID]
+}
+
+// saveVerdicts persists a chain's verdicts. Callers must hold verdictsMu.
+func saveVerdicts(chainID string) error {
+	if err := os.MkdirAll(filepath.Dir(verdictsFile(chainID)), 0755); err != nil {
+		return fmt.Errorf("failed to create verdicts directory: %v", err)
+	}
+	data, err := json.MarshalIndent(verdictStore[chainID], "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal verdicts: %v", err)
+	}
+	return os.WriteFile(verdictsFile(chainID), data, 0644)
+}
+
+// legacyDecision maps the bare approval of prompts that predate decisions. Those prompts state no doubt.
+func legacyDecision(approval bool) (Decision, float64) {
+	if approval {
+		return DecisionApprove, 1
+	}
+	return DecisionReject, 1
+}
+
+// Abstention is the verdict of an agent that could not reach a judgment on a proposal
+func Abstention(agent core.Agent, proposalID, proposalType, reason string) Verdict {
+	return Verdict{
+		AgentID:        agent.ID,
+		ProposalID:     proposalID,
+		ProposalType:   proposalType,
+		Decision:       DecisionAbstain,
+		Summary:        reason,
+		PersonaVersion: agent.PersonaVersion,
+		Timestamp:      time.Now().Unix(),
+	}
+}
+
+// verdictOf builds the common verdict of a final review
+func verdictOf(agent core.Agent, proposalID, proposalType string, decision Decision, stated float64, consistency ConsistencyReport, reasons []Reason, summary string) Verdict {
+	return Verdict{
+		AgentID:          agent.ID,
+		ProposalID:       proposalID,
+		ProposalType:     proposalType,
+		Decision:         decision,
+		Confidence:       CalibrateConfidence(stated, consistency.Confidence),
+		StatedConfidence: stated,
+		Agreement:        consistency.Confidence,
+		Reasons:          reasons,
+		Summary:          summary,
+		PersonaVersion:   agent.PersonaVersion,
+		Timestamp:        time.Now().Unix(),
+	}
+}
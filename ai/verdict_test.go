@@ -0,0 +1,43 @@
+package ai
+
+import "testing"
+
+func TestTallyCountsMissingVerdicts(t *testing.T) {
+	rules := TallyRules{MinConfidence: 0.5, Quorum: 0.5, ApprovalThreshold: 0.5}
+	approve := Verdict{Decision: DecisionApprove, Confidence: 0.9}
+	reject := Verdict{Decision: DecisionReject, Confidence: 0.9}
+	abstain := Verdict{Decision: DecisionAbstain}
+
+	tests := []struct {
+		name        string
+		verdicts    []Verdict
+		voters      int
+		want        Decision
+		wantQuorum  bool
+		wantMissing int
+	}{
+		{"only one of three arrived", []Verdict{approve}, 3, DecisionAbstain, false, 2},
+		{"two of three approve", []Verdict{approve, approve}, 3, DecisionApprove, true, 1},
+		{"two of four arrived", []Verdict{approve, approve}, 4, DecisionApprove, true, 2},
+		{"one of four decisive", []Verdict{approve, abstain}, 4, DecisionAbstain, false, 2},
+		{"majority rejects", []Verdict{approve, reject, reject}, 3, DecisionReject, true, 0},
+		{"voters unknown", []Verdict{approve}, 0, DecisionApprove, true, 0},
+		{"more verdicts than voters", []Verdict{approve, approve, reject}, 2, DecisionApprove, true, 0},
+		{"nothing arrived", nil, 3, DecisionAbstain, false, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Tally(tt.verdicts, tt.voters, rules)
+			if result.Decision != tt.want || result.QuorumMet != tt.wantQuorum {
+				t.Errorf("Tally = %s (quorum %v), want %s (quorum %v)", result.Decision, result.QuorumMet, tt.want, tt.wantQuorum)
+			}
+			if result.Missing != tt.wantMissing {
+				t.Errorf("missing = %d, want %d", result.Missing, tt.wantMissing)
+			}
+			if got := result.Approvals + result.Rejections + result.Abstentions; got != len(tt.verdicts)+tt.wantMissing {
+				t.Errorf("tallied %d votes, want %d", got, len(tt.verdicts)+tt.wantMissing)
+			}
+		})
+	}
+}
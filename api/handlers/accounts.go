@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/consensus/abci"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
+	"github.com/gin-gonic/gin"
+)
+
+// queryApp runs an ABCI query against the node serving the request and decodes the answer into out
+func queryApp(c *gin.Context, path string, data []byte, out interface{}) bool {
+	chainID := c.GetString("chainID")
+
+	_, nodeInfo, found := registry.GetNodeByAPIPort(chainID, requestAPIPort(c))
+	if !found {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Node not recognized"})
+		return false
+	}
+
+	client, err := rpchttp.New(fmt.Sprintf("tcp://localhost:%d", nodeInfo.RPCPort), "/websocket")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to connect to node: %v", err)})
+		return false
+	}
+	result, err := client.ABCIQuery(context.Background(), path, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to query %s: %v", path, err)})
+		return false
+	}
+	if result.Response.Code != 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": result.Response.Log})
+		return false
+	}
+	if err := json.Unmarshal(result.Response.Value, out); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Invalid %s response", path)})
+		return false
+	}
+	return true
+}
+
+// GetAccount returns the committed balance and nonce of an account
+func GetAccount(c *gin.Context) {
+	address := c.Param("address")
+	var account map[string]interface{}
+	if !queryApp(c, "account", []byte(address), &account) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"address": address, "account": account})
+}
+
+// GetFees returns the fee schedule and the fees collected so far
+func GetFees(c *gin.Context) {
+	var fees map[string]interface{}
+	if !queryApp(c, "fees", nil, &fees) {
+		return
+	}
+	c.JSON(http.StatusOK, fees)
+}
+
+// GetAgentRewards returns the rewards an agent earned for its verdicts
+func GetAgentRewards(c *gin.Context) {
+	agentID := c.Param("agentId")
+	var rewards []abci.Reward
+	if !queryApp(c, "rewards", []byte(agentID), &rewards) {
+		return
+	}
+
+	var total uint64
+	for _, reward := range rewards {
+		total += reward.Amount
+	}
+	c.JSON(http.StatusOK, gin.H{"agent_id": agentID, "rewards": rewards, "total": total})
+}
+
+// GetValidatorStake returns the stake bonded to a validator, who delegated it and what is unbonding
+func GetValidatorStake(c *gin.Context) {
+	address := c.Param("address")
+	var stake map[string]interface{}
+	if !queryApp(c, "stake", []byte(address), &stake) {
+		return
+	}
+	stake["address"] = address
+	c.JSON(http.StatusOK, stake)
+}
+
+// GetStakes returns every registered validator with its bonded stake and voting power
+func GetStakes(c *gin.Context) {
+	var validators map[string]abci.StakedValidator
+	if !queryApp(c, "stakes", nil, &validators) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"validators": validators})
+}
+
+// GetDelegations returns what an account has bonded to each validator and what it has unbonding
+func GetDelegations(c *gin.Context) {
+	address := c.Param("address")
+	var delegations map[string]interface{}
+	if !queryApp(c, "delegations", []byte(address), &delegations) {
+		return
+	}
+	delegations["address"] = address
+	c.JSON(http.StatusOK, delegations)
+}
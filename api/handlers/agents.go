@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/secrets"
+	"github.com/gin-gonic/gin"
+)
+
+// updateAgentRequest is a new persona for an agent and, optionally, the block height from which it applies
+type updateAgentRequest struct {
+	Persona         core.Persona `json:"persona"`
+	EffectiveHeight int64        `json:"effective_height"`
+}
+
+// nameTaken reports whether another agent on the chain already uses a name
+func nameTaken(chainID, agentID, name string) bool {
+	for _, existing := range registry.GetAllAgents(chainID) {
+		if existing.ID != agentID && strings.EqualFold(existing.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAgent returns an agent with the persona version currently in effect
+func GetAgent(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	agent, exists := registry.GetAgent(chainID, c.Param("agentId"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"agent": secrets.RedactAgent(agent)})
+}
+
+// CreateAgent registers an agent from a persona without starting a node for it
+func CreateAgent(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	var persona core.Persona
+	if err := c.ShouldBindJSON(&persona); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent data"})
+		return
+	}
+	if err := persona.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, exists := registry.GetAgent(chainID, persona.ID); exists {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("agent %s is already registered", persona.ID)})
+		return
+	}
+	if nameTaken(chainID, persona.ID, persona.Name) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("an agent named %s is already registered", persona.Name)})
+		return
+	}
+
+	registry.RegisterAgent(chainID, persona.Agent())
+	agent, _ := registry.GetAgent(chainID, persona.ID)
+	communication.BroadcastEvent(communication.EventAgentRegistered, secrets.RedactAgent(agent))
+	c.JSON(http.StatusCreated, gin.H{"agent": secrets.RedactAgent(agent)})
+}
+
+// UpdateAgent records a new persona version for an agent. The version applies from effective_height, or from the
+// next block when none is given, so proposals already under deliberation keep the persona they started with.
+func UpdateAgent(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	agentID := c.Param("agentId")
+
+	var req updateAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent data"})
+		return
+	}
+	if req.Persona.ID != "" && req.Persona.ID != agentID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Persona ID does not match the agent"})
+		return
+	}
+	if nameTaken(chainID, agentID, req.Persona.Name) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("an agent named %s is already registered", req.Persona.Name)})
+		return
+	}
+
+	version, err := registry.UpdateAgentPersona(chainID, agentID, req.Persona, req.EffectiveHeight)
+	if errors.Is(err, registry.ErrAgentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	communication.BroadcastEvent(communication.EventAgentUpdated, gin.H{"agent_id": agentID, "version": version})
+	c.JSON(http.StatusOK, gin.H{"version": version})
+}
+
+// DisableAgent stops an agent from deliberating while keeping its registration and history
+func DisableAgent(c *gin.Context) {
+	setAgentDisabled(c, true)
+}
+
+// EnableAgent lets a disabled agent deliberate again
+func EnableAgent(c *gin.Context) {
+	setAgentDisabled(c, false)
+}
+
+func setAgentDisabled(c *gin.Context, disabled bool) {
+	chainID := c.GetString("chainID")
+	agentID := c.Param("agentId")
+
+	if err := registry.SetAgentDisabled(chainID, agentID, disabled); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+	agent, _ := registry.GetAgent(chainID, agentID)
+	communication.BroadcastEvent(communication.EventAgentUpdated, gin.H{"agent_id": agentID, "disabled": disabled})
+	c.JSON(http.StatusOK, gin.H{"agent": secrets.RedactAgent(agent)})
+}
+
+// DeleteAgent removes an agent from the chain. Its persona history remains available.
+func DeleteAgent(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	agentID := c.Param("agentId")
+
+	if err := registry.DeleteAgent(chainID, agentID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+	communication.BroadcastEvent(communication.EventAgentRemoved, gin.H{"agent_id": agentID})
+	c.JSON(http.StatusOK, gin.H{"deleted": agentID})
+}
+
+// GetAgentVersions returns an agent's persona history
+func GetAgentVersions(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	versions := registry.GetPersonaVersions(chainID, c.Param("agentId"))
+	if len(versions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// GetAgentVersion returns one persona version of an agent and the verdicts it produced
+func GetAgentVersion(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	agentID := c.Param("agentId")
+
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+	version, exists := registry.GetPersonaVersion(chainID, agentID, number)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Persona version not found"})
+		return
+	}
+
+	verdicts := make([]ai.Verdict, 0)
+	for _, v := range ai.GetAgentVerdicts(chainID, agentID) {
+		if v.PersonaVersion == number {
+			verdicts = append(verdicts, v)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"version": version, "verdicts": verdicts})
+}
+
+// GetAgentVerdicts returns every verdict an agent has recorded, each tagged with the persona version behind it
+func GetAgentVerdicts(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	verdicts := ai.GetAgentVerdicts(chainID, c.Param("agentId"))
+	if verdicts == nil {
+		verdicts = make([]ai.Verdict, 0)
+	}
+	c.JSON(http.StatusOK, gin.H{"verdicts": verdicts})
+}
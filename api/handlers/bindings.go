@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	"github.com/cometbft/cometbft/crypto"
+	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	validatorKeyMu sync.RWMutex
+	validatorKey   crypto.PrivKey
+	rewardAddress  string
+)
+
+// SetValidatorKey gives the API the node's validator key, which signs the node's agent registrations
+func SetValidatorKey(privKey crypto.PrivKey) {
+	validatorKeyMu.Lock()
+	defer validatorKeyMu.Unlock()
+	validatorKey = privKey
+}
+
+// SetRewardAddress sets the account the node's agent registrations credit with the agent's rewards
+func SetRewardAddress(address string) {
+	validatorKeyMu.Lock()
+	defer validatorKeyMu.Unlock()
+	rewardAddress = address
+}
+
+// BindAgent registers a local agent on chain as the agent deliberating for this node's validator. The
+// registration is signed with the validator key, so it only takes effect for the validator this node runs.
+// The agent's rewards are credited to the reward address the operator configured, never to one from the request;
+// without one they return to the fee pool.
+func BindAgent(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	agentID := c.Param("agentId")
+
+	validatorKeyMu.RLock()
+	privKey, rewards := validatorKey, rewardAddress
+	validatorKeyMu.RUnlock()
+	if privKey == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "This node has no validator key"})
+		return
+	}
+
+	_, nodeInfo, found := registry.GetNodeByAPIPort(chainID, requestAPIPort(c))
+	if !found {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Node not recognized"})
+		return
+	}
+
+	agent, exists := registry.GetAgent(chainID, agentID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+
+	tx, err := core.NewAgentRegistration(chainID, core.PersonaFromAgent(agent), rewards, privKey, uint64(time.Now().UnixNano()))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	txBytes, err := tx.Marshal()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode transaction"})
+		return
+	}
+
+	client, err := rpchttp.New(fmt.Sprintf("tcp://localhost:%d", nodeInfo.RPCPort), "/websocket")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to connect to node: %v", err)})
+		return
+	}
+	result, err := client.BroadcastTxSync(context.Background(), txBytes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to broadcast tx: %v", err)})
+		return
+	}
+	if result.Code != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": result.Log})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Agent registration submitted",
+		"hash":      result.Hash.String(),
+		"validator": tx.From,
+	})
+}
+
+// GetAgentBindings returns the agent bound to each validator in the committed app state
+func GetAgentBindings(c *gin.Context) {
+	var bindings map[string]interface{}
+	if !queryApp(c, "agent_bindings", nil, &bindings) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bindings": bindings})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
+	"github.com/gin-gonic/gin"
+)
+
+// requestAPIPort extracts the API port the request was addressed to
+func requestAPIPort(c *gin.Context) string {
+	host := c.Request.Host
+	if i := strings.LastIndex(host, ":"); i != -1 {
+		return host[i+1:]
+	}
+	return ""
+}
+
+// SubmitHumanComment broadcasts a signed stakeholder comment on a proposal
+func SubmitHumanComment(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	proposalID := c.Param("proposalId")
+
+	_, nodeInfo, found := registry.GetNodeByAPIPort(chainID, requestAPIPort(c))
+	if !found {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Node not recognized"})
+		return
+	}
+
+	var tx core.Transaction
+	if err := c.ShouldBindJSON(&tx); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction format"})
+		return
+	}
+
+	if tx.Type != "human_comment" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transaction type must be human_comment"})
+		return
+	}
+
+	txBytes, err := tx.Marshal()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to encode transaction"})
+		return
+	}
+	comment, err := communication.ParseHumanComment(tx, txBytes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid comment: %v", err)})
+		return
+	}
+	if comment.ProposalID != proposalID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment proposal_id does not match path"})
+		return
+	}
+
+	client, err := rpchttp.New(fmt.Sprintf("tcp://localhost:%d", nodeInfo.RPCPort), "/websocket")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to connect to node: %v", err)})
+		return
+	}
+
+	result, err := client.BroadcastTxSync(context.Background(), txBytes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to broadcast tx: %v", err)})
+		return
+	}
+	if result.Code != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": result.Log})
+		return
+	}
+
+	// The transaction only reaches the chain after the proposal's debate, so the comment is also sent to the
+	// validators deliberating it now
+	if err := communication.PublishHumanComment(chainID, proposalID, txBytes); err != nil {
+		log.Printf("Failed to send comment on %s to the debate: %v", proposalID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment submitted successfully",
+		"hash":    result.Hash.String(),
+	})
+}
+
+// GetHumanComments returns the stakeholder comments posted on a proposal
+func GetHumanComments(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	proposalID := c.Param("proposalId")
+	c.JSON(http.StatusOK, gin.H{"comments": communication.GetHumanComments(chainID, proposalID)})
+}
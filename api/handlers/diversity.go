@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAgentDiversity reports how diverse the chain's registered agents are, including the stances they have taken
+func GetAgentDiversity(c *gin.Context) {
+	chainID := c.GetString("chainID")
+
+	agents := registry.GetAllAgents(chainID)
+	personas := make([]core.Persona, len(agents))
+	for i, agent := range agents {
+		personas[i] = core.PersonaFromAgent(agent)
+	}
+	c.JSON(http.StatusOK, gin.H{"diversity": ai.AnalyzeDiversity(chainID, personas, ai.DefaultDiversityThresholds)})
+}
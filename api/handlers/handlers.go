@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/crc32"
+	"log"
 	"net/http"
 	"os"
 	"os/exec"
@@ -15,8 +16,10 @@ import (
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/cmd/node"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/consensus/abci"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/secrets"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/utils"
 	cfg "github.com/cometbft/cometbft/config"
 	"github.com/cometbft/cometbft/p2p"
@@ -35,11 +38,22 @@ type RelationshipUpdate struct {
 // RegisterAgent registers a new AI agent (Producer or Validator)
 func RegisterAgent(c *gin.Context) {
 	chainID := c.GetString("chainID")
-	var agent core.Agent
-	if err := c.ShouldBindJSON(&agent); err != nil {
+	var persona core.Persona
+	if err := c.ShouldBindJSON(&persona); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent data"})
 		return
 	}
+	if err := persona.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, existing := range registry.GetAllAgents(chainID) {
+		if existing.ID != persona.ID && strings.EqualFold(existing.Name, persona.Name) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("an agent named %s is already registered", persona.Name)})
+			return
+		}
+	}
+	agent := persona.Agent()
 
 	registry.RegisterAgent(chainID, agent)
 
@@ -98,7 +112,7 @@ func RegisterAgent(c *gin.Context) {
 		APIPort:   apiPort,
 	})
 
-	communication.BroadcastEvent(communication.EventAgentRegistered, agent)
+	communication.BroadcastEvent(communication.EventAgentRegistered, secrets.RedactAgent(agent))
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Agent registered successfully",
@@ -234,6 +248,10 @@ func SubmitTransaction(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to broadcast tx: %v", err)})
 		return
 	}
+	if result.Code != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": result.Log})
+		return
+	}
 
 	communication.BroadcastEvent(communication.EventNewTransaction, tx)
 
@@ -286,30 +304,38 @@ func GetAllThreads(c *gin.Context) {
 type CreateChainRequest struct {
 	ChainID       string `json:"chain_id" binding:"required"`
 	GenesisPrompt string `json:"genesis_prompt" binding:"required"`
+	// GenesisAccounts allocates the initial token balances, keyed by account address
+	GenesisAccounts map[string]uint64 `json:"genesis_accounts"`
+	// GenesisStake is bonded to the genesis validator and sets its voting power; 0 bonds the default stake
+	GenesisStake uint64 `json:"genesis_stake"`
+	// GenesisStakeOwner is the account that may unstake the genesis stake
+	GenesisStakeOwner string `json:"genesis_stake_owner"`
+	// Moderators are the accounts that may comment on proposals as moderators
+	Moderators []string `json:"moderators"`
 }
 
-// LoadSampleAgents loads sample agents from a generated file
-func LoadSampleAgents(genesisPrompt string) ([]core.Agent, error) {
-	filename, err := ai.GenerateAgents(genesisPrompt)
+// LoadSampleAgents generates a diverse set of validator personas for a genesis prompt and returns them as agents
+// with the set's diversity report
+func LoadSampleAgents(genesisPrompt string) ([]core.Agent, ai.DiversityReport, error) {
+	filename, report, err := ai.GenerateAgents(genesisPrompt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate agents: %v", err)
+		return nil, report, fmt.Errorf("failed to generate agents: %v", err)
 	}
-	filename = "examples/" + filename
 
-	fileContent, err := os.ReadFile(filename)
+	personas, err := core.LoadPersonas(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read %s: %v", filename, err)
+		return nil, report, err
 	}
 
-	var agents []core.Agent
-	if err := json.Unmarshal(fileContent, &agents); err != nil {
-		return nil, fmt.Errorf("failed to parse %s: %v", filename, err)
+	agents := make([]core.Agent, len(personas))
+	for i, persona := range personas {
+		agents[i] = persona.Agent()
 	}
-
-	return agents, nil
+	return agents, report, nil
 }
 
-// CreateChain creates a new blockchain instance
+// CreateChain creates a new blockchain instance seeded with the default agents. Personas for the genesis prompt
+// are generated in the background; GET /api/chains/:chainId/personas reports on them.
 func CreateChain(c *gin.Context) {
 	var req CreateChainRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -379,9 +405,24 @@ func CreateChain(c *gin.Context) {
 			return
 		}
 
+		appState, err := abci.GenesisAppState(req.GenesisAccounts, req.Moderators)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to encode genesis app state: %v", err)})
+			return
+		}
+		stake := req.GenesisStake
+		if stake == 0 {
+			stake = abci.DefaultGenesisStake
+		}
+		appState, power, err := abci.AddGenesisStake(appState, pubKey.Address().String(), req.GenesisStakeOwner, stake)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to bond genesis stake: %v", err)})
+			return
+		}
+
 		genValidator := types.GenesisValidator{
 			PubKey: pubKey,
-			Power:  1000000,
+			Power:  power,
 			Name:   "genesis",
 		}
 
@@ -390,6 +431,7 @@ func CreateChain(c *gin.Context) {
 			GenesisTime:     time.Now(),
 			ConsensusParams: types.DefaultConsensusParams(),
 			Validators:      []types.GenesisValidator{genValidator},
+			AppState:        appState,
 		}
 
 		if err := genDoc.ValidateAndComplete(); err != nil {
@@ -426,18 +468,32 @@ func CreateChain(c *gin.Context) {
 		"timestamp": time.Now(),
 	})
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"message":  "Chain created successfully",
 		"chain_id": req.ChainID,
 		"genesis_node": map[string]int{
 			"p2p_port": func() int { p, _ := strconv.Atoi(config.P2P.ListenAddress[10:]); return p }(),
 			"rpc_port": func() int { p, _ := strconv.Atoi(config.RPC.ListenAddress[10:]); return p }(),
 		},
-	})
+	}
+
+	// The chain starts with the default personas; those for its genesis prompt take a while to generate
+	defaults, err := LoadDefaultAgents()
+	if err != nil {
+		log.Printf("Chain %s created without default agents: %v", req.ChainID, err)
+		response["seed_error"] = err.Error()
+	}
+	for _, agent := range defaults {
+		registry.RegisterAgent(req.ChainID, agent)
+	}
+	response["seeded_agents"] = len(defaults)
+	response["persona_generation"] = startPersonaGeneration(req.ChainID, req.GenesisPrompt, defaults)
+
+	c.JSON(http.StatusCreated, response)
 }
 
-// AddValidatorToGenesis adds a validator to the genesis file
-func AddValidatorToGenesis(chainID string, agent core.Agent) bool {
+// AddValidatorToGenesis adds a validator to the genesis file with the given stake bonded to it
+func AddValidatorToGenesis(chainID string, agent core.Agent, stake uint64) bool {
 	dataDir := fmt.Sprintf("./data/%s/%s", chainID, agent.ID)
 	genesisFile := fmt.Sprintf("./data/%s/genesis/config/genesis.json", chainID)
 
@@ -463,10 +519,17 @@ func AddValidatorToGenesis(chainID string, agent core.Agent) bool {
 		return false
 	}
 
+	appState, power, err := abci.AddGenesisStake(genDoc.AppState, pubKey.Address().String(), "", stake)
+	if err != nil {
+		log.Printf("Failed to bond genesis stake of agent %s: %v", agent.ID, err)
+		return false
+	}
+	genDoc.AppState = appState
+
 	validator := types.GenesisValidator{
 		Address: pubKey.Address(),
 		PubKey:  pubKey,
-		Power:   10,
+		Power:   power,
 		Name:    agent.ID,
 	}
 	genDoc.Validators = append(genDoc.Validators, validator)
@@ -492,7 +555,7 @@ func AddValidatorToGenesis(chainID string, agent core.Agent) bool {
 func GetAllAgents(c *gin.Context) {
 	chainID := c.GetString("chainID")
 	agents := registry.GetAllAgents(chainID)
-	c.JSON(http.StatusOK, gin.H{"agents": agents})
+	c.JSON(http.StatusOK, gin.H{"agents": secrets.RedactAgents(agents)})
 }
 
 // GetRegistry returns the registry information for a chain
@@ -500,7 +563,7 @@ func GetRegistry(c *gin.Context) {
 	chainID := c.GetString("chainID")
 	agents := registry.GetAllAgents(chainID)
 	c.JSON(http.StatusOK, gin.H{
-		"agents":     agents,
+		"agents":     secrets.RedactAgents(agents),
 		"validators": registry.GetAllValidatorAgentMappings(chainID),
 	})
 }
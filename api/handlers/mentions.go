@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/gin-gonic/gin"
+)
+
+// GetProposalMentionGraph returns the influence graph built from |@Name| mentions in a proposal's discussion
+func GetProposalMentionGraph(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	proposalID := c.Param("proposalId")
+	c.JSON(http.StatusOK, gin.H{"graph": communication.GetMentionGraph(chainID, proposalID)})
+}
+
+// GetChainMentionGraph returns the influence graph aggregated over every proposal on the chain
+func GetChainMentionGraph(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	c.JSON(http.StatusOK, gin.H{"graph": communication.GetChainMentionGraph(chainID)})
+}
+
+// HandleEventStream subscribes a WebSocket client to every event sent through communication.BroadcastEvent
+func HandleEventStream(c *gin.Context) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade connection: %v", err)
+		return
+	}
+
+	manager := communication.GetWSManager()
+	manager.Register() <- conn
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			log.Printf("Event stream connection closed: %v", err)
+			break
+		}
+	}
+	manager.Unregister() <- conn
+}
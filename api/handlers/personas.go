@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPersonaSet is the curated persona set a new chain starts with while personas for its genesis prompt are
+// generated
+var defaultPersonaSet = "physics"
+
+// Statuses of a persona generation job
+const (
+	PersonaJobRunning = "running"
+	PersonaJobDone    = "done"
+	PersonaJobFailed  = "failed"
+)
+
+// PersonaJob tracks the generation of a chain's personas for its genesis prompt
+type PersonaJob struct {
+	ChainID      string              `json:"chain_id"`
+	Status       string              `json:"status"`
+	SeededAgents int                 `json:"seeded_agents"`
+	Diversity    *ai.DiversityReport `json:"diversity,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	StartedAt    time.Time           `json:"started_at"`
+	FinishedAt   *time.Time          `json:"finished_at,omitempty"`
+}
+
+var (
+	personaJobsMu sync.RWMutex
+	personaJobs   = make(map[string]PersonaJob)
+)
+
+// LoadDefaultAgents returns the agents of the curated default persona set
+func LoadDefaultAgents() ([]core.Agent, error) {
+	personas, err := core.LoadPersonas(filepath.Join(ai.PersonaExampleDir, defaultPersonaSet+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default personas: %v", err)
+	}
+	agents := make([]core.Agent, len(personas))
+	for i, persona := range personas {
+		agents[i] = persona.Agent()
+	}
+	return agents, nil
+}
+
+// startPersonaGeneration generates personas for a chain's genesis prompt in the background. Once they are ready
+// they are registered and replace the default agents no validator has been linked to yet.
+func startPersonaGeneration(chainID, genesisPrompt string, defaults []core.Agent) PersonaJob {
+	job := PersonaJob{ChainID: chainID, Status: PersonaJobRunning, StartedAt: time.Now()}
+	personaJobsMu.Lock()
+	personaJobs[chainID] = job
+	personaJobsMu.Unlock()
+
+	go func() {
+		agents, report, err := LoadSampleAgents(genesisPrompt)
+		finished := time.Now()
+		job.FinishedAt = &finished
+		job.Diversity = &report
+		if err != nil {
+			log.Printf("Keeping the default agents of chain %s: %v", chainID, err)
+			job.Status = PersonaJobFailed
+			job.Error = err.Error()
+		} else {
+			for _, agent := range agents {
+				registry.RegisterAgent(chainID, agent)
+			}
+			for _, agent := range defaults {
+				if current, exists := registry.GetAgent(chainID, agent.ID); !exists || current.ValidatorAddress != "" {
+					continue
+				}
+				if err := registry.DeleteAgent(chainID, agent.ID); err != nil {
+					log.Printf("Failed to remove default agent %s of chain %s: %v", agent.ID, chainID, err)
+				}
+			}
+			job.Status = PersonaJobDone
+			job.SeededAgents = len(agents)
+		}
+
+		personaJobsMu.Lock()
+		personaJobs[chainID] = job
+		personaJobsMu.Unlock()
+		communication.BroadcastEvent(communication.EventPersonasGenerated, job)
+	}()
+	return job
+}
+
+// GetPersonaJob reports how the generation of a chain's personas is going
+func GetPersonaJob(c *gin.Context) {
+	chainID := c.Param("chainId")
+
+	personaJobsMu.RLock()
+	job, exists := personaJobs[chainID]
+	personaJobsMu.RUnlock()
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No persona generation for this chain"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/gin-gonic/gin"
+)
+
+// GetProposalScreening returns the prompt-injection screening recorded for a proposal
+func GetProposalScreening(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	proposalID := c.Param("proposalId")
+
+	report, ok := ai.GetScreeningReport(chainID, proposalID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "proposal has not been screened"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"screening": report})
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/gin-gonic/gin"
+)
+
+// GetLLMUsage returns the chain's LLM token usage and estimated cost, broken down by agent and proposal
+func GetLLMUsage(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	c.JSON(http.StatusOK, gin.H{"usage": ai.GetUsage(chainID)})
+}
+
+// GetProposalLLMUsage returns the LLM token usage and estimated cost of deliberating a single proposal
+func GetProposalLLMUsage(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	proposalID := c.Param("proposalId")
+	c.JSON(http.StatusOK, gin.H{
+		"proposal_id": proposalID,
+		"usage":       ai.GetProposalUsage(chainID, proposalID),
+	})
+}
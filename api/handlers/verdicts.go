@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/gin-gonic/gin"
+)
+
+// GetProposalVerdicts returns the verdicts recorded on a proposal and how they tally under the proposal type's rules,
+// counting the deliberating validators whose verdict has not arrived as abstaining
+func GetProposalVerdicts(c *gin.Context) {
+	chainID := c.GetString("chainID")
+	proposalID := c.Param("proposalId")
+
+	verdicts := ai.GetVerdicts(chainID, proposalID)
+	if len(verdicts) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no verdicts recorded for proposal"})
+		return
+	}
+	var deliberators []string
+	if !queryApp(c, "deliberators", nil, &deliberators) {
+		return
+	}
+	rules := ai.TallyRulesFor(verdicts[0].ProposalType)
+	c.JSON(http.StatusOK, gin.H{
+		"verdicts": verdicts,
+		"rules":    rules,
+		"tally":    ai.Tally(verdicts, len(deliberators), rules),
+	})
+}
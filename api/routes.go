@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/api/handlers"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // chainIDMiddleware injects chainID into request context
@@ -36,13 +37,42 @@ func SetupRoutes(router *gin.Engine, chainID string) {
 	api.Use(chainIDMiddleware(chainID))
 	{
 		api.POST("/chains", handlers.CreateChain)
+		api.GET("/chains/:chainId/personas", handlers.GetPersonaJob)
 		api.POST("/register", handlers.RegisterAgent)
 		api.GET("/blocks/:height", handlers.GetBlock)
 		api.GET("/chain/status", handlers.GetNetworkStatus)
 		api.POST("/transactions", handlers.SubmitTransaction)
 		api.GET("/validators", handlers.GetValidators)
 		api.GET("/agents", handlers.GetAllAgents)
+		api.GET("/agents/diversity", handlers.GetAgentDiversity)
+		api.GET("/agents/bindings", handlers.GetAgentBindings)
+		api.POST("/agents", handlers.CreateAgent)
+		api.GET("/agents/:agentId", handlers.GetAgent)
+		api.PUT("/agents/:agentId", handlers.UpdateAgent)
+		api.DELETE("/agents/:agentId", handlers.DeleteAgent)
+		api.POST("/agents/:agentId/disable", handlers.DisableAgent)
+		api.POST("/agents/:agentId/enable", handlers.EnableAgent)
+		api.GET("/agents/:agentId/versions", handlers.GetAgentVersions)
+		api.GET("/agents/:agentId/versions/:version", handlers.GetAgentVersion)
+		api.GET("/agents/:agentId/verdicts", handlers.GetAgentVerdicts)
+		api.POST("/agents/:agentId/bind", handlers.BindAgent)
+		api.GET("/agents/:agentId/rewards", handlers.GetAgentRewards)
+		api.GET("/accounts/:address", handlers.GetAccount)
+		api.GET("/accounts/:address/delegations", handlers.GetDelegations)
+		api.GET("/stakes", handlers.GetStakes)
+		api.GET("/validators/:address/stake", handlers.GetValidatorStake)
+		api.GET("/fees", handlers.GetFees)
+		api.POST("/proposals/:proposalId/comments", handlers.SubmitHumanComment)
+		api.GET("/proposals/:proposalId/comments", handlers.GetHumanComments)
+		api.GET("/proposals/:proposalId/mentions", handlers.GetProposalMentionGraph)
+		api.GET("/mentions", handlers.GetChainMentionGraph)
+		api.GET("/proposals/:proposalId/usage", handlers.GetProposalLLMUsage)
+		api.GET("/proposals/:proposalId/screening", handlers.GetProposalScreening)
+		api.GET("/proposals/:proposalId/verdicts", handlers.GetProposalVerdicts)
+		api.GET("/usage", handlers.GetLLMUsage)
 	}
 
 	router.GET("/ws", handlers.HandleWebSocket)
+	router.GET("/ws/events", handlers.HandleEventStream)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 }
@@ -2,17 +2,23 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/api"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/api/handlers"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/cmd/node"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/consensus/abci"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/secrets"
 	cfg "github.com/cometbft/cometbft/config"
 	"github.com/cometbft/cometbft/p2p"
 	"github.com/cometbft/cometbft/privval"
@@ -27,6 +33,17 @@ func fileExists(filename string) bool {
 	return !os.IsNotExist(err)
 }
 
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // main initializes and starts the genesis node with all necessary configurations
 func main() {
 	chainID := flag.String("chain", "mainnet", "Chain ID")
@@ -35,10 +52,21 @@ func main() {
 	rpcPort := flag.Int("rpc-port", 26657, "CometBFT RPC port")
 	apiPort := flag.Int("api-port", 3000, "API server port")
 	nats := flag.String("nats", "nats://localhost:4222", "NATS URL")
+	natsHost := flag.String("nats-host", "localhost", "Host for the embedded NATS server")
+	natsPort := flag.Int("nats-port", 4222, "Port for the embedded NATS server")
+	jetStream := flag.Bool("jetstream", false, "Enable JetStream durable streams for deliberation, verdicts and events")
+	natsStoreDir := flag.String("nats-store-dir", "data/jetstream", "JetStream storage directory for the embedded NATS server")
+	genesisAccounts := flag.String("genesis-accounts", "", "JSON file mapping account addresses to their genesis balances")
+	genesisStake := flag.Uint64("genesis-stake", abci.DefaultGenesisStake, "Stake bonded to the genesis validator, which sets its voting power")
+	moderators := flag.String("moderators", "", "Comma-separated account addresses that may comment on proposals as moderators")
+	genesisStakeOwner := flag.String("genesis-stake-owner", "", "Account address that owns the genesis stake and may unstake it")
+	rewardAddress := flag.String("reward-address", "", "Account address credited with the rewards of the agent bound to this node's validator")
 	flag.Parse()
 
+	log.SetOutput(secrets.NewRedactingWriter(os.Stderr))
 	registry.InitRegistry()
 	ai.InitAI()
+	abci.LoadSelectionPolicyFromEnv()
 
 	if _, err := os.Stat(fmt.Sprintf("./data/%s", *chainID)); err == nil {
 		os.RemoveAll(fmt.Sprintf("./data/%s", *chainID))
@@ -83,6 +111,8 @@ func main() {
 	}
 
 	pubKey, _ := privVal.GetPubKey()
+	handlers.SetValidatorKey(privVal.Key.PrivKey)
+	handlers.SetRewardAddress(*rewardAddress)
 
 	nodeKeyFile := config.NodeKeyFile()
 	if !fileExists(nodeKeyFile) {
@@ -103,9 +133,28 @@ func main() {
 			log.Fatalf("Failed to get validator public key: %v", err)
 		}
 
+		accounts := make(map[string]uint64)
+		if *genesisAccounts != "" {
+			data, err := os.ReadFile(*genesisAccounts)
+			if err != nil {
+				log.Fatalf("Failed to read genesis accounts: %v", err)
+			}
+			if err := json.Unmarshal(data, &accounts); err != nil {
+				log.Fatalf("Failed to parse genesis accounts: %v", err)
+			}
+		}
+		appState, err := abci.GenesisAppState(accounts, splitList(*moderators))
+		if err != nil {
+			log.Fatalf("Failed to encode genesis app state: %v", err)
+		}
+		appState, power, err := abci.AddGenesisStake(appState, pubKey.Address().String(), *genesisStakeOwner, *genesisStake)
+		if err != nil {
+			log.Fatalf("Failed to bond genesis stake: %v", err)
+		}
+
 		genValidator := types.GenesisValidator{
 			PubKey: pubKey,
-			Power:  1000000,
+			Power:  power,
 			Name:   "genesis",
 		}
 
@@ -114,6 +163,7 @@ func main() {
 			GenesisTime:     time.Now(),
 			ConsensusParams: types.DefaultConsensusParams(),
 			Validators:      []types.GenesisValidator{genValidator},
+			AppState:        appState,
 		}
 
 		if err := genDoc.ValidateAndComplete(); err != nil {
@@ -158,8 +208,17 @@ func main() {
 		APIPort:   *apiPort,
 	})
 
-	core.SetupNATS(*nats)
+	natsConfig := core.DefaultNATSConfig()
+	natsConfig.URL = *nats
+	natsConfig.EmbeddedHost = *natsHost
+	natsConfig.EmbeddedPort = *natsPort
+	natsConfig.JetStream = *jetStream
+	natsConfig.StoreDir = *natsStoreDir
+	natsConfig.Node = *nodeID
+	core.SetupNATS(natsConfig)
 	defer core.CloseNATS()
+	ai.WatchVerdicts(*chainID)
+	communication.WatchHumanComments(*chainID)
 
 	log.Printf("Genesis node for chain %s started with P2P port %d, RPC port %d, and API port %d",
 		*chainID, *p2pPort, *rpcPort, *apiPort)
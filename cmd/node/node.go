@@ -14,6 +14,7 @@ import (
 	"github.com/cometbft/cometbft/proxy"
 
 	tmlog "github.com/cometbft/cometbft/libs/log"
+	mempl "github.com/cometbft/cometbft/mempool"
 	"github.com/cometbft/cometbft/types"
 )
 
@@ -49,6 +50,7 @@ func NewNode(config *cfg.Config, chainId string, selfValidatorAddr string) (*Nod
 		config.PrivValidatorKeyFile(),
 		config.PrivValidatorStateFile(),
 	)
+	app.SetValidatorKey(privValidator.Key.PrivKey)
 
 	genDocProvider := func() (*types.GenesisDoc, error) {
 		return types.GenesisDocFromFile(config.GenesisFile())
@@ -68,6 +70,9 @@ func NewNode(config *cfg.Config, chainId string, selfValidatorAddr string) (*Nod
 		log.Fatalf("failed to create node: %v", err)
 		return nil, fmt.Errorf("failed to create node: %v", err)
 	}
+	app.SetTxBroadcaster(func(tx []byte) error {
+		return node.Mempool().CheckTx(tx, nil, mempl.TxInfo{})
+	})
 
 	return &Node{
 		cometCfg: config,
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/secrets"
+)
+
+const usage = `Usage: personas <command> [flags]
+
+Commands:
+  validate <file>...               check persona files against the schema
+  import -chain <id> <file>        register the personas of a file as agents of a chain
+  export -chain <id> [-o <file>]   write a chain's agents as a persona file
+  generate -topic <t> [-count <n>] [-mode swap|regenerate] [-o <file>]
+                                   generate a validated, diverse persona set with the configured model
+  diversity [-chain <id>] [<file>] report the diversity of a persona file, or of a chain's agents
+`
+
+// main validates, imports, exports and generates persona files
+func main() {
+	log.SetFlags(0)
+	log.SetOutput(secrets.NewRedactingWriter(os.Stderr))
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "validate":
+		validate(os.Args[2:])
+	case "import":
+		importPersonas(os.Args[2:])
+	case "export":
+		exportPersonas(os.Args[2:])
+	case "generate":
+		generate(os.Args[2:])
+	case "diversity":
+		diversity(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// validate reports every schema violation in the given files
+func validate(args []string) {
+	if len(args) == 0 {
+		log.Fatal("validate needs at least one file")
+	}
+	failed := false
+	for _, path := range args {
+		personas, err := core.LoadPersonas(path)
+		if err != nil {
+			failed = true
+			fmt.Printf("%s: invalid\n%v\n", path, err)
+			continue
+		}
+		fmt.Printf("%s: %d personas ok\n", path, len(personas))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// importPersonas registers a validated persona file as agents of a chain
+func importPersonas(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	chainID := fs.String("chain", "mainnet", "Chain ID")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("import needs exactly one file")
+	}
+
+	personas, err := core.LoadPersonas(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Refusing to import: %v", err)
+	}
+	registry.InitRegistry()
+	for _, persona := range personas {
+		registry.RegisterAgent(*chainID, persona.Agent())
+	}
+	fmt.Printf("Imported %d personas into chain %s\n", len(personas), *chainID)
+}
+
+// exportPersonas writes a chain's registered agents in the persona schema. Secret settings are redacted, except
+// for env: and file: references, which are meaningful on other nodes.
+func exportPersonas(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	chainID := fs.String("chain", "mainnet", "Chain ID")
+	out := fs.String("o", "", "Output file (default stdout)")
+	fs.Parse(args)
+
+	registry.InitRegistry()
+	agents := registry.GetAllAgents(*chainID)
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	personas := make([]core.Persona, len(agents))
+	for i, agent := range agents {
+		personas[i] = secrets.RedactPersona(core.PersonaFromAgent(agent))
+	}
+	write(*out, personas)
+}
+
+// generate asks the model for a persona set and writes it once it validates
+func generate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	topic := fs.String("topic", "", "Topic of the chain the personas will deliberate on")
+	count := fs.Int("count", ai.DefaultPersonaCount, "Number of personas")
+	mode := fs.String("mode", string(ai.DiversitySwap), "How to fix a set that is not diverse: swap or regenerate")
+	out := fs.String("o", "", "Output file (default stdout)")
+	fs.Parse(args)
+	if *topic == "" {
+		log.Fatal("generate needs -topic")
+	}
+
+	ai.InitAI()
+	opts := ai.DefaultPersonaGenerationOptions()
+	opts.Count = *count
+	opts.Mode = ai.DiversityMode(*mode)
+	personas, report, err := ai.GeneratePersonas(*topic, opts)
+	if err != nil {
+		log.Fatalf("Generation failed: %v", err)
+	}
+	log.Printf("Generated %d personas, mean trait overlap %.2f, specialization coverage %.2f", len(personas), report.MeanTraitOverlap, report.SpecializationCoverage)
+	write(*out, personas)
+}
+
+// diversity prints the diversity report of a persona file or of a chain's registered agents
+func diversity(args []string) {
+	fs := flag.NewFlagSet("diversity", flag.ExitOnError)
+	chainID := fs.String("chain", "", "Chain whose agents and recorded stances to analyze")
+	fs.Parse(args)
+
+	var personas []core.Persona
+	switch {
+	case fs.NArg() == 1:
+		loaded, err := core.LoadPersonas(fs.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		personas = loaded
+	case *chainID != "":
+		registry.InitRegistry()
+		for _, agent := range registry.GetAllAgents(*chainID) {
+			personas = append(personas, core.PersonaFromAgent(agent))
+		}
+	default:
+		log.Fatal("diversity needs a file or -chain")
+	}
+
+	report := ai.AnalyzeDiversity(*chainID, personas, ai.DefaultDiversityThresholds)
+	data, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(data))
+	if !report.Diverse {
+		os.Exit(1)
+	}
+}
+
+// write saves personas to path, or prints them when path is empty
+func write(path string, personas []core.Persona) {
+	if path == "" {
+		path = "/dev/stdout"
+	}
+	if err := core.SavePersonas(path, personas); err != nil {
+		log.Fatalf("Failed to write personas: %v", err)
+	}
+}
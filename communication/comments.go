@@ -0,0 +1,311 @@
+package communication
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// Comment roles. Anyone may comment as a stakeholder; the author and borrower roles are only granted to the
+// account that signed the proposal, and the moderator role to the chain's moderator accounts.
+const (
+	CommentRoleStakeholder = "stakeholder"
+	CommentRoleAuthor      = "author"
+	CommentRoleBorrower    = "borrower"
+	CommentRoleModerator   = "moderator"
+)
+
+// HumanComment is a rebuttal or note posted by a human stakeholder into a proposal's discussion
+type HumanComment struct {
+	ID         string    `json:"id"`
+	ProposalID string    `json:"proposal_id"`
+	Author     string    `json:"author"`
+	Role       string    `json:"role"`
+	Message    string    `json:"message"`
+	PublicKey  string    `json:"public_key"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+var (
+	comments   = make(map[string]map[string][]HumanComment)
+	commentsMu sync.RWMutex
+
+	// submitters are the accounts that signed the proposals this node has seen, before they are committed
+	submitters   = make(map[string]map[string]string)
+	submittersMu sync.RWMutex
+
+	commentAuthorizers = make(map[string]func(core.Transaction, HumanComment) error)
+	commentWatches     = make(map[string]*nats.Subscription)
+	commentWatchMu     sync.Mutex
+)
+
+// ValidCommentRole reports whether role is one of the accepted stakeholder roles
+func ValidCommentRole(role string) bool {
+	switch role {
+	case CommentRoleStakeholder, CommentRoleAuthor, CommentRoleBorrower, CommentRoleModerator:
+		return true
+	}
+	return false
+}
+
+// ParseHumanComment verifies a signed human_comment transaction and extracts the comment it carries. The author is
+// the signing account and a comment without a role is a stakeholder's; whether the signer may claim its role is
+// left to AuthorizeCommentRole.
+func ParseHumanComment(tx core.Transaction, raw []byte) (HumanComment, error) {
+	if tx.Nonce == 0 {
+		return HumanComment{}, fmt.Errorf("human comments are paid and must carry a nonce")
+	}
+	sender, err := tx.Sender()
+	if err != nil {
+		return HumanComment{}, err
+	}
+
+	var comment HumanComment
+	if err := json.Unmarshal([]byte(tx.Content), &comment); err != nil {
+		return HumanComment{}, fmt.Errorf("invalid comment format: %v", err)
+	}
+	if comment.ProposalID == "" || comment.Message == "" {
+		return HumanComment{}, fmt.Errorf("proposal_id and message are required")
+	}
+	if comment.Role == "" {
+		comment.Role = CommentRoleStakeholder
+	}
+	if !ValidCommentRole(comment.Role) {
+		return HumanComment{}, fmt.Errorf("invalid role %q", comment.Role)
+	}
+
+	comment.ID = core.ProposalID(raw)
+	comment.Author = sender
+	comment.PublicKey = tx.PublicKey
+	comment.Timestamp = time.Unix(tx.Timestamp, 0)
+	return comment, nil
+}
+
+// AuthorizeCommentRole checks the role a comment claims: author and borrower belong to the proposal's submitter,
+// moderator to the moderator accounts
+func AuthorizeCommentRole(comment HumanComment, submitter string, moderators []string) error {
+	switch comment.Role {
+	case CommentRoleAuthor, CommentRoleBorrower:
+		if submitter == "" {
+			return fmt.Errorf("submitter of proposal %s is not known, cannot comment as %s", comment.ProposalID, comment.Role)
+		}
+		if comment.Author != submitter {
+			return fmt.Errorf("only %s, who submitted proposal %s, can comment as %s", submitter, comment.ProposalID, comment.Role)
+		}
+	case CommentRoleModerator:
+		for _, moderator := range moderators {
+			if moderator == comment.Author {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s is not a moderator", comment.Author)
+	}
+	return nil
+}
+
+// RecordProposalSubmitter remembers who signed a proposal this node has seen but not yet committed, so its author
+// can comment while it is being deliberated
+func RecordProposalSubmitter(chainID, proposalID, account string) {
+	submittersMu.Lock()
+	defer submittersMu.Unlock()
+	if submitters[chainID] == nil {
+		submitters[chainID] = make(map[string]string)
+	}
+	submitters[chainID][proposalID] = account
+}
+
+// ProposalSubmitter returns the account that signed a proposal this node has seen
+func ProposalSubmitter(chainID, proposalID string) (string, bool) {
+	submittersMu.RLock()
+	defer submittersMu.RUnlock()
+	account, exists := submitters[chainID][proposalID]
+	return account, exists
+}
+
+// HumanCommentSubject returns the NATS subject carrying the comments posted on a proposal while it is deliberated
+func HumanCommentSubject(chainID, proposalID string) string {
+	return fmt.Sprintf("comments.%s.%s", chainID, proposalID)
+}
+
+// PublishHumanComment sends a signed human_comment transaction to the validators deliberating its proposal. On
+// chain, comments are only delivered once the proposal is committed, after its debate; this is how they reach
+// the debate itself. It is a no-op without NATS.
+func PublishHumanComment(chainID, proposalID string, raw []byte) error {
+	broker := core.DefaultBroker()
+	if broker == nil {
+		return nil
+	}
+	return broker.Publish(HumanCommentSubject(chainID, proposalID), raw)
+}
+
+// SetCommentAuthorizer sets how comments arriving over NATS for a chain are authorized; until it is set they are
+// dropped
+func SetCommentAuthorizer(chainID string, authorize func(core.Transaction, HumanComment) error) {
+	commentWatchMu.Lock()
+	defer commentWatchMu.Unlock()
+	commentAuthorizers[chainID] = authorize
+}
+
+// WatchHumanComments stores the comments published on a chain's proposals, so reviewers see them in the next
+// debate round. With JetStream the node's durable consumer also delivers those posted while it was offline. It is
+// a no-op without NATS or when the chain is already watched.
+func WatchHumanComments(chainID string) {
+	broker := core.DefaultBroker()
+	if broker == nil {
+		return
+	}
+	commentWatchMu.Lock()
+	defer commentWatchMu.Unlock()
+	if commentWatches[chainID] != nil {
+		return
+	}
+
+	sub, err := broker.SubscribeNode(HumanCommentSubject(chainID, "*"), "comments-"+chainID, func(msg *nats.Msg) {
+		var tx core.Transaction
+		if err := json.Unmarshal(msg.Data, &tx); err != nil || tx.Type != "human_comment" || tx.ChainID != chainID {
+			log.Printf("Dropping malformed comment on chain %s", chainID)
+			return
+		}
+		comment, err := ParseHumanComment(tx, msg.Data)
+		if err != nil {
+			log.Printf("Dropping invalid comment on chain %s: %v", chainID, err)
+			return
+		}
+
+		commentWatchMu.Lock()
+		authorize := commentAuthorizers[chainID]
+		commentWatchMu.Unlock()
+		if authorize == nil {
+			return
+		}
+		if err := authorize(tx, comment); err != nil {
+			log.Printf("Dropping comment from %s on proposal %s: %v", comment.Author, comment.ProposalID, err)
+			return
+		}
+		if _, err := AddHumanComment(chainID, comment); err != nil {
+			log.Printf("Failed to store comment on proposal %s: %v", comment.ProposalID, err)
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to watch comments of chain %s: %v", chainID, err)
+		return
+	}
+	commentWatches[chainID] = sub
+}
+
+// commentsFile returns the path where comments for a chain are persisted
+func commentsFile(chainID string) string {
+	return fmt.Sprintf("data/discussions/%s_comments.json", chainID)
+}
+
+// loadComments reads persisted comments for a chain into memory if not already loaded
+func loadComments(chainID string) {
+	if _, loaded := comments[chainID]; loaded {
+		return
+	}
+	comments[chainID] = make(map[string][]HumanComment)
+
+	data, err := os.ReadFile(commentsFile(chainID))
+	if err != nil {
+		return
+	}
+	var stored map[string][]HumanComment
+	if err := json.Unmarshal(data, &stored); err != nil {
+		log.Printf("Failed to unmarshal comments for chain %s: %v", chainID, err)
+		return
+	}
+	if stored != nil {
+		comments[chainID] = stored
+	}
+}
+
+// saveComments writes all comments for a chain to disk
+func saveComments(chainID string) {
+	filename := commentsFile(chainID)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		log.Printf("Failed to create comments directory: %v", err)
+		return
+	}
+
+	data, err := json.MarshalIndent(comments[chainID], "", "  ")
+	if err != nil {
+		log.Printf("Failed to marshal comments: %v", err)
+		return
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		log.Printf("Failed to save comments: %v", err)
+	}
+}
+
+// AddHumanComment stores a stakeholder comment for a proposal and notifies connected clients
+func AddHumanComment(chainID string, comment HumanComment) (HumanComment, error) {
+	if comment.ProposalID == "" {
+		return HumanComment{}, fmt.Errorf("missing proposal id")
+	}
+	if strings.TrimSpace(comment.Message) == "" {
+		return HumanComment{}, fmt.Errorf("empty comment")
+	}
+	if !ValidCommentRole(comment.Role) {
+		return HumanComment{}, fmt.Errorf("invalid role %q", comment.Role)
+	}
+
+	if comment.ID == "" {
+		comment.ID = uuid.New().String()
+	}
+	if comment.Timestamp.IsZero() {
+		comment.Timestamp = time.Now()
+	}
+
+	commentsMu.Lock()
+	loadComments(chainID)
+	for _, existing := range comments[chainID][comment.ProposalID] {
+		if existing.ID == comment.ID {
+			commentsMu.Unlock()
+			return existing, nil
+		}
+	}
+	comments[chainID][comment.ProposalID] = append(comments[chainID][comment.ProposalID], comment)
+	saveComments(chainID)
+	commentsMu.Unlock()
+
+	go BroadcastEvent(EventHumanComment, comment)
+	return comment, nil
+}
+
+// GetHumanComments returns the stakeholder comments posted on a proposal in submission order
+func GetHumanComments(chainID, proposalID string) []HumanComment {
+	commentsMu.Lock()
+	defer commentsMu.Unlock()
+
+	loadComments(chainID)
+	result := make([]HumanComment, len(comments[chainID][proposalID]))
+	copy(result, comments[chainID][proposalID])
+	return result
+}
+
+// FormatHumanComments renders comments for inclusion in a reviewer prompt with explicit attribution
+func FormatHumanComments(list []HumanComment) string {
+	if len(list) == 0 {
+		return "(no stakeholder comments)"
+	}
+
+	var b strings.Builder
+	for _, c := range list {
+		role := c.Role
+		if role == "" || role == CommentRoleStakeholder {
+			role = "stakeholder, role not verified"
+		}
+		b.WriteString(fmt.Sprintf("[%s] %s (human %s, not a validator): %s\n",
+			c.Timestamp.Format("2006-01-02 15:04:05"), c.Author, role, c.Message))
+	}
+	return b.String()
+}
@@ -0,0 +1,498 @@
+package communication
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/utils"
+	"github.com/cometbft/cometbft/crypto"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultRoundTimeout bounds how long a validator waits for its peers at a round barrier
+const DefaultRoundTimeout = 30 * time.Second
+
+// DeliberationMessage is one validator's contribution to a deliberation round, signed by its validator key
+type DeliberationMessage struct {
+	ChainID          string `json:"chain_id"`
+	ProposalID       string `json:"proposal_id"`
+	Round            int    `json:"round"`
+	AgentID          string `json:"agent_id"`
+	AgentName        string `json:"agent_name"`
+	ValidatorAddress string `json:"validator_address"`
+	Approval         bool   `json:"approval"`
+	Summary          string `json:"summary"`
+	Passed           bool   `json:"passed,omitempty"`
+	Abstained        bool   `json:"abstained,omitempty"`
+	Timestamp        int64  `json:"timestamp"`
+	PubKey           []byte `json:"pub_key"`
+	Signature        []byte `json:"signature,omitempty"`
+}
+
+// signBytes returns the canonical bytes covered by the message signature
+func (m DeliberationMessage) signBytes() []byte {
+	m.Signature = nil
+	data, _ := json.Marshal(m)
+	return data
+}
+
+// Verify checks the signature and that the signing key belongs to the claimed validator address
+func (m DeliberationMessage) Verify() bool {
+	return verifyValidatorSignature(m.PubKey, m.ValidatorAddress, m.signBytes(), m.Signature)
+}
+
+// verifyValidatorSignature checks a signature made with an ed25519 validator key and that the key belongs to the
+// claimed validator address
+func verifyValidatorSignature(pubKeyBytes []byte, validatorAddr string, msg, sig []byte) bool {
+	if len(pubKeyBytes) != ed25519.PubKeySize {
+		return false
+	}
+	pubKey := ed25519.PubKey(pubKeyBytes)
+	if pubKey.Address().String() != validatorAddr {
+		return false
+	}
+	return pubKey.VerifySignature(msg, sig)
+}
+
+// Deliberation exchanges the rounds of a single proposal's discussion with the other validators over NATS
+type Deliberation struct {
+	messenger  *Messenger
+	chainID    string
+	proposalID string
+	agent      core.Agent
+	privKey    crypto.PrivKey
+	sub        *nats.Subscription
+
+	mu      sync.Mutex
+	rounds  map[int]map[string]DeliberationMessage
+	arrived chan struct{}
+}
+
+var (
+	validatorAgents   = make(map[string]map[string]core.Agent)
+	validatorAgentsMu sync.RWMutex
+)
+
+// SetValidatorAgents replaces the validator set of a chain, with the agent each validator deliberates for. Only
+// messages and verdicts from these validators, in the name of their own agent, are accepted.
+func SetValidatorAgents(chainID string, agents map[string]core.Agent) {
+	validatorAgentsMu.Lock()
+	defer validatorAgentsMu.Unlock()
+	validatorAgents[chainID] = agents
+}
+
+// ValidatorAgent returns the agent a validator in the current set deliberates for. The agent is empty for a
+// validator without one.
+func ValidatorAgent(chainID, validatorAddr string) (core.Agent, bool) {
+	validatorAgentsMu.RLock()
+	defer validatorAgentsMu.RUnlock()
+	agent, member := validatorAgents[chainID][validatorAddr]
+	return agent, member
+}
+
+// checkValidatorAgent verifies that a validator is in the current set and speaks for the agent bound to it
+func checkValidatorAgent(chainID, validatorAddr, agentID, agentName string) error {
+	agent, member := ValidatorAgent(chainID, validatorAddr)
+	if !member {
+		return fmt.Errorf("%s is not in the validator set", validatorAddr)
+	}
+	if agent.ID == "" || agent.ID != agentID || (agentName != "" && agent.Name != agentName) {
+		return fmt.Errorf("validator %s does not deliberate for agent %s (%s)", validatorAddr, agentID, agentName)
+	}
+	return nil
+}
+
+// DeliberationSubject returns the NATS subject carrying a given round of a proposal's discussion
+func DeliberationSubject(chainID, proposalID string, round int) string {
+	return fmt.Sprintf("deliberation.%s.%s.round.%d", chainID, proposalID, round)
+}
+
+// NewDeliberation joins the discussion of a proposal and starts collecting peer messages
+func NewDeliberation(messenger *Messenger, chainID, proposalID string, agent core.Agent, privKey crypto.PrivKey) (*Deliberation, error) {
+	d := &Deliberation{
+		messenger:  messenger,
+		chainID:    chainID,
+		proposalID: proposalID,
+		agent:      agent,
+		privKey:    privKey,
+		rounds:     make(map[int]map[string]DeliberationMessage),
+		arrived:    make(chan struct{}, 1),
+	}
+
+	// With JetStream the stored rounds are replayed first, so a validator joining late still sees earlier arguments
+	subject := fmt.Sprintf("deliberation.%s.%s.>", chainID, proposalID)
+	var sub *nats.Subscription
+	var err error
+	if messenger.broker.Durable(subject) {
+		sub, err = messenger.broker.ReplayFrom(subject, 0, d.handle)
+	} else {
+		sub, err = messenger.broker.Subscribe(subject, d.handle)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to deliberation: %v", err)
+	}
+	d.sub = sub
+	return d, nil
+}
+
+// handle verifies and records an incoming deliberation message. Only validators in the current set may post, and
+// only in the name of the agent bound to them.
+func (d *Deliberation) handle(msg *nats.Msg) {
+	var m DeliberationMessage
+	if err := json.Unmarshal(msg.Data, &m); err != nil {
+		log.Printf("Dropping malformed deliberation message: %v", err)
+		return
+	}
+	if m.ChainID != d.chainID || m.ProposalID != d.proposalID {
+		return
+	}
+	if !m.Verify() {
+		log.Printf("Dropping deliberation message with invalid signature from %s", m.ValidatorAddress)
+		return
+	}
+	if m.ValidatorAddress != d.privKey.PubKey().Address().String() {
+		if err := checkValidatorAgent(d.chainID, m.ValidatorAddress, m.AgentID, m.AgentName); err != nil {
+			log.Printf("Dropping deliberation message: %v", err)
+			return
+		}
+	}
+	d.record(m)
+}
+
+// record stores a verified message once and persists it to the chain's discussion log
+func (d *Deliberation) record(m DeliberationMessage) {
+	d.mu.Lock()
+	if d.rounds[m.Round] == nil {
+		d.rounds[m.Round] = make(map[string]DeliberationMessage)
+	}
+	if _, seen := d.rounds[m.Round][m.ValidatorAddress]; seen {
+		d.mu.Unlock()
+		return
+	}
+	d.rounds[m.Round][m.ValidatorAddress] = m
+	d.mu.Unlock()
+
+	utils.AppendDiscussionLog(d.chainID, formatDeliberationLine(m))
+	d.persist(m)
+	if !m.Passed && !m.Abstained {
+		RecordMentions(d.chainID, d.proposalID, m.AgentName, m.Round, m.Summary)
+	}
+
+	select {
+	case d.arrived <- struct{}{}:
+	default:
+	}
+}
+
+// persist appends the signed message to the proposal's deliberation record
+func (d *Deliberation) persist(m DeliberationMessage) {
+	filename := fmt.Sprintf("data/discussions/%s/%s.jsonl", d.chainID, d.proposalID)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		log.Printf("Warning: Failed to create deliberation directory: %v", err)
+		return
+	}
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Warning: Failed to open deliberation record: %v", err)
+		return
+	}
+	defer f.Close()
+
+	data, _ := json.Marshal(m)
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("Warning: Failed to persist deliberation message: %v", err)
+	}
+}
+
+// Post signs and publishes this validator's contribution for a round
+func (d *Deliberation) Post(round int, approval bool, summary string) error {
+	return d.publish(DeliberationMessage{Round: round, Approval: approval, Summary: summary})
+}
+
+// Pass records that this validator has nothing new to add in a round while keeping its stance
+func (d *Deliberation) Pass(round int, approval bool) error {
+	return d.publish(DeliberationMessage{Round: round, Approval: approval, Summary: "passes; stance unchanged", Passed: true})
+}
+
+// Abstain records that this validator could not review in a round. It counts towards the round barrier but
+// carries no stance.
+func (d *Deliberation) Abstain(round int) error {
+	return d.publish(DeliberationMessage{Round: round, Summary: "could not produce a review this round", Abstained: true})
+}
+
+// publish fills in this validator's identity on a round message, then signs, records and sends it
+func (d *Deliberation) publish(m DeliberationMessage) error {
+	m.ChainID = d.chainID
+	m.ProposalID = d.proposalID
+	m.AgentID = d.agent.ID
+	m.AgentName = d.agent.Name
+	m.ValidatorAddress = d.privKey.PubKey().Address().String()
+	m.Timestamp = time.Now().Unix()
+	m.PubKey = d.privKey.PubKey().Bytes()
+
+	sig, err := d.privKey.Sign(m.signBytes())
+	if err != nil {
+		return fmt.Errorf("failed to sign deliberation message: %v", err)
+	}
+	m.Signature = sig
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	// Record locally first so our own message counts towards the barrier even if the echo is slow
+	d.record(m)
+	return d.messenger.broker.Publish(DeliberationSubject(d.chainID, d.proposalID, m.Round), data)
+}
+
+// AwaitRound blocks until expected validators have posted for the round or the timeout elapses
+func (d *Deliberation) AwaitRound(round int, expected int, timeout time.Duration) []DeliberationMessage {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		msgs := d.RoundMessages(round)
+		if len(msgs) >= expected {
+			return msgs
+		}
+
+		select {
+		case <-d.arrived:
+		case <-deadline.C:
+			msgs := d.RoundMessages(round)
+			log.Printf("Round %d of proposal %s timed out with %d/%d messages", round, d.proposalID, len(msgs), expected)
+			return msgs
+		}
+	}
+}
+
+// AwaitTurn blocks until every validator scheduled to speak before this one in the round has posted,
+// or the timeout elapses
+func (d *Deliberation) AwaitTurn(round int, order []string, timeout time.Duration) {
+	self := d.privKey.PubKey().Address().String()
+	var before []string
+	for _, addr := range order {
+		if addr == self {
+			break
+		}
+		before = append(before, addr)
+	}
+	if len(before) == 0 {
+		return
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		d.mu.Lock()
+		waiting := 0
+		for _, addr := range before {
+			if _, ok := d.rounds[round][addr]; !ok {
+				waiting++
+			}
+		}
+		d.mu.Unlock()
+		if waiting == 0 {
+			return
+		}
+
+		select {
+		case <-d.arrived:
+		case <-deadline.C:
+			log.Printf("Turn wait in round %d of proposal %s timed out with %d speakers outstanding", round, d.proposalID, waiting)
+			return
+		}
+	}
+}
+
+// SpeakingOrder returns the deterministic order in which participants speak in a round.
+// Every validator computes the same order, and it rotates between rounds so no one always goes first.
+func SpeakingOrder(proposalID string, round int, participants []string) []string {
+	order := make([]string, len(participants))
+	copy(order, participants)
+	key := func(addr string) string {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%s", proposalID, round, addr)))
+		return hex.EncodeToString(sum[:])
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return key(order[i]) < key(order[j])
+	})
+	return order
+}
+
+// RoundMessages returns the messages received for a round ordered by validator address
+func (d *Deliberation) RoundMessages(round int) []DeliberationMessage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	msgs := make([]DeliberationMessage, 0, len(d.rounds[round]))
+	for _, m := range d.rounds[round] {
+		msgs = append(msgs, m)
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		return msgs[i].ValidatorAddress < msgs[j].ValidatorAddress
+	})
+	return msgs
+}
+
+// Transcript renders every round received so far in the discussion log format
+func (d *Deliberation) Transcript() string {
+	d.mu.Lock()
+	rounds := make([]int, 0, len(d.rounds))
+	for round := range d.rounds {
+		rounds = append(rounds, round)
+	}
+	d.mu.Unlock()
+	sort.Ints(rounds)
+
+	var b strings.Builder
+	for _, round := range rounds {
+		for _, m := range d.RoundMessages(round) {
+			b.WriteString(formatDeliberationLine(m) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Close leaves the proposal's discussion
+func (d *Deliberation) Close() {
+	if d.sub != nil {
+		d.sub.Unsubscribe()
+	}
+}
+
+// formatDeliberationLine renders a message in the format parsed by WatchDiscussionFile. Abstentions are marked
+// "(abstain)" in place of a stance so parsers do not read them as votes.
+func formatDeliberationLine(m DeliberationMessage) string {
+	summary := strings.ReplaceAll(m.Summary, "\n", " ")
+	if m.Abstained {
+		return fmt.Sprintf("[Round %d] (abstain) |@%s|: %s", m.Round, m.AgentName, summary)
+	}
+	if m.Passed {
+		summary = "(pass) " + summary
+	}
+	return fmt.Sprintf("[Round %d] (%v) |@%s|: %s", m.Round, m.Approval, m.AgentName, summary)
+}
+
+// VerdictSubject returns the NATS subject on which final verdicts for a proposal are announced
+func VerdictSubject(chainID, proposalID string) string {
+	return fmt.Sprintf("verdict.%s.%s", chainID, proposalID)
+}
+
+// VerdictAnnouncement is a validator's final verdict on a proposal, with the review behind it, signed by its
+// validator key like a deliberation message
+type VerdictAnnouncement struct {
+	ChainID          string          `json:"chain_id"`
+	ProposalID       string          `json:"proposal_id"`
+	AgentID          string          `json:"agent_id"`
+	ValidatorAddress string          `json:"validator_address"`
+	Verdict          json.RawMessage `json:"verdict"`
+	Review           json.RawMessage `json:"review,omitempty"`
+	Timestamp        int64           `json:"timestamp"`
+	PubKey           []byte          `json:"pub_key"`
+	Signature        []byte          `json:"signature,omitempty"`
+}
+
+// signBytes returns the canonical bytes covered by the announcement signature
+func (a VerdictAnnouncement) signBytes() []byte {
+	a.Signature = nil
+	data, _ := json.Marshal(a)
+	return data
+}
+
+// Verify checks the signature and that the signing key belongs to the claimed validator address
+func (a VerdictAnnouncement) Verify() bool {
+	return verifyValidatorSignature(a.PubKey, a.ValidatorAddress, a.signBytes(), a.Signature)
+}
+
+// SubscribeVerdicts delivers the verdicts announced on any proposal of a chain. Only announcements signed by a
+// validator in the current set, in the name of the agent bound to it, are delivered. With JetStream the node's
+// durable consumer also delivers those announced while it was offline. It returns nil without NATS.
+func SubscribeVerdicts(chainID string, handle func(VerdictAnnouncement)) (*nats.Subscription, error) {
+	broker := core.DefaultBroker()
+	if broker == nil {
+		return nil, nil
+	}
+	return broker.SubscribeNode(VerdictSubject(chainID, "*"), "verdicts-"+chainID, func(msg *nats.Msg) {
+		var a VerdictAnnouncement
+		if err := json.Unmarshal(msg.Data, &a); err != nil {
+			log.Printf("Dropping malformed verdict announcement: %v", err)
+			return
+		}
+		if a.ChainID != chainID || msg.Subject != VerdictSubject(chainID, a.ProposalID) {
+			return
+		}
+		if !a.Verify() {
+			log.Printf("Dropping verdict announcement with invalid signature from %s", a.ValidatorAddress)
+			return
+		}
+		if err := checkValidatorAgent(chainID, a.ValidatorAddress, a.AgentID, ""); err != nil {
+			log.Printf("Dropping verdict announcement: %v", err)
+			return
+		}
+		handle(a)
+	})
+}
+
+// PublishVerdict signs an agent's final verdict on a proposal with the validator key and announces it when NATS
+// is available
+func PublishVerdict(chainID, proposalID, agentID string, privKey crypto.PrivKey, verdict, review interface{}) {
+	broker := core.DefaultBroker()
+	if broker == nil {
+		return
+	}
+	a, err := signVerdict(chainID, proposalID, agentID, privKey, verdict, review)
+	if err != nil {
+		log.Printf("Failed to sign verdict for %s: %v", proposalID, err)
+		return
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		log.Printf("Failed to marshal verdict announcement: %v", err)
+		return
+	}
+	if err := broker.Publish(VerdictSubject(chainID, proposalID), data); err != nil {
+		log.Printf("Failed to publish verdict for %s: %v", proposalID, err)
+	}
+}
+
+// signVerdict wraps a verdict and its review in an announcement signed with the validator key
+func signVerdict(chainID, proposalID, agentID string, privKey crypto.PrivKey, verdict, review interface{}) (VerdictAnnouncement, error) {
+	verdictData, err := json.Marshal(verdict)
+	if err != nil {
+		return VerdictAnnouncement{}, fmt.Errorf("failed to marshal verdict: %v", err)
+	}
+	reviewData, err := json.Marshal(review)
+	if err != nil {
+		return VerdictAnnouncement{}, fmt.Errorf("failed to marshal review: %v", err)
+	}
+
+	a := VerdictAnnouncement{
+		ChainID:          chainID,
+		ProposalID:       proposalID,
+		AgentID:          agentID,
+		ValidatorAddress: privKey.PubKey().Address().String(),
+		Verdict:          verdictData,
+		Review:           reviewData,
+		Timestamp:        time.Now().Unix(),
+		PubKey:           privKey.PubKey().Bytes(),
+	}
+	if a.Signature, err = privKey.Sign(a.signBytes()); err != nil {
+		return VerdictAnnouncement{}, err
+	}
+	return a, nil
+}
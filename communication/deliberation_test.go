@@ -0,0 +1,72 @@
+package communication
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestVerdictAnnouncementVerify(t *testing.T) {
+	key := ed25519.GenPrivKey()
+	verdict := map[string]interface{}{"agent_id": "alice", "proposal_id": "P1", "decision": "reject"}
+
+	tests := []struct {
+		name   string
+		tamper func(a *VerdictAnnouncement)
+		want   bool
+	}{
+		{"untouched", func(a *VerdictAnnouncement) {}, true},
+		{"verdict rewritten", func(a *VerdictAnnouncement) {
+			a.Verdict = json.RawMessage(`{"agent_id":"alice","proposal_id":"P1","decision":"approve"}`)
+		}, false},
+		{"agent rewritten", func(a *VerdictAnnouncement) { a.AgentID = "mallory" }, false},
+		{"claims another validator", func(a *VerdictAnnouncement) {
+			a.ValidatorAddress = ed25519.GenPrivKey().PubKey().Address().String()
+		}, false},
+		{"re-signed with another key", func(a *VerdictAnnouncement) {
+			other := ed25519.GenPrivKey()
+			a.PubKey = other.PubKey().Bytes()
+			a.Signature, _ = other.Sign(a.signBytes())
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := signVerdict("chain", "P1", "alice", key, verdict, nil)
+			if err != nil {
+				t.Fatalf("signVerdict: %v", err)
+			}
+			tt.tamper(&a)
+			if got := a.Verify(); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckValidatorAgent(t *testing.T) {
+	member := ed25519.GenPrivKey().PubKey().Address().String()
+	SetValidatorAgents("verdict-chain", map[string]core.Agent{member: {ID: "alice", Name: "Alice"}})
+
+	tests := []struct {
+		name      string
+		validator string
+		agentID   string
+		wantErr   bool
+	}{
+		{"bound agent", member, "alice", false},
+		{"someone else's agent", member, "bob", true},
+		{"outside the validator set", ed25519.GenPrivKey().PubKey().Address().String(), "alice", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkValidatorAgent("verdict-chain", tt.validator, tt.agentID, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkValidatorAgent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -128,6 +128,25 @@ func processLine(line string, broadcast func(AgentVote)) {
 	}
 }
 
+// ParseDiscussion returns every round entry in a discussion log as a vote, in log order
+func ParseDiscussion(discussionLog string) []AgentVote {
+	var votes []AgentVote
+	for _, line := range strings.Split(discussionLog, "\n") {
+		matches := roundRegex.FindStringSubmatch(line)
+		if len(matches) != 5 {
+			continue
+		}
+		votes = append(votes, AgentVote{
+			ValidatorID:   matches[3],
+			ValidatorName: matches[3],
+			Message:       strings.TrimSpace(matches[4]),
+			Round:         parseInt(matches[1]),
+			Approval:      matches[2] == "true",
+		})
+	}
+	return votes
+}
+
 // parseInt converts a string to an integer, returning 0 on error
 func parseInt(s string) int {
 	val := 0
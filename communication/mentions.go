@@ -0,0 +1,286 @@
+package communication
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	SentimentAgree    = "agree"
+	SentimentDisagree = "disagree"
+	SentimentNeutral  = "neutral"
+)
+
+// Mention is a single |@Name| reference from one participant to another
+type Mention struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Sentiment string `json:"sentiment"`
+	Round     int    `json:"round"`
+	Excerpt   string `json:"excerpt"`
+	Valid     bool   `json:"valid"`
+}
+
+// InfluenceEdge aggregates every mention from one participant to another
+type InfluenceEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Agree    int    `json:"agree"`
+	Disagree int    `json:"disagree"`
+	Neutral  int    `json:"neutral"`
+}
+
+// InfluenceGraph is the mention graph of a proposal, or of a whole chain when ProposalID is empty
+type InfluenceGraph struct {
+	ChainID         string          `json:"chain_id"`
+	ProposalID      string          `json:"proposal_id,omitempty"`
+	Participants    []string        `json:"participants"`
+	Edges           []InfluenceEdge `json:"edges"`
+	InvalidMentions []Mention       `json:"invalid_mentions"`
+}
+
+type proposalMentions struct {
+	participants map[string]bool
+	mentions     []Mention
+}
+
+var (
+	mentionRegex  = regexp.MustCompile(`\|@([^|]+)\|`)
+	sentenceRegex = regexp.MustCompile(`[^.!?]+[.!?]*`)
+
+	wordRegex = regexp.MustCompile(`[a-z]+(?:'[a-z]+)?`)
+
+	// Markers match whole words, so "incorrect" is not read as "correct"
+	disagreeMarkers = splitMarkers("disagree", "disagrees", "however", "but", "challenge", "challenges", "flawed",
+		"wrong", "incorrect", "unsupported", "invalid", "unconvinced", "doubt", "doubts", "overlook", "overlooks",
+		"misses", "fails to", "counter", "skeptical", "reject", "rejects")
+	// Words that only agree in some phrases, such as "right" or "share", are only markers within those phrases
+	agreeMarkers = splitMarkers("i agree", "we agree", "agree with", "agrees with", "agreed", "valid point",
+		"good point", "fair point", "is right", "are right", "you're right", "right about", "rightly", "concur",
+		"concurs", "i echo", "echoes", "builds on", "build on", "as noted", "support", "supports", "correct",
+		"convinced", "well said", "share the concern", "shares the concern", "share the view", "shares the view")
+	// negations flip the marker that follows within negationWindow words
+	negations = map[string]bool{"not": true, "no": true, "never": true, "hardly": true, "cannot": true,
+		"can't": true, "don't": true, "doesn't": true, "didn't": true, "isn't": true, "aren't": true, "wasn't": true}
+
+	mentionGraphs   = make(map[string]map[string]*proposalMentions)
+	mentionGraphsMu sync.RWMutex
+)
+
+// ExtractMentions finds every |@Name| reference in a message and classifies the sentence it appears in
+func ExtractMentions(speaker, message string, round int) []Mention {
+	var mentions []Mention
+	for _, sentence := range sentenceRegex.FindAllString(message, -1) {
+		matches := mentionRegex.FindAllStringSubmatch(sentence, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		sentiment := classifySentiment(sentence)
+		for _, match := range matches {
+			mentions = append(mentions, Mention{
+				From:      speaker,
+				To:        strings.TrimSpace(match[1]),
+				Sentiment: sentiment,
+				Round:     round,
+				Excerpt:   strings.TrimSpace(sentence),
+			})
+		}
+	}
+	return mentions
+}
+
+// negationWindow is how many words before a marker are searched for a negation
+const negationWindow = 3
+
+// classifySentiment labels a sentence as agreeing, disagreeing or neutral towards the validators it mentions.
+// A negated marker counts for the opposite side, so "not correct" disagrees and "not wrong" agrees.
+func classifySentiment(sentence string) string {
+	words := wordRegex.FindAllString(strings.ToLower(mentionRegex.ReplaceAllString(sentence, "")), -1)
+	agree, disagree := 0, 0
+	for i := 0; i < len(words); i++ {
+		length, agrees := markerAt(words, i)
+		if length == 0 {
+			continue
+		}
+		if agrees != negated(words, i) {
+			agree++
+		} else {
+			disagree++
+		}
+		// Words of a phrase count once, so "I agree with" is one marker rather than two
+		i += length - 1
+	}
+
+	switch {
+	case disagree > 0 && disagree >= agree:
+		return SentimentDisagree
+	case agree > 0:
+		return SentimentAgree
+	default:
+		return SentimentNeutral
+	}
+}
+
+// splitMarkers splits each marker phrase into its words
+func splitMarkers(phrases ...string) [][]string {
+	markers := make([][]string, len(phrases))
+	for i, phrase := range phrases {
+		markers[i] = strings.Fields(phrase)
+	}
+	return markers
+}
+
+// markerAt returns the length of the marker that starts at words[i], or 0 if none does, and whether it agrees
+func markerAt(words []string, i int) (int, bool) {
+	for _, marker := range agreeMarkers {
+		if matchesMarker(words, i, marker) {
+			return len(marker), true
+		}
+	}
+	for _, marker := range disagreeMarkers {
+		if matchesMarker(words, i, marker) {
+			return len(marker), false
+		}
+	}
+	return 0, false
+}
+
+// matchesMarker reports whether the marker's words start at words[i]
+func matchesMarker(words []string, i int, marker []string) bool {
+	if i+len(marker) > len(words) {
+		return false
+	}
+	for j, word := range marker {
+		if words[i+j] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// negated reports whether one of the negationWindow words before words[i] is a negation
+func negated(words []string, i int) bool {
+	for j := i - 1; j >= 0 && j >= i-negationWindow; j-- {
+		if negations[words[j]] {
+			return true
+		}
+	}
+	return false
+}
+
+// proposalGraph returns the mention record of a proposal, creating it if needed. Callers must hold mentionGraphsMu.
+func proposalGraph(chainID, proposalID string) *proposalMentions {
+	if mentionGraphs[chainID] == nil {
+		mentionGraphs[chainID] = make(map[string]*proposalMentions)
+	}
+	pm := mentionGraphs[chainID][proposalID]
+	if pm == nil {
+		pm = &proposalMentions{participants: make(map[string]bool)}
+		mentionGraphs[chainID][proposalID] = pm
+	}
+	return pm
+}
+
+// RecordParticipant marks a validator as having spoken in a proposal's discussion
+func RecordParticipant(chainID, proposalID, name string) {
+	mentionGraphsMu.Lock()
+	defer mentionGraphsMu.Unlock()
+	proposalGraph(chainID, proposalID).participants[name] = true
+}
+
+// RecordMentions adds a participant's message to a proposal's mention graph. Mentions of validators that
+// have not yet spoken in the proposal, or of the speaker itself, are kept but flagged invalid.
+func RecordMentions(chainID, proposalID, speaker string, round int, message string) []Mention {
+	mentionGraphsMu.Lock()
+	pm := proposalGraph(chainID, proposalID)
+
+	mentions := ExtractMentions(speaker, message, round)
+	for i := range mentions {
+		mentions[i].Valid = pm.participants[mentions[i].To] && mentions[i].To != speaker
+	}
+	pm.mentions = append(pm.mentions, mentions...)
+	pm.participants[speaker] = true
+	mentionGraphsMu.Unlock()
+
+	if len(mentions) > 0 {
+		go BroadcastEvent(EventMentionGraph, GetMentionGraph(chainID, proposalID))
+	}
+	return mentions
+}
+
+// GetMentionGraph returns the influence graph of a single proposal
+func GetMentionGraph(chainID, proposalID string) InfluenceGraph {
+	mentionGraphsMu.RLock()
+	defer mentionGraphsMu.RUnlock()
+
+	graph := InfluenceGraph{ChainID: chainID, ProposalID: proposalID}
+	if pm := mentionGraphs[chainID][proposalID]; pm != nil {
+		buildGraph(&graph, []*proposalMentions{pm})
+	}
+	return graph
+}
+
+// GetChainMentionGraph returns the influence graph aggregated over every proposal on a chain
+func GetChainMentionGraph(chainID string) InfluenceGraph {
+	mentionGraphsMu.RLock()
+	defer mentionGraphsMu.RUnlock()
+
+	graph := InfluenceGraph{ChainID: chainID}
+	var all []*proposalMentions
+	for _, pm := range mentionGraphs[chainID] {
+		all = append(all, pm)
+	}
+	buildGraph(&graph, all)
+	return graph
+}
+
+// buildGraph folds recorded mentions into participants, weighted edges and invalid mentions
+func buildGraph(graph *InfluenceGraph, sources []*proposalMentions) {
+	participants := make(map[string]bool)
+	edges := make(map[[2]string]*InfluenceEdge)
+
+	for _, pm := range sources {
+		for participant := range pm.participants {
+			participants[participant] = true
+		}
+		for _, m := range pm.mentions {
+			if !m.Valid {
+				graph.InvalidMentions = append(graph.InvalidMentions, m)
+				continue
+			}
+			key := [2]string{m.From, m.To}
+			edge := edges[key]
+			if edge == nil {
+				edge = &InfluenceEdge{From: m.From, To: m.To}
+				edges[key] = edge
+			}
+			switch m.Sentiment {
+			case SentimentAgree:
+				edge.Agree++
+			case SentimentDisagree:
+				edge.Disagree++
+			default:
+				edge.Neutral++
+			}
+		}
+	}
+
+	graph.Participants = make([]string, 0, len(participants))
+	for participant := range participants {
+		graph.Participants = append(graph.Participants, participant)
+	}
+	sort.Strings(graph.Participants)
+
+	graph.Edges = make([]InfluenceEdge, 0, len(edges))
+	for _, edge := range edges {
+		graph.Edges = append(graph.Edges, *edge)
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+}
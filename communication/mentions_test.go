@@ -0,0 +1,41 @@
+package communication
+
+import "testing"
+
+func TestClassifySentiment(t *testing.T) {
+	tests := []struct {
+		name     string
+		sentence string
+		want     string
+	}{
+		{"plain agreement", "|@Bob| is correct about the sample size.", SentimentAgree},
+		{"in- prefix", "|@Bob| is incorrect about the sample size.", SentimentDisagree},
+		{"un- prefix", "The claim by |@Bob| is unsupported.", SentimentDisagree},
+		{"support", "I support the point |@Bob| raised.", SentimentAgree},
+		{"negated agreement", "I do not agree with |@Bob|.", SentimentDisagree},
+		{"negated correctness", "|@Bob| is not correct here.", SentimentDisagree},
+		{"negated disagreement", "|@Bob| is not wrong.", SentimentAgree},
+		{"disagree is not agree", "I disagree with |@Bob|.", SentimentDisagree},
+		{"hedged agreement", "I agree with |@Bob|, but the method is flawed.", SentimentDisagree},
+		{"marker inside a word", "|@Bob| reports brighter results.", SentimentNeutral},
+		{"neutral", "|@Bob| mentioned the dataset.", SentimentNeutral},
+		{"phrase marker", "As noted by |@Bob|, the controls are adequate.", SentimentAgree},
+		{"agree with", "I agree with |@Bob| on the controls.", SentimentAgree},
+		{"agreement phrase hedged once", "I agree with |@Bob|, however the sample is small.", SentimentDisagree},
+		{"negated agreement phrase", "I don't agree with |@Bob| at all.", SentimentDisagree},
+		{"right as agreement", "|@Bob| is right about the controls.", SentimentAgree},
+		{"negated right", "|@Bob| is not right about the controls.", SentimentDisagree},
+		{"right in another sense", "|@Bob| has the right to object.", SentimentNeutral},
+		{"share as a noun", "|@Bob| claims a larger share of the rewards.", SentimentNeutral},
+		{"share as agreement", "I share the concern |@Bob| raised.", SentimentAgree},
+		{"negated share", "I do not share the view of |@Bob|.", SentimentDisagree},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifySentiment(tt.sentence); got != tt.want {
+				t.Errorf("classifySentiment(%q) = %s, want %s", tt.sentence, got, tt.want)
+			}
+		})
+	}
+}
@@ -20,6 +20,11 @@ func NewMessenger(url string) (*Messenger, error) {
 	return &Messenger{broker: broker}, nil
 }
 
+// Creates a Messenger on top of an already established broker
+func NewMessengerFromBroker(broker *core.NATSBroker) *Messenger {
+	return &Messenger{broker: broker}
+}
+
 // Publishes a message to a global subject
 func (m *Messenger) PublishGlobal(subject, message string) error {
 	return m.broker.Publish(subject, []byte(message))
@@ -33,11 +38,13 @@ func (m *Messenger) PublishPrivate(agentID, message string) error {
 
 // Subscribes to messages on a global topic
 func (m *Messenger) SubscribeGlobal(subject string, handler nats.MsgHandler) error {
-	return m.broker.Subscribe(subject, handler)
+	_, err := m.broker.Subscribe(subject, handler)
+	return err
 }
 
 // Subscribes to private messages for a specific agent
 func (m *Messenger) SubscribePrivate(agentID string, handler nats.MsgHandler) error {
 	subject := fmt.Sprintf("agent.%s.private", agentID)
-	return m.broker.Subscribe(subject, handler)
+	_, err := m.broker.Subscribe(subject, handler)
+	return err
 }
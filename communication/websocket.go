@@ -1,9 +1,12 @@
 package communication
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"sync"
 
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
 	"github.com/gorilla/websocket"
 )
 
@@ -13,13 +16,23 @@ type WSEvent struct {
 }
 
 const (
-	EventBlockVerdict    = "BLOCK_VERDICT"
-	EventAgentVote       = "AGENT_VOTE"
-	EventVotingResult    = "VOTING_RESULT"
-	EventAgentAlliance   = "AGENT_ALLIANCE"
-	EventAgentRegistered = "AGENT_REGISTERED"
-	EventNewTransaction  = "NEW_TRANSACTION"
-	EventChainCreated    = "CHAIN_CREATED"
+	EventBlockVerdict       = "BLOCK_VERDICT"
+	EventAgentVote          = "AGENT_VOTE"
+	EventVotingResult       = "VOTING_RESULT"
+	EventAgentAlliance      = "AGENT_ALLIANCE"
+	EventAgentRegistered    = "AGENT_REGISTERED"
+	EventAgentUpdated       = "AGENT_UPDATED"
+	EventAgentRemoved       = "AGENT_REMOVED"
+	EventNewTransaction     = "NEW_TRANSACTION"
+	EventChainCreated       = "CHAIN_CREATED"
+	EventHumanComment       = "HUMAN_COMMENT"
+	EventMentionGraph       = "MENTION_GRAPH"
+	EventProposalFlagged    = "PROPOSAL_FLAGGED"
+	EventBlockSelection     = "BLOCK_SELECTION"
+	EventBlockAnnouncement  = "BLOCK_ANNOUNCEMENT"
+	EventRewardsDistributed = "REWARDS_DISTRIBUTED"
+	EventStakeChanged       = "STAKE_CHANGED"
+	EventPersonasGenerated  = "PERSONAS_GENERATED"
 )
 
 type WebSocketManager struct {
@@ -30,9 +43,16 @@ type WebSocketManager struct {
 	mu         sync.RWMutex
 }
 
+// eventQueueSize bounds the events waiting for WebSocket clients and for NATS. Events are dropped, not waited
+// for, once a queue is full, so a slow client or broker never stalls block execution.
+const eventQueueSize = 1024
+
 var (
 	wsManager *WebSocketManager
 	once      sync.Once
+
+	eventQueue     chan WSEvent
+	eventQueueOnce sync.Once
 )
 
 // GetWSManager returns a singleton instance of WebSocketManager
@@ -40,7 +60,7 @@ func GetWSManager() *WebSocketManager {
 	once.Do(func() {
 		wsManager = &WebSocketManager{
 			clients:    make(map[*websocket.Conn]bool),
-			broadcast:  make(chan WSEvent),
+			broadcast:  make(chan WSEvent, eventQueueSize),
 			register:   make(chan *websocket.Conn),
 			unregister: make(chan *websocket.Conn),
 		}
@@ -80,13 +100,49 @@ func (manager *WebSocketManager) run() {
 	}
 }
 
-// BroadcastEvent sends an event to all connected WebSocket clients
+// BroadcastEvent sends an event to all connected WebSocket clients and to the NATS event bus. It never blocks:
+// both are fed through bounded queues, and an event that finds its queue full is dropped.
 func BroadcastEvent(eventType string, payload interface{}) {
 	event := WSEvent{
 		Type:    eventType,
 		Payload: payload,
 	}
-	GetWSManager().broadcast <- event
+
+	eventQueueOnce.Do(func() {
+		eventQueue = make(chan WSEvent, eventQueueSize)
+		go func() {
+			for queued := range eventQueue {
+				publishEvent(queued)
+			}
+		}()
+	})
+	select {
+	case eventQueue <- event:
+	default:
+		log.Printf("Event queue full, not publishing %s to NATS", event.Type)
+	}
+
+	select {
+	case GetWSManager().broadcast <- event:
+	default:
+		log.Printf("WebSocket queue full, dropping %s", event.Type)
+	}
+}
+
+// publishEvent mirrors an event onto the events.<type> subject when NATS is available
+func publishEvent(event WSEvent) {
+	broker := core.DefaultBroker()
+	if broker == nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal event %s: %v", event.Type, err)
+		return
+	}
+	if err := broker.Publish(fmt.Sprintf("events.%s", event.Type), data); err != nil {
+		log.Printf("Failed to publish event %s: %v", event.Type, err)
+	}
 }
 
 // Register returns the channel for registering new WebSocket connections
@@ -0,0 +1,134 @@
+package abci
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+)
+
+// AgentBinding is the on-chain record of which agent deliberates for a validator
+type AgentBinding struct {
+	AgentID          string       `json:"agent_id"`
+	PersonaHash      string       `json:"persona_hash"`
+	Persona          core.Persona `json:"persona"`
+	ValidatorAddress string       `json:"validator_address"`
+	ValidatorPubKey  []byte       `json:"validator_pub_key"`
+	Nonce            uint64       `json:"nonce"`
+	Height           int64        `json:"height"`
+	RewardAddress    string       `json:"reward_address,omitempty"`
+}
+
+// checkAgentRegistration validates a register_agent transaction against the chain and the bindings committed so far
+func (app *Application) checkAgentRegistration(tx core.Transaction) (core.AgentRegistration, error) {
+	if tx.ChainID != app.chainID {
+		return core.AgentRegistration{}, fmt.Errorf("registration is for chain %q, not %q", tx.ChainID, app.chainID)
+	}
+	reg, err := core.ParseAgentRegistration(tx)
+	if err != nil {
+		return core.AgentRegistration{}, err
+	}
+
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+
+	address := reg.ValidatorAddress()
+	if existing, exists := app.state.AgentBindings[address]; exists && reg.Nonce <= existing.Nonce {
+		return core.AgentRegistration{}, fmt.Errorf("nonce %d is not above the current registration's %d", reg.Nonce, existing.Nonce)
+	}
+	for otherAddress, binding := range app.state.AgentBindings {
+		if binding.AgentID == reg.Persona.ID && otherAddress != address {
+			return core.AgentRegistration{}, fmt.Errorf("agent %s is already bound to validator %s", reg.Persona.ID, otherAddress)
+		}
+	}
+	return reg, nil
+}
+
+// applyAgentRegistration records a binding in the app state and mirrors it into the node's local registry, where
+// the agent keeps any settings configured on this node
+func (app *Application) applyAgentRegistration(reg core.AgentRegistration, height int64) {
+	address := reg.ValidatorAddress()
+	binding := AgentBinding{
+		AgentID:          reg.Persona.ID,
+		PersonaHash:      reg.PersonaHash,
+		Persona:          reg.Persona,
+		ValidatorAddress: address,
+		ValidatorPubKey:  reg.ValidatorPubKey,
+		Nonce:            reg.Nonce,
+		Height:           height,
+		RewardAddress:    reg.RewardAddress,
+	}
+
+	app.stateMu.Lock()
+	app.state.AgentBindings[address] = binding
+	// A bound validator can be staked to even if it never sent a register_validator transaction
+	if _, registered := app.state.Validators[address]; !registered {
+		app.state.Validators[address] = StakedValidator{PubKey: reg.ValidatorPubKey}
+	}
+	app.stateMu.Unlock()
+
+	persona := reg.Persona
+	if local, exists := registry.GetAgent(app.chainID, persona.ID); exists {
+		persona.Settings = core.PersonaFromAgent(local).Settings
+	}
+	registry.RegisterAgent(app.chainID, persona.Agent())
+	registry.LinkAgentToValidator(app.chainID, persona.ID, address)
+	communication.BroadcastEvent(communication.EventAgentRegistered, binding)
+}
+
+// agentBinding returns the committed binding of a validator
+func (app *Application) agentBinding(validatorAddr string) (AgentBinding, bool) {
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+	binding, exists := app.state.AgentBindings[validatorAddr]
+	return binding, exists
+}
+
+// resolveAgent returns the agent deliberating for a validator. The on-chain binding decides which agent and
+// persona that is; the local registry only adds node-local settings and the persona version when it holds the
+// same persona. Validators without a binding fall back to the local registry.
+func (app *Application) resolveAgent(validatorAddr string) (core.Agent, bool) {
+	binding, bound := app.agentBinding(validatorAddr)
+	if !bound {
+		return registry.GetAgentByValidator(app.chainID, validatorAddr)
+	}
+
+	agent := binding.Persona.Agent()
+	if local, exists := registry.GetAgent(app.chainID, binding.AgentID); exists {
+		if local.Disabled {
+			return core.Agent{}, false
+		}
+		localPersona := core.PersonaFromAgent(local)
+		for key, value := range localPersona.Settings {
+			if _, set := agent.Metadata[key]; !set {
+				agent.Metadata[key] = value
+			}
+		}
+		if localPersona.Hash() == binding.PersonaHash {
+			agent.PersonaVersion = local.PersonaVersion
+		} else {
+			log.Printf("Local persona of agent %s differs from its on-chain registration, using the registered persona", binding.AgentID)
+		}
+	}
+	agent.ValidatorAddress = validatorAddr
+	agent.IsValidator = true
+	return agent, true
+}
+
+// queryAgentBindings answers the agent_binding and agent_bindings queries
+func (app *Application) queryAgentBindings(path string, data []byte) ([]byte, error) {
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+
+	if path == "agent_bindings" {
+		return json.Marshal(app.state.AgentBindings)
+	}
+	binding, exists := app.state.AgentBindings[string(data)]
+	if !exists {
+		return nil, fmt.Errorf("no agent bound to validator %s", data)
+	}
+	return json.Marshal(binding)
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/utils"
@@ -24,16 +25,105 @@ type Application struct {
 	selfValidatorAddr string
 	validators        []types.ValidatorUpdate
 	pendingValUpdates []types.ValidatorUpdate
+	privKey           crypto.PrivKey
+	height            int64
+	stateMu           sync.RWMutex
+	state             *appState
+	// skipped counts, per pending transaction, the proposals of this node whose persona left it out
+	skipped map[string]int
+	// ownVerdicts are this node's verdicts from ProcessProposal, attested on chain once their proposal commits
+	ownVerdicts        map[string]ai.Verdict
+	committedProposals []string
+	broadcastTx        func(tx []byte) error
 }
 
-func NewApplication(chainID string, selfValidatorAddr string) types.Application {
-	return &Application{
+func NewApplication(chainID string, selfValidatorAddr string) *Application {
+	app := &Application{
 		chainID:           chainID,
 		discussions:       make(map[string]map[string]bool),
 		selfValidatorAddr: selfValidatorAddr,
 		validators:        make([]types.ValidatorUpdate, 0),
 		pendingValUpdates: make([]types.ValidatorUpdate, 0),
+		state:             newAppState(),
+		skipped:           make(map[string]int),
+		ownVerdicts:       make(map[string]ai.Verdict),
 	}
+	communication.SetCommentAuthorizer(chainID, app.authorizePublishedComment)
+	return app
+}
+
+// SetValidatorKey gives the application the node's validator key so it can sign deliberation messages
+func (app *Application) SetValidatorKey(privKey crypto.PrivKey) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	app.privKey = privKey
+}
+
+// SetTxBroadcaster gives the application a way to submit its own transactions to the node's mempool
+func (app *Application) SetTxBroadcaster(broadcast func(tx []byte) error) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	app.broadcastTx = broadcast
+}
+
+// discussionChannel returns the channel the agent deliberates a proposal over, preferring NATS when available.
+// The returned function releases the channel once deliberation is over.
+func (app *Application) discussionChannel(agent core.Agent, proposalID string) (ai.DiscussionChannel, func()) {
+	broker := core.DefaultBroker()
+	if broker == nil || app.privKey == nil {
+		return ai.NewLocalChannel(app.chainID, proposalID, agent), func() {}
+	}
+
+	messenger := communication.NewMessengerFromBroker(broker)
+	deliberation, err := communication.NewDeliberation(messenger, app.chainID, proposalID, agent, app.privKey)
+	if err != nil {
+		log.Printf("Falling back to local discussion log: %v", err)
+		return ai.NewLocalChannel(app.chainID, proposalID, agent), func() {}
+	}
+
+	participants := app.deliberatingValidators()
+	return ai.NewNetworkChannel(deliberation, proposalID, participants, communication.DefaultRoundTimeout), deliberation.Close
+}
+
+// currentHeight returns the height of the block being executed
+func (app *Application) currentHeight() int64 {
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+	return app.height
+}
+
+// deliberators returns the addresses of validators in the current set that have an agent attached. Callers hold mu.
+func (app *Application) deliberators() []string {
+	var participants []string
+	for _, val := range app.validators {
+		address := ed25519.PubKey(val.PubKey.GetEd25519()).Address().String()
+		if _, exists := app.resolveAgent(address); exists {
+			participants = append(participants, address)
+		}
+	}
+	return participants
+}
+
+// deliberatingValidators returns the validators that deliberate with this node, or this node alone when no
+// validator in the set has an agent attached
+func (app *Application) deliberatingValidators() []string {
+	participants := app.deliberators()
+	if len(participants) == 0 {
+		participants = append(participants, app.privKey.PubKey().Address().String())
+	}
+	return participants
+}
+
+// publishValidatorAgents shares the current validator set and the agent each validator deliberates for, which
+// incoming deliberation messages and verdicts are checked against. Callers hold mu.
+func (app *Application) publishValidatorAgents() {
+	agents := make(map[string]core.Agent, len(app.validators))
+	for _, val := range app.validators {
+		address := ed25519.PubKey(val.PubKey.GetEd25519()).Address().String()
+		agent, _ := app.resolveAgent(address)
+		agents[address] = agent
+	}
+	communication.SetValidatorAgents(app.chainID, agents)
 }
 
 // Info returns basic information about the application
@@ -51,9 +141,16 @@ func (app *Application) Info(req types.RequestInfo) types.ResponseInfo {
 func (app *Application) InitChain(req types.RequestInitChain) types.ResponseInitChain {
 	app.mu.Lock()
 	defer app.mu.Unlock()
+	defer app.publishValidatorAgents()
 
 	log.Printf("the number of validators coming from the genesis is %d", len(req.Validators))
 	app.validators = req.Validators
+	if err := app.initGenesis(req.AppStateBytes, req.Validators); err != nil {
+		log.Printf("Starting without genesis balances: %v", err)
+	} else if bonded := app.bondedValidators(); len(bonded) > 0 {
+		// Voting power follows the genesis stake
+		app.validators = bonded
+	}
 
 	return types.ResponseInitChain{
 		Validators: app.validators,
@@ -79,11 +176,99 @@ func (app *Application) InitChain(req types.RequestInitChain) types.ResponseInit
 
 // Query handles queries to the application state
 func (app *Application) Query(req types.RequestQuery) types.ResponseQuery {
+	switch req.Path {
+	case "agent_binding", "agent_bindings":
+		value, err := app.queryAgentBindings(req.Path, req.Data)
+		if err != nil {
+			return types.ResponseQuery{Code: 1, Log: err.Error()}
+		}
+		return types.ResponseQuery{Code: 0, Key: req.Data, Value: value}
+	case "rewards":
+		value, err := json.Marshal(app.queryRewards(string(req.Data)))
+		if err != nil {
+			return types.ResponseQuery{Code: 1, Log: err.Error()}
+		}
+		return types.ResponseQuery{Code: 0, Key: req.Data, Value: value}
+	case "stake", "stakes", "delegations":
+		value, err := app.queryStakes(req.Path, req.Data)
+		if err != nil {
+			return types.ResponseQuery{Code: 1, Log: err.Error()}
+		}
+		return types.ResponseQuery{Code: 0, Key: req.Data, Value: value}
+	case "account", "fees":
+		value, err := app.queryAccount(req.Path, req.Data)
+		if err != nil {
+			return types.ResponseQuery{Code: 1, Log: err.Error()}
+		}
+		return types.ResponseQuery{Code: 0, Key: req.Data, Value: value}
+	case "deliberators":
+		app.mu.RLock()
+		deliberators := app.deliberators()
+		app.mu.RUnlock()
+		value, err := json.Marshal(deliberators)
+		if err != nil {
+			return types.ResponseQuery{Code: 1, Log: err.Error()}
+		}
+		return types.ResponseQuery{Code: 0, Key: req.Data, Value: value}
+	}
 	return types.ResponseQuery{}
 }
 
 // CheckTx validates a transaction before it enters the mempool
 func (app *Application) CheckTx(req types.RequestCheckTx) types.ResponseCheckTx {
+	var tx core.Transaction
+	if err := json.Unmarshal(req.Tx, &tx); err != nil {
+		return types.ResponseCheckTx{Code: 0}
+	}
+
+	if err := app.checkStaking(tx); err != nil {
+		return types.ResponseCheckTx{
+			Code: 1,
+			Log:  fmt.Sprintf("Invalid staking transaction: %v", err),
+		}
+	}
+
+	if err := app.newLedger().pay(tx); err != nil {
+		return types.ResponseCheckTx{
+			Code: 1,
+			Log:  fmt.Sprintf("Unpaid transaction: %v", err),
+		}
+	}
+
+	if tx.Type == "human_comment" {
+		if _, err := app.checkHumanComment(tx, req.Tx, true); err != nil {
+			return types.ResponseCheckTx{
+				Code: 1,
+				Log:  fmt.Sprintf("Invalid human comment: %v", err),
+			}
+		}
+	}
+
+	// Remember who submitted the proposal, so its author can comment while it is deliberated
+	if deliberatedTx(tx) {
+		if sender, err := tx.Sender(); err == nil {
+			communication.RecordProposalSubmitter(app.chainID, core.ProposalID(req.Tx), sender)
+		}
+	}
+
+	if tx.Type == core.TxRegisterAgent {
+		if _, err := app.checkAgentRegistration(tx); err != nil {
+			return types.ResponseCheckTx{
+				Code: 1,
+				Log:  fmt.Sprintf("Invalid agent registration: %v", err),
+			}
+		}
+	}
+
+	if tx.Type == core.TxAgentVerdict {
+		if _, err := app.checkVerdictAttestation(tx); err != nil {
+			return types.ResponseCheckTx{
+				Code: 1,
+				Log:  fmt.Sprintf("Invalid verdict attestation: %v", err),
+			}
+		}
+	}
+
 	return types.ResponseCheckTx{Code: 0}
 }
 
@@ -99,6 +284,30 @@ func (app *Application) DeliverTx(req types.RequestDeliverTx) types.ResponseDeli
 		}
 	}
 
+	// Staking is checked before payment so a stake that cannot be bonded does not leave the account
+	if err := app.checkStaking(tx); err != nil {
+		return types.ResponseDeliverTx{
+			Code: 1,
+			Log:  fmt.Sprintf("Invalid staking transaction: %v", err),
+		}
+	}
+
+	// The fee is charged once the transaction is in a block, whether or not it then succeeds
+	payments := app.newLedger()
+	if err := payments.pay(tx); err != nil {
+		return types.ResponseDeliverTx{
+			Code: 1,
+			Log:  fmt.Sprintf("Unpaid transaction: %v", err),
+		}
+	}
+	payments.commit()
+	if deliberatedTx(tx) {
+		app.recordSubmitter(core.ProposalID(req.Tx), tx)
+	}
+	if deliberatedTx(tx) && tx.Fee > 0 {
+		app.openDeliberation(core.ProposalID(req.Tx), tx, app.currentHeight())
+	}
+
 	switch tx.Type {
 	case "submit_paper":
 		var paper ai.ResearchPaper
@@ -109,26 +318,73 @@ func (app *Application) DeliverTx(req types.RequestDeliverTx) types.ResponseDeli
 			}
 		}
 		log.Printf("Research paper submitted: %s by %s", paper.Title, paper.Author)
+		proposalID := core.ProposalID(req.Tx)
+		ai.RecordOutcome(app.chainID, proposalID, ai.OutcomeCommitted)
+		ai.IndexCommittedProposal(app.chainID, proposalID, tx.Type, paper.Title, paper.Abstract+"\n"+paper.Content, app.currentHeight())
 		return types.ResponseDeliverTx{
 			Code: 0,
 			Log:  fmt.Sprintf("Paper '%s' accepted for review", paper.Title),
 		}
 
 	case "register_validator":
-		if len(tx.Data) == 0 {
+		if len(tx.Data) != ed25519.PubKeySize {
 			return types.ResponseDeliverTx{
 				Code: 1,
 				Log:  "Missing validator public key",
 			}
 		}
 		pubKey := ed25519.PubKey(tx.Data)
-		app.RegisterValidator(pubKey, 1000000)
+		app.RegisterValidator(pubKey)
 		log.Printf("Registered validator %s with pubkey %X", tx.From, tx.Data)
 		return types.ResponseDeliverTx{
 			Code: 0,
 			Log:  fmt.Sprintf("Validator %s registered successfully", tx.From),
 		}
 
+	case core.TxRegisterAgent:
+		reg, err := app.checkAgentRegistration(tx)
+		if err != nil {
+			return types.ResponseDeliverTx{
+				Code: 1,
+				Log:  fmt.Sprintf("Invalid agent registration: %v", err),
+			}
+		}
+		app.applyAgentRegistration(reg, app.currentHeight())
+		log.Printf("Agent %s bound to validator %s", reg.Persona.ID, tx.From)
+		return types.ResponseDeliverTx{
+			Code: 0,
+			Log:  fmt.Sprintf("Agent %s bound to validator %s", reg.Persona.ID, tx.From),
+		}
+
+	case core.TxAgentVerdict:
+		attestation, err := app.checkVerdictAttestation(tx)
+		if err != nil {
+			return types.ResponseDeliverTx{
+				Code: 1,
+				Log:  fmt.Sprintf("Invalid verdict attestation: %v", err),
+			}
+		}
+		app.applyVerdictAttestation(attestation)
+		return types.ResponseDeliverTx{
+			Code: 0,
+			Log:  fmt.Sprintf("Verdict of agent %s on proposal %s attested", attestation.AgentID, attestation.ProposalID),
+		}
+
+	case core.TxStake, core.TxDelegate, core.TxUnstake:
+		validator := app.applyStaking(tx, app.currentHeight())
+		log.Printf("Applied %s of %.0f by %s to validator %s, now bonded %d", tx.Type, tx.Amount, tx.From, tx.To, validator.Bonded)
+		return types.ResponseDeliverTx{
+			Code: 0,
+			Log:  fmt.Sprintf("Applied %s of %.0f by %s to validator %s", tx.Type, tx.Amount, tx.From, tx.To),
+		}
+
+	case core.TxTransfer:
+		log.Printf("Transferred %.0f from %s to %s", tx.Amount, tx.From, tx.To)
+		return types.ResponseDeliverTx{
+			Code: 0,
+			Log:  fmt.Sprintf("Transferred %.0f from %s to %s", tx.Amount, tx.From, tx.To),
+		}
+
 	case "discuss_transaction":
 		log.Printf("Accepted discussion from validator %s", tx.From)
 		return types.ResponseDeliverTx{
@@ -138,11 +394,34 @@ func (app *Application) DeliverTx(req types.RequestDeliverTx) types.ResponseDeli
 
 	case "loan_request":
 		log.Printf("Loan request received from: %s", tx.From)
+		proposalID := core.ProposalID(req.Tx)
+		ai.RecordOutcome(app.chainID, proposalID, ai.OutcomeCommitted)
+		ai.IndexCommittedProposal(app.chainID, proposalID, tx.Type, fmt.Sprintf("Loan request from %s", tx.From), tx.Content, app.currentHeight())
 		return types.ResponseDeliverTx{
 			Code: 0,
 			Log:  fmt.Sprintf("Loan request from %s accepted for review", tx.From),
 		}
 
+	case "human_comment":
+		comment, err := app.checkHumanComment(tx, req.Tx, false)
+		if err != nil {
+			return types.ResponseDeliverTx{
+				Code: 1,
+				Log:  fmt.Sprintf("Invalid human comment: %v", err),
+			}
+		}
+		if _, err := communication.AddHumanComment(app.chainID, comment); err != nil {
+			return types.ResponseDeliverTx{
+				Code: 1,
+				Log:  fmt.Sprintf("Failed to store human comment: %v", err),
+			}
+		}
+		log.Printf("Comment from %s (%s) recorded on proposal %s", comment.Author, comment.Role, comment.ProposalID)
+		return types.ResponseDeliverTx{
+			Code: 0,
+			Log:  fmt.Sprintf("Comment from %s recorded on proposal %s", comment.Author, comment.ProposalID),
+		}
+
 	default:
 		return types.ResponseDeliverTx{Code: 0}
 	}
@@ -150,13 +429,23 @@ func (app *Application) DeliverTx(req types.RequestDeliverTx) types.ResponseDeli
 
 // BeginBlock signals the start of a new block
 func (app *Application) BeginBlock(req types.RequestBeginBlock) types.ResponseBeginBlock {
+	app.mu.Lock()
+	app.height = req.Header.Height
+	app.mu.Unlock()
+	registry.SetChainHeight(app.chainID, req.Header.Height)
 	return types.ResponseBeginBlock{}
 }
 
 // EndBlock processes validator updates at the end of a block
 func (app *Application) EndBlock(req types.RequestEndBlock) types.ResponseEndBlock {
+	announceRewards(app.chainID, req.Height, app.settleDeliberations(req.Height))
+	stakeUpdates := app.endBlockStaking(req.Height)
+
 	app.mu.Lock()
 	defer app.mu.Unlock()
+	// Bindings committed in this block and the new set take effect for deliberation right away
+	defer app.publishValidatorAgents()
+	app.pendingValUpdates = append(app.pendingValUpdates, stakeUpdates...)
 
 	if len(app.pendingValUpdates) > 0 {
 		log.Printf("EndBlock at height %d - applying %d validator updates",
@@ -180,6 +469,15 @@ func (app *Application) EndBlock(req types.RequestEndBlock) types.ResponseEndBlo
 			log.Printf("Added/Updated validator: %X", update.PubKey.GetEd25519())
 		}
 
+		// A validator whose power drops to zero leaves the set
+		active := newValidators[:0]
+		for _, validator := range newValidators {
+			if validator.Power > 0 {
+				active = append(active, validator)
+			}
+		}
+		newValidators = active
+
 		app.validators = newValidators
 		updates := app.pendingValUpdates
 		app.pendingValUpdates = nil
@@ -192,9 +490,14 @@ func (app *Application) EndBlock(req types.RequestEndBlock) types.ResponseEndBlo
 	return types.ResponseEndBlock{}
 }
 
-// Commit finalizes the current block
+// Commit finalizes the current block and commits to the app state in the app hash
 func (app *Application) Commit() types.ResponseCommit {
-	return types.ResponseCommit{}
+	app.stateMu.RLock()
+	hash := app.state.hash()
+	app.stateMu.RUnlock()
+
+	app.attestVerdicts()
+	return types.ResponseCommit{Data: hash}
 }
 
 // ListSnapshots returns available snapshots
@@ -217,19 +520,26 @@ func (app *Application) ApplySnapshotChunk(req types.RequestApplySnapshotChunk)
 	return types.ResponseApplySnapshotChunk{}
 }
 
-// PrepareProposal creates a block proposal
+// PrepareProposal creates a block proposal. It does not hold mu, as the proposer's persona may be consulted on
+// the way; the checks it runs take the state lock themselves.
 func (app *Application) PrepareProposal(req types.RequestPrepareProposal) types.ResponsePrepareProposal {
 	log.Printf("PrepareProposal called with %d transactions", len(req.Txs))
 
-	app.mu.Lock()
-	defer app.mu.Unlock()
-
-	var validTxs [][]byte
+	var candidates []proposalCandidate
+	payments := app.newLedger()
 	for _, tx := range req.Txs {
 		var transaction core.Transaction
 		if err := json.Unmarshal(tx, &transaction); err != nil {
 			continue
 		}
+		if err := app.checkStaking(transaction); err != nil {
+			log.Printf("Dropping %s from %s: %v", transaction.Type, transaction.From, err)
+			continue
+		}
+		if err := payments.pay(transaction); err != nil {
+			log.Printf("Dropping unpaid %s from %s: %v", transaction.Type, transaction.From, err)
+			continue
+		}
 
 		switch transaction.Type {
 		case "submit_paper":
@@ -239,17 +549,17 @@ func (app *Application) PrepareProposal(req types.RequestPrepareProposal) types.
 			}
 			if paper.Title != "" && paper.Content != "" {
 				log.Printf("Including paper submission: %s", paper.Title)
-				validTxs = append(validTxs, tx)
+				candidates = append(candidates, newProposalCandidate(tx, transaction))
 			}
 		case "register_validator":
 			log.Printf("Including validator registration tx from %s", transaction.From)
-			validTxs = append(validTxs, tx)
+			candidates = append(candidates, newProposalCandidate(tx, transaction))
 			continue
 		case "discuss_transaction":
 			if transaction.Content != "" {
 				log.Printf("Including discussion tx from %s with content: %s",
 					transaction.From, transaction.Content)
-				validTxs = append(validTxs, tx)
+				candidates = append(candidates, newProposalCandidate(tx, transaction))
 			} else {
 				log.Printf("Rejecting empty discussion tx from %s", transaction.From)
 			}
@@ -257,11 +567,50 @@ func (app *Application) PrepareProposal(req types.RequestPrepareProposal) types.
 			// Accept any loan request that has content
 			if transaction.Content != "" {
 				log.Printf("Including loan request from %s", transaction.From)
-				validTxs = append(validTxs, tx)
+				candidates = append(candidates, newProposalCandidate(tx, transaction))
+			}
+		case "human_comment":
+			if _, err := app.checkHumanComment(transaction, tx, false); err != nil {
+				log.Printf("Dropping human comment from %s: %v", transaction.From, err)
+				continue
+			}
+			log.Printf("Including human comment from %s", transaction.From)
+			candidates = append(candidates, newProposalCandidate(tx, transaction))
+		case core.TxRegisterAgent:
+			if _, err := app.checkAgentRegistration(transaction); err != nil {
+				log.Printf("Dropping agent registration from %s: %v", transaction.From, err)
+				continue
 			}
+			log.Printf("Including agent registration from %s", transaction.From)
+			candidates = append(candidates, newProposalCandidate(tx, transaction))
+		case core.TxTransfer, core.TxStake, core.TxDelegate, core.TxUnstake:
+			// Payment and stake were checked above, which is all these need
+			candidates = append(candidates, newProposalCandidate(tx, transaction))
+		case core.TxAgentVerdict:
+			if _, err := app.checkVerdictAttestation(transaction); err != nil {
+				log.Printf("Dropping verdict attestation from %s: %v", transaction.From, err)
+				continue
+			}
+			candidates = append(candidates, newProposalCandidate(tx, transaction))
 		}
 	}
 
+	// The block is filled by the persona in effect at the height it would be committed at
+	registry.SetChainHeight(app.chainID, req.Height)
+	var proposer *core.Agent
+	if agent, exists := app.resolveAgent(app.selfValidatorAddr); exists {
+		proposer = &agent
+	}
+
+	selected, summary := app.selectProposalTxs(req.Height, candidates, req.MaxTxBytes, proposer)
+	validTxs := make([][]byte, len(selected))
+	txs := make([]core.Transaction, len(selected))
+	for i, candidate := range selected {
+		validTxs[i] = candidate.raw
+		txs[i] = candidate.tx
+	}
+	app.announceBlock(summary, txs, proposer)
+
 	return types.ResponsePrepareProposal{Txs: validTxs}
 }
 
@@ -274,7 +623,21 @@ func (app *Application) ProcessProposal(req types.RequestProcessProposal) types.
 
 	utils.LogDiscussion("Validator", app.selfValidatorAddr, app.chainID, false)
 
-	currentAgent, exists := registry.GetAgentByValidator(app.chainID, app.selfValidatorAddr)
+	// Registrations are checked by every validator, with or without an agent, as they change who deliberates
+	for _, tx := range req.Txs {
+		var transaction core.Transaction
+		if err := json.Unmarshal(tx, &transaction); err != nil || transaction.Type != core.TxRegisterAgent {
+			continue
+		}
+		if _, err := app.checkAgentRegistration(transaction); err != nil {
+			log.Printf("Rejecting proposal with invalid agent registration from %s: %v", transaction.From, err)
+			return types.ResponseProcessProposal{Status: types.ResponseProcessProposal_REJECT}
+		}
+	}
+
+	// The proposal is judged by the persona in effect at the height it would be committed at
+	registry.SetChainHeight(app.chainID, req.Height)
+	currentAgent, exists := app.resolveAgent(app.selfValidatorAddr)
 	if !exists {
 		log.Printf("No agent found for current validator %s", app.selfValidatorAddr)
 		return types.ResponseProcessProposal{Status: types.ResponseProcessProposal_ACCEPT}
@@ -284,11 +647,20 @@ func (app *Application) ProcessProposal(req types.RequestProcessProposal) types.
 
 	shouldReject := false
 
+	// Only transactions their sender can pay for are deliberated; DeliverTx rejects the rest without charging
+	payments := app.newLedger()
 	for _, tx := range req.Txs {
 		var transaction core.Transaction
 		if err := json.Unmarshal(tx, &transaction); err != nil {
 			continue
 		}
+		if err := payments.pay(transaction); err != nil {
+			log.Printf("Skipping deliberation on unpaid %s from %s: %v", transaction.Type, transaction.From, err)
+			continue
+		}
+		if sender, err := transaction.Sender(); err == nil && deliberatedTx(transaction) {
+			communication.RecordProposalSubmitter(app.chainID, core.ProposalID(tx), sender)
+		}
 
 		switch transaction.Type {
 		case "submit_paper":
@@ -297,30 +669,64 @@ func (app *Application) ProcessProposal(req types.RequestProcessProposal) types.
 				continue
 			}
 
-			review := ai.GetMultiRoundReview(currentAgent, paper, app.chainID)
+			proposalID := core.ProposalID(tx)
+			channel, closeChannel := app.discussionChannel(currentAgent, proposalID)
+			review, err := ai.GetMultiRoundReview(currentAgent, paper, app.chainID, proposalID, channel)
+			closeChannel()
+			verdict := ai.Abstention(currentAgent, proposalID, transaction.Type, "could not produce a review")
+			if err != nil {
+				log.Printf("Validator %s could not review paper '%s': %v", currentAgent.Name, paper.Title, err)
+			} else {
+				verdict = *review.Verdict
+			}
+			app.announceVerdict(verdict, review)
 			log.Printf("Review of the paper: %+v, for the paper %+v", review, paper)
 			utils.LogDiscussion(currentAgent.Name, fmt.Sprintf("%+v", review), app.chainID, false)
 			log.Printf("Validator %s review of paper '%s': %s", currentAgent.Name, paper.Title, review.Summary)
 
-			if !review.Approval {
-				log.Printf("Validator %s rejected paper: %s", currentAgent.Name, review.Flaws)
+			if !app.accepts(verdict, transaction.Type) {
+				log.Printf("Validator %s does not accept paper '%s' (%s, confidence %.2f): %s", currentAgent.Name, paper.Title, verdict.Decision, verdict.Confidence, review.Flaws)
 				shouldReject = true
 			}
 		case "discuss_transaction":
-			discussion := ai.GetValidatorDiscussion(currentAgent, transaction)
+			discussion, err := ai.GetValidatorDiscussion(currentAgent, transaction, ai.UsageScope{
+				ChainID:    app.chainID,
+				ProposalID: core.ProposalID(tx),
+				AgentID:    currentAgent.ID,
+			})
+			if err != nil {
+				log.Printf("Validator %s could not discuss transaction: %v", currentAgent.Name, err)
+			}
 			utils.LogDiscussion(currentAgent.Name, discussion.Message, app.chainID, false)
+			communication.RecordMentions(app.chainID, core.ProposalID(tx), currentAgent.Name, discussion.Round, discussion.Message)
+
+			verdict := ai.Abstention(currentAgent, core.ProposalID(tx), transaction.Type, "could not discuss the transaction")
+			if err == nil {
+				verdict = discussion.Verdict(core.ProposalID(tx))
+			}
+			app.announceVerdict(verdict, discussion)
 
-			if !discussion.Support {
-				log.Printf("Validator %s rejected discussion: %s", currentAgent.Name, transaction.Content)
+			if !app.accepts(verdict, transaction.Type) {
+				log.Printf("Validator %s does not accept discussion (%s, confidence %.2f): %s", currentAgent.Name, verdict.Decision, verdict.Confidence, transaction.Content)
 				shouldReject = true
 			}
 		case "loan_request":
-			review := ai.GetMultiRoundLoanReview(currentAgent, transaction.Content, app.chainID)
+			proposalID := core.ProposalID(tx)
+			channel, closeChannel := app.discussionChannel(currentAgent, proposalID)
+			review, err := ai.GetMultiRoundLoanReview(currentAgent, transaction.Content, app.chainID, proposalID, channel)
+			closeChannel()
+			verdict := ai.Abstention(currentAgent, proposalID, transaction.Type, "could not produce a review")
+			if err != nil {
+				log.Printf("Validator %s could not review loan request: %v", currentAgent.Name, err)
+			} else {
+				verdict = *review.Verdict
+			}
+			app.announceVerdict(verdict, review)
 			log.Printf("Review of the loan request: %+v, for the request %+v", review, transaction.Content)
 			utils.LogDiscussion(currentAgent.Name, fmt.Sprintf("%+v", review), app.chainID, false)
 
-			if !review.Approval {
-				log.Printf("Validator %s rejected loan request: %s", currentAgent.Name, review.RiskFactors)
+			if !app.accepts(verdict, transaction.Type) {
+				log.Printf("Validator %s does not accept loan request (%s, confidence %.2f): %s", currentAgent.Name, verdict.Decision, verdict.Confidence, review.RiskFactors)
 				shouldReject = true
 			}
 		}
@@ -332,23 +738,46 @@ func (app *Application) ProcessProposal(req types.RequestProcessProposal) types.
 	return types.ResponseProcessProposal{Status: types.ResponseProcessProposal_ACCEPT}
 }
 
-// RegisterValidator adds a new validator to the validator set
-func (app *Application) RegisterValidator(pubKey crypto.PubKey, power int64) {
-	app.mu.Lock()
-	defer app.mu.Unlock()
+// accepts decides this node's vote on a deliberated proposal. A confident rejection by this node rejects it;
+// otherwise the node's verdict is tallied with those its peers announced, and the proposal is accepted only when a
+// quorum of the deliberating validators approves. A failed review, or a peer verdict that has not arrived, is an
+// abstention: it counts towards the quorum but never as approval, so a proposal no one could review is not accepted.
+func (app *Application) accepts(verdict ai.Verdict, proposalType string) bool {
+	rules := ai.TallyRulesFor(proposalType)
+	if rules.IsHardRejection(verdict) {
+		return false
+	}
+	result := ai.Tally(ai.GetVerdicts(app.chainID, verdict.ProposalID), len(app.deliberators()), rules)
+	if result.Decision != ai.DecisionApprove {
+		log.Printf("Proposal %s lacks a quorum of approving verdicts: %d approvals, %d rejections, %d abstentions of which %d missing",
+			verdict.ProposalID, result.Approvals, result.Rejections, result.Abstentions, result.Missing)
+		return false
+	}
+	return true
+}
 
-	valUpdate := types.Ed25519ValidatorUpdate(pubKey.Bytes(), power)
-	address := pubKey.Address().String()
+// announceVerdict records the node's verdict on a proposal and, signed with the validator key, publishes it with
+// the review behind it to the other validators
+func (app *Application) announceVerdict(verdict ai.Verdict, review interface{}) {
+	app.ownVerdicts[verdict.ProposalID] = verdict
+	ai.RecordVerdict(app.chainID, verdict)
+	if app.privKey == nil {
+		return
+	}
+	communication.PublishVerdict(app.chainID, verdict.ProposalID, verdict.AgentID, app.privKey, verdict, review)
+}
 
-	log.Printf("Registering validator with address: %X, power: %d", pubKey.Address(), power)
+// RegisterValidator records a validator key so stake can be bonded to it. The validator joins the set once its
+// bonded stake is worth voting power.
+func (app *Application) RegisterValidator(pubKey crypto.PubKey) {
+	app.stateMu.Lock()
+	defer app.stateMu.Unlock()
 
-	for _, val := range app.validators {
-		if bytes.Equal(val.PubKey.GetEd25519(), pubKey.Bytes()) {
-			log.Printf("Validator already exists, not adding again")
-			return
-		}
+	address := pubKey.Address().String()
+	if _, exists := app.state.Validators[address]; exists {
+		log.Printf("Validator %s already registered, not adding again", address)
+		return
 	}
-
-	app.pendingValUpdates = append(app.pendingValUpdates, valUpdate)
-	log.Printf("Added validator to pending updates, will be active in next block. Address: %s, Power: %d", address, power)
+	app.state.Validators[address] = StakedValidator{PubKey: pubKey.Bytes()}
+	log.Printf("Registered validator %s, it joins the validator set once stake is bonded to it", address)
 }
@@ -0,0 +1,178 @@
+package abci
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/registry"
+	types "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+const testChainID = "test-chain"
+
+// newTestApp returns an application for validatorAddr whose genesis allocates the given balances, working in a
+// temporary directory so nothing it persists outlives the test
+func newTestApp(t *testing.T, validatorAddr string, balances map[string]uint64) *Application {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	registry.InitRegistry()
+	t.Cleanup(func() { registry.CloseRegistry() })
+
+	app := NewApplication(testChainID, validatorAddr)
+	appState, err := GenesisAppState(balances, nil)
+	if err != nil {
+		t.Fatalf("failed to encode genesis: %v", err)
+	}
+	if err := app.initGenesis(appState, nil); err != nil {
+		t.Fatalf("failed to load genesis: %v", err)
+	}
+	return app
+}
+
+// newTestAccount returns a signing key and the account address it controls
+func newTestAccount(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := core.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key, core.AccountAddressOf(key)
+}
+
+// signTx signs a transaction on the test chain as the account of key
+func signTx(t *testing.T, key *ecdsa.PrivateKey, tx core.Transaction) core.Transaction {
+	t.Helper()
+	tx.From = core.AccountAddressOf(key)
+	tx.ChainID = testChainID
+	if err := tx.SignTransaction(key); err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+// failingProvider stands in for a model that cannot be reached
+type failingProvider struct{}
+
+func (failingProvider) Name() string {
+	return "failing"
+}
+
+func (failingProvider) Complete(ctx context.Context, req ai.CompletionRequest) (ai.CompletionResponse, error) {
+	return ai.CompletionResponse{}, errors.New("model unavailable")
+}
+
+func TestProcessProposalNeedsAnApprovingReview(t *testing.T) {
+	approving := `{"summary": "Sound method and clear results.", "flaws": [], "suggestions": [], "is_reproducible": true,
+		"decision": "approve", "confidence": 0.9, "citations": []}`
+
+	tests := []struct {
+		name     string
+		provider ai.Provider
+		want     types.ResponseProcessProposal_ProposalStatus
+	}{
+		{"review fails", failingProvider{}, types.ResponseProcessProposal_REJECT},
+		{"reviewer abstains", ai.NewScriptedProvider(nil), types.ResponseProcessProposal_REJECT},
+		{"reviewer approves", ai.NewScriptedProvider([]ai.ScriptedResponse{{Response: approving}}), types.ResponseProcessProposal_ACCEPT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := ai.CurrentProvider()
+			ai.SetProvider(tt.provider)
+			t.Cleanup(func() { ai.SetProvider(previous) })
+
+			validatorAddr := ed25519.GenPrivKey().PubKey().Address().String()
+			key, submitter := newTestAccount(t)
+			app := newTestApp(t, validatorAddr, map[string]uint64{submitter: 1000})
+			app.state.AgentBindings[validatorAddr] = AgentBinding{
+				AgentID:          "reviewer",
+				Persona:          core.Persona{ID: "reviewer", Name: "Reviewer", Role: core.RoleValidator, Traits: []string{"careful"}},
+				ValidatorAddress: validatorAddr,
+			}
+
+			raw := paperSubmission(t, app, key)
+			resp := app.ProcessProposal(types.RequestProcessProposal{Txs: [][]byte{raw}, Height: 1})
+			if resp.Status != tt.want {
+				t.Errorf("ProcessProposal = %s, want %s", resp.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessProposalCountsMissingVerdictsAsAbstentions(t *testing.T) {
+	approving := `{"summary": "Sound method and clear results.", "flaws": [], "suggestions": [], "is_reproducible": true,
+		"decision": "approve", "confidence": 0.9, "citations": []}`
+
+	tests := []struct {
+		name  string
+		peers []ai.Decision // verdicts of the two other deliberating validators that arrived in time
+		want  types.ResponseProcessProposal_ProposalStatus
+	}{
+		{"only this node's verdict arrived", nil, types.ResponseProcessProposal_REJECT},
+		{"a peer abstained", []ai.Decision{ai.DecisionAbstain}, types.ResponseProcessProposal_REJECT},
+		{"a peer approved", []ai.Decision{ai.DecisionApprove}, types.ResponseProcessProposal_ACCEPT},
+		{"a peer rejected", []ai.Decision{ai.DecisionReject}, types.ResponseProcessProposal_REJECT},
+		{"both peers approved", []ai.Decision{ai.DecisionApprove, ai.DecisionApprove}, types.ResponseProcessProposal_ACCEPT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := ai.CurrentProvider()
+			ai.SetProvider(ai.NewScriptedProvider([]ai.ScriptedResponse{{Response: approving}}))
+			t.Cleanup(func() { ai.SetProvider(previous) })
+
+			self := ed25519.GenPrivKey().PubKey()
+			key, submitter := newTestAccount(t)
+			app := newTestApp(t, self.Address().String(), map[string]uint64{submitter: 1000})
+			validators := []crypto.PubKey{self, ed25519.GenPrivKey().PubKey(), ed25519.GenPrivKey().PubKey()}
+			for i, pubKey := range validators {
+				address := pubKey.Address().String()
+				agentID := fmt.Sprintf("reviewer-%d", i)
+				app.validators = append(app.validators, types.Ed25519ValidatorUpdate(pubKey.Bytes(), 10))
+				app.state.AgentBindings[address] = AgentBinding{
+					AgentID:          agentID,
+					Persona:          core.Persona{ID: agentID, Name: agentID, Role: core.RoleValidator, Traits: []string{"careful"}},
+					ValidatorAddress: address,
+				}
+			}
+
+			raw := paperSubmission(t, app, key)
+			for i, decision := range tt.peers {
+				ai.RecordVerdict(testChainID, ai.Verdict{
+					AgentID:      fmt.Sprintf("reviewer-%d", i+1),
+					ProposalID:   core.ProposalID(raw),
+					ProposalType: "submit_paper",
+					Decision:     decision,
+					Confidence:   0.95,
+				})
+			}
+
+			resp := app.ProcessProposal(types.RequestProcessProposal{Txs: [][]byte{raw}, Height: 1})
+			if resp.Status != tt.want {
+				t.Errorf("ProcessProposal = %s, want %s", resp.Status, tt.want)
+			}
+		})
+	}
+}
+
+// paperSubmission returns a paid paper submission signed by key
+func paperSubmission(t *testing.T, app *Application, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	content, _ := json.Marshal(ai.ResearchPaper{
+		Title:    "Surface Code Thresholds under Biased Noise",
+		Abstract: "We estimate the threshold of the XZZX surface code when dephasing dominates.",
+		Content:  "Simulations over 10^6 shots show a threshold near 2% for a bias of 100.",
+	})
+	tx := core.Transaction{Type: "submit_paper", Content: string(content), Nonce: 1}
+	tx.Fee = app.minimumFee(tx)
+	raw, _ := json.Marshal(signTx(t, key, tx))
+	return raw
+}
@@ -0,0 +1,58 @@
+package abci
+
+import (
+	"fmt"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+)
+
+// recordSubmitter records on chain which account signed a committed proposal, so its author can be recognised in
+// comments
+func (app *Application) recordSubmitter(proposalID string, tx core.Transaction) {
+	sender, err := tx.Sender()
+	if err != nil {
+		return
+	}
+	app.stateMu.Lock()
+	defer app.stateMu.Unlock()
+	app.state.Submitters[proposalID] = sender
+}
+
+// checkHumanComment verifies a human_comment transaction and the role it claims
+func (app *Application) checkHumanComment(tx core.Transaction, raw []byte, pending bool) (communication.HumanComment, error) {
+	comment, err := communication.ParseHumanComment(tx, raw)
+	if err != nil {
+		return communication.HumanComment{}, err
+	}
+	if err := app.authorizeComment(comment, pending); err != nil {
+		return communication.HumanComment{}, err
+	}
+	return comment, nil
+}
+
+// authorizeComment checks the role a comment claims. Delivered comments are checked against committed proposals
+// only; pending also accepts the submitters of proposals this node has seen but not yet committed, which CheckTx
+// and the off-chain delivery may rely on.
+func (app *Application) authorizeComment(comment communication.HumanComment, pending bool) error {
+	app.stateMu.RLock()
+	submitter := app.state.Submitters[comment.ProposalID]
+	moderators := app.state.Moderators
+	app.stateMu.RUnlock()
+	if submitter == "" && pending {
+		submitter, _ = communication.ProposalSubmitter(app.chainID, comment.ProposalID)
+	}
+	return communication.AuthorizeCommentRole(comment, submitter, moderators)
+}
+
+// authorizePublishedComment admits a comment sent over NATS during a debate: its role must check out and its
+// sender must be able to pay for it, as it would on chain
+func (app *Application) authorizePublishedComment(tx core.Transaction, comment communication.HumanComment) error {
+	if tx.ChainID != app.chainID {
+		return fmt.Errorf("comment is for chain %q, not %q", tx.ChainID, app.chainID)
+	}
+	if err := app.authorizeComment(comment, true); err != nil {
+		return err
+	}
+	return app.newLedger().pay(tx)
+}
@@ -0,0 +1,225 @@
+package abci
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	types "github.com/cometbft/cometbft/abci/types"
+)
+
+// Account is a native token balance, with the nonce of the last paid transaction of its owner
+type Account struct {
+	Balance uint64 `json:"balance"`
+	Nonce   uint64 `json:"nonce"`
+}
+
+// FeeRule prices a transaction type. The minimum fee is (Base + PerKB for every started KB of content), charged
+// once for every deliberation round the proposal may take.
+type FeeRule struct {
+	Base   uint64 `json:"base"`
+	PerKB  uint64 `json:"per_kb"`
+	Rounds int    `json:"rounds"`
+}
+
+// Minimum returns the lowest fee accepted for a transaction with the given content length
+func (r FeeRule) Minimum(contentLength int) uint64 {
+	kb := uint64((contentLength + 1023) / 1024)
+	rounds := uint64(1)
+	if r.Rounds > 1 {
+		rounds = uint64(r.Rounds)
+	}
+	return (r.Base + r.PerKB*kb) * rounds
+}
+
+// DefaultFeeSchedule prices the proposal types agents deliberate on by their default maximum debate rounds.
+// Types without a rule, such as registrations and discussions, are free.
+func DefaultFeeSchedule() map[string]FeeRule {
+	return map[string]FeeRule{
+		"submit_paper":  {Base: 10, PerKB: 2, Rounds: 3},
+		"loan_request":  {Base: 10, PerKB: 2, Rounds: 4},
+		"human_comment": {Base: 1, PerKB: 1, Rounds: 1},
+		core.TxTransfer: {Base: 1, Rounds: 1},
+		core.TxStake:    {Base: 1, Rounds: 1},
+		core.TxDelegate: {Base: 1, Rounds: 1},
+		core.TxUnstake:  {Base: 1, Rounds: 1},
+	}
+}
+
+// GenesisState is the app_state of the genesis document: the initial balances, the fee schedule, how fees
+// are shared as rewards, the stake bonded to the genesis validators and the comment moderators
+type GenesisState struct {
+	Accounts   map[string]uint64       `json:"accounts,omitempty"`
+	Fees       map[string]FeeRule      `json:"fees,omitempty"`
+	Rewards    *RewardParams           `json:"rewards,omitempty"`
+	Staking    *StakingParams          `json:"staking,omitempty"`
+	Stakes     map[string]GenesisStake `json:"stakes,omitempty"`
+	Moderators []string                `json:"moderators,omitempty"`
+}
+
+// GenesisAppState encodes the app_state of a new chain allocating the given balances under the default fees,
+// rewards and staking parameters, with the given accounts moderating comments
+func GenesisAppState(accounts map[string]uint64, moderators []string) (json.RawMessage, error) {
+	rewards := DefaultRewardParams()
+	staking := DefaultStakingParams()
+	return json.Marshal(GenesisState{
+		Accounts:   accounts,
+		Fees:       DefaultFeeSchedule(),
+		Rewards:    &rewards,
+		Staking:    &staking,
+		Moderators: moderators,
+	})
+}
+
+// initGenesis loads the app_state of the genesis document into the app state and bonds the stake of the genesis
+// validators
+func (app *Application) initGenesis(appState []byte, validators []types.ValidatorUpdate) error {
+	genesis := GenesisState{}
+	if len(appState) > 0 {
+		if err := json.Unmarshal(appState, &genesis); err != nil {
+			return fmt.Errorf("invalid genesis app state: %v", err)
+		}
+	}
+
+	app.stateMu.Lock()
+	defer app.stateMu.Unlock()
+	for address, balance := range genesis.Accounts {
+		app.state.Accounts[address] = Account{Balance: balance}
+	}
+	if genesis.Fees != nil {
+		app.state.Fees = genesis.Fees
+	}
+	if genesis.Rewards != nil {
+		app.state.RewardParams = *genesis.Rewards
+	}
+	if genesis.Staking != nil {
+		app.state.StakingParams = *genesis.Staking
+	}
+	app.state.Moderators = genesis.Moderators
+	app.bondGenesisStakes(genesis.Stakes, validators)
+	return nil
+}
+
+// paidTx reports whether a transaction has to be paid for by its sender
+func (app *Application) paidTx(tx core.Transaction) bool {
+	if tx.Type == core.TxTransfer || stakingTx(tx) || tx.Fee > 0 {
+		return true
+	}
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+	rule, priced := app.state.Fees[tx.Type]
+	return priced && rule.Minimum(len(tx.Content)) > 0
+}
+
+// minimumFee returns the lowest fee the fee schedule accepts for a transaction
+func (app *Application) minimumFee(tx core.Transaction) uint64 {
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+	rule, priced := app.state.Fees[tx.Type]
+	if !priced {
+		return 0
+	}
+	return rule.Minimum(len(tx.Content))
+}
+
+// ledger applies payments on top of the committed accounts. Nothing changes in the app state until commit, so
+// CheckTx and proposal checks can run a ledger and throw it away.
+type ledger struct {
+	app     *Application
+	changed map[string]Account
+	fees    uint64
+}
+
+func (app *Application) newLedger() *ledger {
+	return &ledger{app: app, changed: make(map[string]Account)}
+}
+
+// account returns an account as the payments so far left it
+func (l *ledger) account(address string) Account {
+	if account, exists := l.changed[address]; exists {
+		return account
+	}
+	l.app.stateMu.RLock()
+	defer l.app.stateMu.RUnlock()
+	return l.app.state.Accounts[address]
+}
+
+// tokenAmount returns the amount a transfer or staking transaction moves, which must be a whole number of tokens
+func tokenAmount(tx core.Transaction) (uint64, error) {
+	if tx.Amount <= 0 || tx.Amount != math.Trunc(tx.Amount) || tx.Amount > math.MaxInt64 {
+		return 0, fmt.Errorf("%s amount must be a positive whole number of tokens", tx.Type)
+	}
+	return uint64(tx.Amount), nil
+}
+
+// pay charges a transaction's fee to its signed sender and, for transfers, moves the amount. Stake and
+// delegations take the amount out of the account, to be bonded by applyStaking. Transactions that need no
+// payment pass untouched.
+func (l *ledger) pay(tx core.Transaction) error {
+	if !l.app.paidTx(tx) {
+		return nil
+	}
+
+	if minimum := l.app.minimumFee(tx); tx.Fee < minimum {
+		return fmt.Errorf("fee %d is below the minimum of %d for a %s of %d bytes", tx.Fee, minimum, tx.Type, len(tx.Content))
+	}
+	sender, err := tx.Sender()
+	if err != nil {
+		return err
+	}
+
+	var amount uint64
+	if tx.Type == core.TxTransfer || bondingTx(tx) {
+		if amount, err = tokenAmount(tx); err != nil {
+			return err
+		}
+		if tx.Type == core.TxTransfer && (tx.To == "" || tx.To == sender) {
+			return fmt.Errorf("transfer needs a recipient other than the sender")
+		}
+	}
+
+	account := l.account(sender)
+	if tx.Nonce <= account.Nonce {
+		return fmt.Errorf("nonce %d is not above the account's %d", tx.Nonce, account.Nonce)
+	}
+	if account.Balance < tx.Fee || account.Balance-tx.Fee < amount {
+		return fmt.Errorf("account %s holds %d, needs %d", sender, account.Balance, tx.Fee+amount)
+	}
+
+	account.Balance -= tx.Fee + amount
+	account.Nonce = tx.Nonce
+	l.changed[sender] = account
+	if tx.Type == core.TxTransfer {
+		recipient := l.account(tx.To)
+		recipient.Balance += amount
+		l.changed[tx.To] = recipient
+	}
+	l.fees += tx.Fee
+	return nil
+}
+
+// commit writes the payments into the app state. Fees go to the fee pool, from which openDeliberation escrows
+// those of deliberated proposals.
+func (l *ledger) commit() {
+	l.app.stateMu.Lock()
+	defer l.app.stateMu.Unlock()
+	for address, account := range l.changed {
+		l.app.state.Accounts[address] = account
+	}
+	l.app.state.FeePool += l.fees
+}
+
+// queryAccount answers the account and fees queries
+func (app *Application) queryAccount(path string, data []byte) ([]byte, error) {
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+
+	if path == "fees" {
+		return json.Marshal(map[string]interface{}{
+			"schedule": app.state.Fees,
+			"pool":     app.state.FeePool,
+		})
+	}
+	return json.Marshal(app.state.Accounts[string(data)])
+}
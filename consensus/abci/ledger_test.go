@@ -0,0 +1,150 @@
+package abci
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestLedgerPay(t *testing.T) {
+	paper := strings.Repeat("x", 2000) // two started KB: (10 + 2*2) * 3 rounds = 42
+
+	tests := []struct {
+		name        string
+		account     Account
+		tx          core.Transaction
+		wantErr     string
+		wantBalance uint64
+	}{
+		{
+			name:        "transfer",
+			account:     Account{Balance: 100},
+			tx:          core.Transaction{Type: core.TxTransfer, Amount: 10, Fee: 1, Nonce: 1},
+			wantBalance: 89,
+		},
+		{
+			name:    "replayed nonce",
+			account: Account{Balance: 100, Nonce: 3},
+			tx:      core.Transaction{Type: core.TxTransfer, Amount: 10, Fee: 1, Nonce: 3},
+			wantErr: "nonce 3 is not above",
+		},
+		{
+			name:    "older nonce",
+			account: Account{Balance: 100, Nonce: 3},
+			tx:      core.Transaction{Type: core.TxTransfer, Amount: 10, Fee: 1, Nonce: 2},
+			wantErr: "nonce 2 is not above",
+		},
+		{
+			name:        "nonce may skip ahead",
+			account:     Account{Balance: 100, Nonce: 3},
+			tx:          core.Transaction{Type: core.TxTransfer, Amount: 10, Fee: 1, Nonce: 7},
+			wantBalance: 89,
+		},
+		{
+			name:        "balance covers fee and amount exactly",
+			account:     Account{Balance: 11},
+			tx:          core.Transaction{Type: core.TxTransfer, Amount: 10, Fee: 1, Nonce: 1},
+			wantBalance: 0,
+		},
+		{
+			name:    "balance short of fee and amount",
+			account: Account{Balance: 10},
+			tx:      core.Transaction{Type: core.TxTransfer, Amount: 10, Fee: 1, Nonce: 1},
+			wantErr: "holds 10, needs 11",
+		},
+		{
+			name:    "balance short of the fee",
+			account: Account{Balance: 20},
+			tx:      core.Transaction{Type: "submit_paper", Content: paper, Fee: 42, Nonce: 1},
+			wantErr: "holds 20, needs 42",
+		},
+		{
+			name:    "transfer without a fee",
+			account: Account{Balance: 100},
+			tx:      core.Transaction{Type: core.TxTransfer, Amount: 10, Nonce: 1},
+			wantErr: "fee 0 is below the minimum of 1",
+		},
+		{
+			name:    "paper fee below the minimum for its size",
+			account: Account{Balance: 100},
+			tx:      core.Transaction{Type: "submit_paper", Content: paper, Fee: 41, Nonce: 1},
+			wantErr: "fee 41 is below the minimum of 42",
+		},
+		{
+			name:        "paper fee at the minimum for its size",
+			account:     Account{Balance: 100},
+			tx:          core.Transaction{Type: "submit_paper", Content: paper, Fee: 42, Nonce: 1},
+			wantBalance: 58,
+		},
+		{
+			name:    "fractional transfer",
+			account: Account{Balance: 100},
+			tx:      core.Transaction{Type: core.TxTransfer, Amount: 2.5, Fee: 1, Nonce: 1},
+			wantErr: "whole number of tokens",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, sender := newTestAccount(t)
+			_, recipient := newTestAccount(t)
+			app := newTestApp(t, ed25519.GenPrivKey().PubKey().Address().String(), nil)
+			app.state.Accounts[sender] = tt.account
+
+			tx := tt.tx
+			if tx.Type == core.TxTransfer {
+				tx.To = recipient
+			}
+			ledger := app.newLedger()
+			err := ledger.pay(signTx(t, key, tx))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("pay() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				if len(ledger.changed) > 0 || ledger.fees > 0 {
+					t.Errorf("a rejected payment changed the ledger: %+v, fees %d", ledger.changed, ledger.fees)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pay() error = %v", err)
+			}
+
+			ledger.commit()
+			if got := app.state.Accounts[sender]; got.Balance != tt.wantBalance || got.Nonce != tx.Nonce {
+				t.Errorf("sender = %+v, want balance %d and nonce %d", got, tt.wantBalance, tx.Nonce)
+			}
+			if want := uint64(tx.Amount); tx.Type == core.TxTransfer && app.state.Accounts[recipient].Balance != want {
+				t.Errorf("recipient holds %d, want %d", app.state.Accounts[recipient].Balance, want)
+			}
+			if app.state.FeePool != tx.Fee {
+				t.Errorf("fee pool holds %d, want %d", app.state.FeePool, tx.Fee)
+			}
+		})
+	}
+}
+
+func TestLedgerRejectsReplayedTransaction(t *testing.T) {
+	key, sender := newTestAccount(t)
+	_, recipient := newTestAccount(t)
+	app := newTestApp(t, ed25519.GenPrivKey().PubKey().Address().String(), map[string]uint64{sender: 100})
+	tx := signTx(t, key, core.Transaction{Type: core.TxTransfer, To: recipient, Amount: 10, Fee: 1, Nonce: 1})
+
+	first := app.newLedger()
+	if err := first.pay(tx); err != nil {
+		t.Fatalf("first payment failed: %v", err)
+	}
+	if err := first.pay(tx); err == nil {
+		t.Error("the same transaction was paid twice within a block")
+	}
+	first.commit()
+
+	if err := app.newLedger().pay(tx); err == nil {
+		t.Error("the same transaction was paid again in a later block")
+	}
+	if got := app.state.Accounts[recipient].Balance; got != 10 {
+		t.Errorf("recipient holds %d, want 10", got)
+	}
+}
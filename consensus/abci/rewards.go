@@ -0,0 +1,288 @@
+package abci
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+)
+
+// RewardParams decide how the fee of a proposal is shared among the agents that deliberated it
+type RewardParams struct {
+	// SettleAfter is how many blocks verdicts on a committed proposal are collected before its fee is shared
+	SettleAfter int64 `json:"settle_after"`
+	// ParticipationWeight is the weight of every agent that attested a decisive verdict
+	ParticipationWeight uint64 `json:"participation_weight"`
+	// AgreementWeight is added for agents whose verdict matches the outcome; 0 rewards participation alone
+	AgreementWeight uint64 `json:"agreement_weight"`
+}
+
+// DefaultRewardParams settles proposals three blocks after their commit and doubles the share of agents that
+// agreed with the outcome
+func DefaultRewardParams() RewardParams {
+	return RewardParams{SettleAfter: 3, ParticipationWeight: 1, AgreementWeight: 1}
+}
+
+// Deliberation is a committed proposal whose fee is held until the verdicts on it are settled
+type Deliberation struct {
+	ProposalType string                     `json:"proposal_type"`
+	Fee          uint64                     `json:"fee"`
+	Height       int64                      `json:"height"`
+	Verdicts     map[string]AttestedVerdict `json:"verdicts"`
+}
+
+// AttestedVerdict is a verdict a validator put on chain for its agent
+type AttestedVerdict struct {
+	AgentID    string  `json:"agent_id"`
+	Decision   string  `json:"decision"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Reward is an agent's share of the fee of a settled proposal
+type Reward struct {
+	ProposalID   string `json:"proposal_id"`
+	ProposalType string `json:"proposal_type"`
+	Height       int64  `json:"height"`
+	Validator    string `json:"validator"`
+	Account      string `json:"account"`
+	Amount       uint64 `json:"amount"`
+	Decision     string `json:"decision"`
+	Outcome      string `json:"outcome,omitempty"`
+	Agreed       bool   `json:"agreed"`
+}
+
+// deliberatedTx reports whether validators deliberate on a transaction type in ProcessProposal
+func deliberatedTx(tx core.Transaction) bool {
+	return tx.Type == "submit_paper" || tx.Type == "loan_request" || tx.Type == "discuss_transaction"
+}
+
+// openDeliberation moves the fee of a committed proposal from the fee pool into escrow until it is settled
+func (app *Application) openDeliberation(proposalID string, tx core.Transaction, height int64) {
+	app.stateMu.Lock()
+	app.state.FeePool -= tx.Fee
+	app.state.Deliberations[proposalID] = Deliberation{
+		ProposalType: tx.Type,
+		Fee:          tx.Fee,
+		Height:       height,
+		Verdicts:     make(map[string]AttestedVerdict),
+	}
+	app.stateMu.Unlock()
+
+	app.mu.Lock()
+	app.committedProposals = append(app.committedProposals, proposalID)
+	app.mu.Unlock()
+}
+
+// checkVerdictAttestation validates an agent_verdict transaction: the validator must have the attested agent
+// bound on chain and may attest each open proposal once
+func (app *Application) checkVerdictAttestation(tx core.Transaction) (core.VerdictAttestation, error) {
+	if tx.ChainID != app.chainID {
+		return core.VerdictAttestation{}, fmt.Errorf("attestation is for chain %q, not %q", tx.ChainID, app.chainID)
+	}
+	attestation, err := core.ParseVerdictAttestation(tx)
+	if err != nil {
+		return core.VerdictAttestation{}, err
+	}
+	if !ai.Decision(attestation.Decision).Valid() {
+		return core.VerdictAttestation{}, fmt.Errorf("invalid decision %q", attestation.Decision)
+	}
+
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+
+	address := attestation.ValidatorAddress()
+	binding, bound := app.state.AgentBindings[address]
+	if !bound || binding.AgentID != attestation.AgentID {
+		return core.VerdictAttestation{}, fmt.Errorf("agent %s is not bound to validator %s", attestation.AgentID, address)
+	}
+	deliberation, open := app.state.Deliberations[attestation.ProposalID]
+	if !open {
+		return core.VerdictAttestation{}, fmt.Errorf("proposal %s is not awaiting verdicts", attestation.ProposalID)
+	}
+	if _, attested := deliberation.Verdicts[address]; attested {
+		return core.VerdictAttestation{}, fmt.Errorf("validator %s already attested proposal %s", address, attestation.ProposalID)
+	}
+	return attestation, nil
+}
+
+// applyVerdictAttestation records an attested verdict on its proposal
+func (app *Application) applyVerdictAttestation(attestation core.VerdictAttestation) {
+	app.stateMu.Lock()
+	defer app.stateMu.Unlock()
+	app.state.Deliberations[attestation.ProposalID].Verdicts[attestation.ValidatorAddress()] = AttestedVerdict{
+		AgentID:    attestation.AgentID,
+		Decision:   attestation.Decision,
+		Confidence: attestation.Confidence,
+	}
+}
+
+// outcomeOf returns the decision most attested verdicts reached, or nothing on a tie
+func outcomeOf(verdicts map[string]AttestedVerdict) string {
+	counts := make(map[string]int)
+	for _, verdict := range verdicts {
+		if ai.Decision(verdict.Decision) != ai.DecisionAbstain {
+			counts[verdict.Decision]++
+		}
+	}
+	approve, reject := counts[string(ai.DecisionApprove)], counts[string(ai.DecisionReject)]
+	switch {
+	case approve > reject:
+		return string(ai.DecisionApprove)
+	case reject > approve:
+		return string(ai.DecisionReject)
+	}
+	return ""
+}
+
+// settleDeliberations shares the fees of the proposals whose verdict window closed at this height. Every agent
+// with a decisive verdict gets the participation weight, plus the agreement weight when it matched the outcome;
+// abstentions earn nothing. What cannot be shared evenly, was not earned, or is due to a validator without a
+// reward address returns to the fee pool.
+func (app *Application) settleDeliberations(height int64) []Reward {
+	app.stateMu.Lock()
+	defer app.stateMu.Unlock()
+
+	params := app.state.RewardParams
+	var due []string
+	for proposalID, deliberation := range app.state.Deliberations {
+		if deliberation.Height+params.SettleAfter <= height {
+			due = append(due, proposalID)
+		}
+	}
+	sort.Strings(due)
+
+	var rewards []Reward
+	for _, proposalID := range due {
+		deliberation := app.state.Deliberations[proposalID]
+		delete(app.state.Deliberations, proposalID)
+		outcome := outcomeOf(deliberation.Verdicts)
+
+		validators := make([]string, 0, len(deliberation.Verdicts))
+		weights := make(map[string]uint64)
+		var total uint64
+		for validator, verdict := range deliberation.Verdicts {
+			if ai.Decision(verdict.Decision) == ai.DecisionAbstain {
+				continue
+			}
+			weight := params.ParticipationWeight
+			if outcome != "" && verdict.Decision == outcome {
+				weight += params.AgreementWeight
+			}
+			if weight == 0 {
+				continue
+			}
+			validators = append(validators, validator)
+			weights[validator] = weight
+			total += weight
+		}
+		sort.Strings(validators)
+
+		remaining := deliberation.Fee
+		for _, validator := range validators {
+			verdict := deliberation.Verdicts[validator]
+			amount := deliberation.Fee * weights[validator] / total
+			if amount == 0 {
+				continue
+			}
+
+			binding, bound := app.state.AgentBindings[validator]
+			if !bound || binding.RewardAddress == "" {
+				log.Printf("Validator %s has no reward address, returning its share of %s to the fee pool", validator, proposalID)
+				continue
+			}
+			account := binding.RewardAddress
+			credited := app.state.Accounts[account]
+			credited.Balance += amount
+			app.state.Accounts[account] = credited
+			remaining -= amount
+
+			reward := Reward{
+				ProposalID:   proposalID,
+				ProposalType: deliberation.ProposalType,
+				Height:       height,
+				Validator:    validator,
+				Account:      account,
+				Amount:       amount,
+				Decision:     verdict.Decision,
+				Outcome:      outcome,
+				Agreed:       outcome != "" && verdict.Decision == outcome,
+			}
+			app.state.Rewards[verdict.AgentID] = append(app.state.Rewards[verdict.AgentID], reward)
+			rewards = append(rewards, reward)
+		}
+		app.state.FeePool += remaining
+	}
+	return rewards
+}
+
+// queryRewards answers the rewards query with the reward history of an agent
+func (app *Application) queryRewards(agentID string) []Reward {
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+	return append([]Reward{}, app.state.Rewards[agentID]...)
+}
+
+// attestVerdicts puts this node's verdicts on the proposals committed in the last block on chain. The
+// transactions are submitted in the background, once the block is committed and the mempool accepts them again.
+func (app *Application) attestVerdicts() {
+	app.mu.Lock()
+	committed := app.committedProposals
+	verdicts := app.ownVerdicts
+	app.committedProposals = nil
+	app.ownVerdicts = make(map[string]ai.Verdict)
+	privKey, broadcast := app.privKey, app.broadcastTx
+	app.mu.Unlock()
+
+	if privKey == nil || broadcast == nil {
+		return
+	}
+	binding, bound := app.agentBinding(app.selfValidatorAddr)
+	if !bound {
+		return
+	}
+
+	var txs [][]byte
+	for _, proposalID := range committed {
+		verdict, exists := verdicts[proposalID]
+		if !exists || verdict.AgentID != binding.AgentID {
+			continue
+		}
+		tx, err := core.NewVerdictAttestation(app.chainID, proposalID, verdict.AgentID, string(verdict.Decision), verdict.Confidence, privKey)
+		if err != nil {
+			log.Printf("Failed to attest verdict on %s: %v", proposalID, err)
+			continue
+		}
+		raw, err := tx.Marshal()
+		if err != nil {
+			log.Printf("Failed to encode verdict attestation on %s: %v", proposalID, err)
+			continue
+		}
+		txs = append(txs, raw)
+	}
+	if len(txs) == 0 {
+		return
+	}
+
+	go func() {
+		for _, tx := range txs {
+			if err := broadcast(tx); err != nil {
+				log.Printf("Failed to submit verdict attestation: %v", err)
+			}
+		}
+	}()
+}
+
+// announceRewards publishes the rewards settled in a block
+func announceRewards(chainID string, height int64, rewards []Reward) {
+	if len(rewards) == 0 {
+		return
+	}
+	communication.BroadcastEvent(communication.EventRewardsDistributed, map[string]interface{}{
+		"chain_id": chainID,
+		"height":   height,
+		"rewards":  rewards,
+	})
+}
@@ -0,0 +1,119 @@
+package abci
+
+import (
+	"testing"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestSettleDeliberationsSharesTheWholeFee(t *testing.T) {
+	tests := []struct {
+		name     string
+		fee      uint64
+		verdicts map[string]string // decision by validator
+		unbound  []string          // validators whose agent has no reward address
+		want     map[string]uint64 // reward by validator
+	}{
+		{
+			name:     "splits evenly",
+			fee:      10,
+			verdicts: map[string]string{"val-a": "approve", "val-b": "approve", "val-c": "reject"},
+			want:     map[string]uint64{"val-a": 4, "val-b": 4, "val-c": 2},
+		},
+		{
+			name:     "rounds shares down",
+			fee:      7,
+			verdicts: map[string]string{"val-a": "approve", "val-b": "approve", "val-c": "reject"},
+			want:     map[string]uint64{"val-a": 2, "val-b": 2, "val-c": 1},
+		},
+		{
+			name:     "fee too small to share",
+			fee:      1,
+			verdicts: map[string]string{"val-a": "approve", "val-b": "reject", "val-c": "abstain"},
+			want:     map[string]uint64{},
+		},
+		{
+			name:     "tie rewards participation alone",
+			fee:      9,
+			verdicts: map[string]string{"val-a": "approve", "val-b": "reject"},
+			want:     map[string]uint64{"val-a": 4, "val-b": 4},
+		},
+		{
+			name:     "abstentions earn nothing",
+			fee:      12,
+			verdicts: map[string]string{"val-a": "approve", "val-b": "abstain", "val-c": "abstain"},
+			want:     map[string]uint64{"val-a": 12},
+		},
+		{
+			name:     "nobody decided",
+			fee:      12,
+			verdicts: map[string]string{"val-a": "abstain"},
+			want:     map[string]uint64{},
+		},
+		{
+			name:     "share of a validator without reward address",
+			fee:      10,
+			verdicts: map[string]string{"val-a": "approve", "val-b": "approve", "val-c": "reject"},
+			unbound:  []string{"val-b"},
+			want:     map[string]uint64{"val-a": 4, "val-c": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, ed25519.GenPrivKey().PubKey().Address().String(), nil)
+			app.state.FeePool = tt.fee
+			app.openDeliberation("proposal", core.Transaction{Type: "submit_paper", Fee: tt.fee}, 1)
+			if app.state.FeePool != 0 {
+				t.Fatalf("fee pool holds %d after escrow, want 0", app.state.FeePool)
+			}
+
+			for validator, decision := range tt.verdicts {
+				agentID := "agent-" + validator
+				app.state.AgentBindings[validator] = AgentBinding{AgentID: agentID, ValidatorAddress: validator, RewardAddress: "acct-" + validator}
+				app.state.Deliberations["proposal"].Verdicts[validator] = AttestedVerdict{AgentID: agentID, Decision: decision, Confidence: 0.8}
+			}
+			for _, validator := range tt.unbound {
+				binding := app.state.AgentBindings[validator]
+				binding.RewardAddress = ""
+				app.state.AgentBindings[validator] = binding
+			}
+
+			settleAt := 1 + app.state.RewardParams.SettleAfter
+			if rewards := app.settleDeliberations(settleAt - 1); len(rewards) > 0 {
+				t.Fatalf("settled before the verdict window closed: %+v", rewards)
+			}
+			rewards := app.settleDeliberations(settleAt)
+
+			var paid uint64
+			got := make(map[string]uint64)
+			for _, reward := range rewards {
+				got[reward.Validator] = reward.Amount
+				paid += reward.Amount
+				if balance := app.state.Accounts[reward.Account].Balance; balance != reward.Amount {
+					t.Errorf("%s holds %d, rewarded %d", reward.Account, balance, reward.Amount)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("rewards = %v, want %v", got, tt.want)
+			}
+			for validator, amount := range tt.want {
+				if got[validator] != amount {
+					t.Errorf("reward of %s = %d, want %d", validator, got[validator], amount)
+				}
+			}
+			for _, validator := range tt.unbound {
+				if balance := app.state.Accounts[validator].Balance; balance != 0 {
+					t.Errorf("validator address %s was credited %d", validator, balance)
+				}
+			}
+			if paid+app.state.FeePool != tt.fee {
+				t.Errorf("paid %d and returned %d to the fee pool, want them to add up to the escrow of %d", paid, app.state.FeePool, tt.fee)
+			}
+			if _, open := app.state.Deliberations["proposal"]; open {
+				t.Error("the deliberation is still open after settling")
+			}
+		})
+	}
+}
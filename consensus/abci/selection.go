@@ -0,0 +1,367 @@
+package abci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+)
+
+// SelectionPolicy bounds how the proposer's persona may fill a block. Whatever the persona answers, the block
+// respects the byte limit, includes system and priority-fee transactions, caps each sender and cannot pass over
+// a transaction indefinitely.
+type SelectionPolicy struct {
+	// PersonaSelection lets the proposer's agent choose and order the discretionary transactions
+	PersonaSelection bool `json:"persona_selection"`
+	// PriorityFee is the fee at or above which a transaction is always included, ahead of the persona's choices
+	PriorityFee uint64 `json:"priority_fee"`
+	// MaxTxsPerSender caps the transactions of one sender in a block; 0 means no cap
+	MaxTxsPerSender int `json:"max_txs_per_sender"`
+	// MaxSkips is how many of this node's proposals may leave a transaction out before it is forced in
+	MaxSkips int `json:"max_skips"`
+	// Timeout bounds the persona's answer; after it the block is filled by fee. It has to stay well under the
+	// consensus timeout_propose, or the proposal arrives after the round has moved on.
+	Timeout time.Duration `json:"timeout"`
+}
+
+// DefaultSelectionPolicy fills blocks by fee, without consulting the persona
+func DefaultSelectionPolicy() SelectionPolicy {
+	return SelectionPolicy{
+		MaxSkips: 3,
+		Timeout:  time.Second,
+	}
+}
+
+var (
+	selectionMu     sync.RWMutex
+	selectionPolicy = DefaultSelectionPolicy()
+)
+
+// SetSelectionPolicy replaces the policy proposers fill blocks with
+func SetSelectionPolicy(policy SelectionPolicy) {
+	selectionMu.Lock()
+	defer selectionMu.Unlock()
+	selectionPolicy = policy
+}
+
+// GetSelectionPolicy returns the policy proposers fill blocks with
+func GetSelectionPolicy() SelectionPolicy {
+	selectionMu.RLock()
+	defer selectionMu.RUnlock()
+	return selectionPolicy
+}
+
+// LoadSelectionPolicyFromEnv reads the TX_SELECTION_* variables over the default policy
+func LoadSelectionPolicyFromEnv() {
+	policy := DefaultSelectionPolicy()
+	if v := os.Getenv("TX_SELECTION_PERSONA"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Printf("Warning: invalid TX_SELECTION_PERSONA %q", v)
+		}
+		policy.PersonaSelection = enabled
+	}
+	if v := os.Getenv("TX_SELECTION_PRIORITY_FEE"); v != "" {
+		fee, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			log.Printf("Warning: invalid TX_SELECTION_PRIORITY_FEE %q", v)
+		}
+		policy.PriorityFee = fee
+	}
+	if v := os.Getenv("TX_SELECTION_MAX_PER_SENDER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Printf("Warning: invalid TX_SELECTION_MAX_PER_SENDER %q", v)
+		} else {
+			policy.MaxTxsPerSender = n
+		}
+	}
+	if v := os.Getenv("TX_SELECTION_MAX_SKIPS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Printf("Warning: invalid TX_SELECTION_MAX_SKIPS %q", v)
+		} else {
+			policy.MaxSkips = n
+		}
+	}
+	if v := os.Getenv("TX_SELECTION_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			log.Printf("Warning: invalid TX_SELECTION_TIMEOUT %q", v)
+		} else {
+			policy.Timeout = timeout
+		}
+	}
+	SetSelectionPolicy(policy)
+}
+
+// BlockSelection explains how a proposer filled a block, and is published with the EventBlockSelection event
+type BlockSelection struct {
+	ChainID         string `json:"chain_id"`
+	Height          int64  `json:"height"`
+	Proposer        string `json:"proposer"`
+	AgentID         string `json:"agent_id,omitempty"`
+	PersonaVersion  int    `json:"persona_version,omitempty"`
+	PersonaSelected bool   `json:"persona_selected"`
+	Offered         int    `json:"offered"`
+	Included        int    `json:"included"`
+	Forced          int    `json:"forced"`
+	Excluded        int    `json:"excluded"`
+	Deferred        int    `json:"deferred"`
+	Bytes           int64  `json:"bytes"`
+	Rationale       string `json:"rationale,omitempty"`
+	Fallback        string `json:"fallback,omitempty"`
+}
+
+// proposalCandidate is a valid transaction offered to the proposer
+type proposalCandidate struct {
+	raw []byte
+	tx  core.Transaction
+	key string
+}
+
+func newProposalCandidate(raw []byte, tx core.Transaction) proposalCandidate {
+	sum := sha256.Sum256(raw)
+	return proposalCandidate{raw: raw, tx: tx, key: hex.EncodeToString(sum[:])}
+}
+
+// systemTx reports whether a transaction changes who takes part in consensus or what they earn. No persona may
+// leave these out.
+func systemTx(tx core.Transaction) bool {
+	return tx.Type == core.TxRegisterAgent || tx.Type == "register_validator" || tx.Type == core.TxAgentVerdict
+}
+
+// selectProposalTxs fills a block from the valid candidates. Candidates are ranked by fee, capped per sender and
+// split into forced and discretionary ones; the persona, when enabled, chooses and orders the discretionary ones,
+// and the block is packed forced first up to maxBytes. Each sender's transactions keep their nonce order, and
+// what the block cannot pay for in its final order is deferred.
+func (app *Application) selectProposalTxs(height int64, candidates []proposalCandidate, maxBytes int64, agent *core.Agent) ([]proposalCandidate, BlockSelection) {
+	policy := GetSelectionPolicy()
+	summary := BlockSelection{
+		ChainID:  app.chainID,
+		Height:   height,
+		Proposer: app.selfValidatorAddr,
+		Offered:  len(candidates),
+	}
+
+	app.mu.RLock()
+	skips := app.skipped
+	app.mu.RUnlock()
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].tx.Fee > candidates[j].tx.Fee
+	})
+	app.sequenceBySender(candidates)
+
+	var forced, discretionary []proposalCandidate
+	perSender := make(map[string]int)
+	for _, candidate := range candidates {
+		if !systemTx(candidate.tx) {
+			if policy.MaxTxsPerSender > 0 && perSender[candidate.tx.From] >= policy.MaxTxsPerSender {
+				summary.Deferred++
+				continue
+			}
+			perSender[candidate.tx.From]++
+		}
+
+		switch {
+		case systemTx(candidate.tx),
+			policy.PriorityFee > 0 && candidate.tx.Fee >= policy.PriorityFee,
+			policy.MaxSkips > 0 && skips[candidate.key] >= policy.MaxSkips:
+			forced = append(forced, candidate)
+		default:
+			discretionary = append(discretionary, candidate)
+		}
+	}
+	summary.Forced = len(forced)
+
+	chosen := discretionary
+	if agent != nil {
+		summary.AgentID = agent.ID
+		summary.PersonaVersion = agent.PersonaVersion
+	}
+	if policy.PersonaSelection && agent != nil && len(discretionary) > 0 {
+		txs := make([]core.Transaction, len(discretionary))
+		for i, candidate := range discretionary {
+			txs[i] = candidate.tx
+		}
+
+		selection, err := app.personaSelection(*agent, txs, policy.Timeout)
+		if err != nil {
+			log.Printf("Agent %s could not select transactions, filling block %d by fee: %v", agent.ID, height, err)
+			summary.Fallback = err.Error()
+		} else {
+			chosen = make([]proposalCandidate, len(selection.Indexes))
+			for i, index := range selection.Indexes {
+				chosen[i] = discretionary[index]
+			}
+			summary.PersonaSelected = true
+			summary.Rationale = selection.Rationale
+			summary.Excluded = len(discretionary) - len(chosen)
+		}
+	}
+
+	// A sender's transactions are paid in nonce order, so one left out holds back that sender's later ones
+	heldFrom := make(map[string]uint64)
+	holdFrom := func(tx core.Transaction) {
+		if nonce, held := heldFrom[tx.From]; !held || tx.Nonce < nonce {
+			heldFrom[tx.From] = tx.Nonce
+		}
+	}
+	if summary.PersonaSelected {
+		chosenKeys := make(map[string]bool, len(chosen))
+		for _, candidate := range chosen {
+			chosenKeys[candidate.key] = true
+		}
+		for _, candidate := range discretionary {
+			if !chosenKeys[candidate.key] && app.paidTx(candidate.tx) {
+				holdFrom(candidate.tx)
+			}
+		}
+	}
+
+	// The block is paid for in its final order, as DeliverTx will, and whatever that order cannot pay for waits
+	ordered := append(append([]proposalCandidate{}, forced...), chosen...)
+	app.sequenceBySender(ordered)
+	payments := app.newLedger()
+	var block []proposalCandidate
+	included := make(map[string]bool)
+	for _, candidate := range ordered {
+		paid := app.paidTx(candidate.tx)
+		if nonce, held := heldFrom[candidate.tx.From]; paid && held && nonce < candidate.tx.Nonce {
+			summary.Deferred++
+			continue
+		}
+		if maxBytes > 0 && summary.Bytes+int64(len(candidate.raw)) > maxBytes {
+			if paid {
+				holdFrom(candidate.tx)
+			}
+			summary.Deferred++
+			continue
+		}
+		if err := payments.pay(candidate.tx); err != nil {
+			log.Printf("Deferring %s from %s, the block cannot pay for it in this order: %v", candidate.tx.Type, candidate.tx.From, err)
+			holdFrom(candidate.tx)
+			summary.Deferred++
+			continue
+		}
+		block = append(block, candidate)
+		included[candidate.key] = true
+		summary.Bytes += int64(len(candidate.raw))
+	}
+	summary.Included = len(block)
+
+	// Only the persona's exclusions count towards forcing a transaction in; space and sender caps do not
+	excluded := make(map[string]bool)
+	if summary.PersonaSelected {
+		for _, candidate := range discretionary {
+			excluded[candidate.key] = true
+		}
+		for _, candidate := range chosen {
+			delete(excluded, candidate.key)
+		}
+	}
+	skipped := make(map[string]int)
+	for _, candidate := range candidates {
+		switch {
+		case included[candidate.key]:
+		case excluded[candidate.key]:
+			skipped[candidate.key] = skips[candidate.key] + 1
+		case skips[candidate.key] > 0:
+			skipped[candidate.key] = skips[candidate.key]
+		}
+	}
+	app.mu.Lock()
+	app.skipped = skipped
+	app.mu.Unlock()
+
+	return block, summary
+}
+
+// personaSelection asks the agent to choose among the discretionary transactions. The answer is given up on once
+// the timeout expires, whether or not the model call honours its context, so a slow model never holds up the
+// proposal.
+func (app *Application) personaSelection(agent core.Agent, txs []core.Transaction, timeout time.Duration) (ai.TxSelection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type answer struct {
+		selection ai.TxSelection
+		err       error
+	}
+	answers := make(chan answer, 1)
+	go func() {
+		selection, err := ai.PersonalityFromAgent(app.chainID, agent).SelectTransactions(ctx, txs)
+		answers <- answer{selection, err}
+	}()
+
+	select {
+	case a := <-answers:
+		return a.selection, a.err
+	case <-ctx.Done():
+		return ai.TxSelection{}, fmt.Errorf("no selection within %s: %w", timeout, ctx.Err())
+	}
+}
+
+// sequenceBySender puts the paid transactions of every sender back in nonce order within the places ranking gave
+// them, as the ledger only accepts a sender's nonces in increasing order
+func (app *Application) sequenceBySender(candidates []proposalCandidate) {
+	places := make(map[string][]int)
+	for i, candidate := range candidates {
+		if app.paidTx(candidate.tx) {
+			places[candidate.tx.From] = append(places[candidate.tx.From], i)
+		}
+	}
+	for _, indexes := range places {
+		if len(indexes) < 2 {
+			continue
+		}
+		sender := make([]proposalCandidate, len(indexes))
+		for i, index := range indexes {
+			sender[i] = candidates[index]
+		}
+		sort.SliceStable(sender, func(i, j int) bool {
+			return sender[i].tx.Nonce < sender[j].tx.Nonce
+		})
+		for i, index := range indexes {
+			candidates[index] = sender[i]
+		}
+	}
+}
+
+// announceBlock publishes how a block was filled and, when its agent chose the transactions, lets the agent
+// announce it. The announcement is generated in the background so it never delays the proposal.
+func (app *Application) announceBlock(summary BlockSelection, txs []core.Transaction, agent *core.Agent) {
+	communication.BroadcastEvent(communication.EventBlockSelection, summary)
+	if agent == nil || !summary.PersonaSelected {
+		return
+	}
+
+	personality := ai.PersonalityFromAgent(app.chainID, *agent)
+	block := core.Block{
+		Height:    int(summary.Height),
+		Txs:       txs,
+		Timestamp: time.Now().Unix(),
+		Proposer:  summary.Proposer,
+		ChainID:   app.chainID,
+	}
+	go func() {
+		communication.BroadcastEvent(communication.EventBlockAnnouncement, map[string]interface{}{
+			"chain_id":     summary.ChainID,
+			"height":       summary.Height,
+			"proposer":     summary.Proposer,
+			"agent_id":     agent.ID,
+			"announcement": personality.GenerateBlockAnnouncement(block),
+		})
+	}()
+}
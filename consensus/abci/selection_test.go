@@ -0,0 +1,172 @@
+package abci
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/ai"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestSelectProposalTxsKeepsBlocksPayable(t *testing.T) {
+	type account struct {
+		key     *ecdsa.PrivateKey
+		address string
+	}
+	type offer struct {
+		from, to string // test account names
+		amount   float64
+		fee      uint64
+		nonce    uint64
+	}
+	tests := []struct {
+		name     string
+		balances map[string]uint64
+		offered  []offer
+		want     []int // indexes into offered, in block order
+	}{
+		{
+			name:     "higher fee on the higher nonce",
+			balances: map[string]uint64{"alice": 100},
+			offered: []offer{
+				{from: "alice", to: "bob", amount: 10, fee: 1, nonce: 1},
+				{from: "alice", to: "bob", amount: 10, fee: 5, nonce: 2},
+			},
+			want: []int{0, 1},
+		},
+		{
+			name:     "nonces interleaved with another sender",
+			balances: map[string]uint64{"alice": 100, "carol": 100},
+			offered: []offer{
+				{from: "alice", to: "bob", amount: 10, fee: 1, nonce: 1},
+				{from: "carol", to: "bob", amount: 10, fee: 3, nonce: 1},
+				{from: "alice", to: "bob", amount: 10, fee: 5, nonce: 2},
+			},
+			want: []int{0, 1, 2},
+		},
+		{
+			name:     "spending funds the block has not brought in yet",
+			balances: map[string]uint64{"alice": 100, "bob": 1},
+			offered: []offer{
+				{from: "alice", to: "bob", amount: 20, fee: 1, nonce: 1},
+				{from: "bob", to: "carol", amount: 10, fee: 1, nonce: 1},
+				{from: "bob", to: "carol", amount: 5, fee: 2, nonce: 2},
+			},
+			want: []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := make(map[string]account)
+			balances := make(map[string]uint64)
+			for _, name := range []string{"alice", "bob", "carol"} {
+				key, address := newTestAccount(t)
+				keys[name] = account{key, address}
+				balances[address] = tt.balances[name]
+			}
+			app := newTestApp(t, ed25519.GenPrivKey().PubKey().Address().String(), balances)
+
+			var candidates []proposalCandidate
+			offered := make(map[string]int)
+			for i, o := range tt.offered {
+				tx := signTx(t, keys[o.from].key, core.Transaction{
+					Type: core.TxTransfer, To: keys[o.to].address, Amount: o.amount, Fee: o.fee, Nonce: o.nonce,
+				})
+				raw, _ := json.Marshal(tx)
+				candidate := newProposalCandidate(raw, tx)
+				offered[candidate.key] = i
+				candidates = append(candidates, candidate)
+			}
+
+			block, summary := app.selectProposalTxs(1, candidates, 0, nil)
+
+			got := make([]int, len(block))
+			payments := app.newLedger()
+			for i, candidate := range block {
+				got[i] = offered[candidate.key]
+				if err := payments.pay(candidate.tx); err != nil {
+					t.Errorf("transaction %d of the block fails in DeliverTx: %v", got[i], err)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("block = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("block = %v, want %v", got, tt.want)
+				}
+			}
+			if summary.Included+summary.Deferred != len(tt.offered) {
+				t.Errorf("included %d and deferred %d of %d offered", summary.Included, summary.Deferred, len(tt.offered))
+			}
+		})
+	}
+}
+
+// stalledProvider stands in for a model that does not answer, whatever the context says, until it is released
+type stalledProvider struct {
+	release chan struct{}
+}
+
+func (stalledProvider) Name() string {
+	return "stalled"
+}
+
+func (p stalledProvider) Complete(ctx context.Context, req ai.CompletionRequest) (ai.CompletionResponse, error) {
+	<-p.release
+	return ai.CompletionResponse{}, errors.New("model answered too late")
+}
+
+func TestSelectProposalTxsFallsBackToFeeOrderWhenThePersonaStalls(t *testing.T) {
+	provider := stalledProvider{release: make(chan struct{})}
+	previousProvider := ai.CurrentProvider()
+	ai.SetProvider(provider)
+	previousPolicy := GetSelectionPolicy()
+	SetSelectionPolicy(SelectionPolicy{PersonaSelection: true, MaxSkips: 3, Timeout: 50 * time.Millisecond})
+	t.Cleanup(func() {
+		close(provider.release)
+		ai.SetProvider(previousProvider)
+		SetSelectionPolicy(previousPolicy)
+	})
+
+	key, sender := newTestAccount(t)
+	_, recipient := newTestAccount(t)
+	app := newTestApp(t, ed25519.GenPrivKey().PubKey().Address().String(), map[string]uint64{sender: 100})
+	var candidates []proposalCandidate
+	for nonce, fee := range []uint64{1, 3, 2} {
+		tx := signTx(t, key, core.Transaction{Type: "discuss_transaction", To: recipient, Content: "hello", Fee: fee, Nonce: uint64(nonce + 1)})
+		raw, _ := json.Marshal(tx)
+		candidates = append(candidates, newProposalCandidate(raw, tx))
+	}
+
+	done := make(chan struct{})
+	var block []proposalCandidate
+	var summary BlockSelection
+	go func() {
+		block, summary = app.selectProposalTxs(1, candidates, 0, &core.Agent{ID: "producer", Name: "Producer"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the proposal waited for the stalled model")
+	}
+
+	if summary.PersonaSelected || summary.Fallback == "" {
+		t.Errorf("summary = %+v, want a fallback to fee order", summary)
+	}
+	if len(block) != len(candidates) {
+		t.Fatalf("block has %d transactions, want %d", len(block), len(candidates))
+	}
+	for i, candidate := range block {
+		if candidate.tx.Nonce != uint64(i+1) {
+			t.Errorf("transaction %d of the block has nonce %d, want %d", i, candidate.tx.Nonce, i+1)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package abci
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/communication"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	types "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+// DefaultGenesisStake is bonded to the genesis validator of a new chain
+const DefaultGenesisStake = 1000000
+
+// StakingParams decide how bonded stake turns into voting power and how long unstaked tokens stay locked
+type StakingParams struct {
+	// UnbondingBlocks is how many blocks unstaked tokens stay locked before they return to their owner
+	UnbondingBlocks int64 `json:"unbonding_blocks"`
+	// PowerReduction is how many bonded tokens make one unit of voting power
+	PowerReduction uint64 `json:"power_reduction"`
+}
+
+// DefaultStakingParams gives a validator one unit of voting power per bonded token and unbonds in 100 blocks
+func DefaultStakingParams() StakingParams {
+	return StakingParams{UnbondingBlocks: 100, PowerReduction: 1}
+}
+
+// reduction returns the tokens per unit of voting power, never less than one
+func (p StakingParams) reduction() uint64 {
+	if p.PowerReduction == 0 {
+		return 1
+	}
+	return p.PowerReduction
+}
+
+// power returns the voting power a bonded stake is worth
+func (p StakingParams) power(bonded uint64) int64 {
+	power := bonded / p.reduction()
+	if power > uint64(cmttypes.MaxTotalVotingPower) {
+		return cmttypes.MaxTotalVotingPower
+	}
+	return int64(power)
+}
+
+// StakedValidator is a validator key with the stake bonded to it and the voting power it has in the validator set
+type StakedValidator struct {
+	PubKey []byte `json:"pub_key"`
+	Bonded uint64 `json:"bonded"`
+	Power  int64  `json:"power"`
+}
+
+// Unbonding is unstaked tokens on their way back to their owner's account
+type Unbonding struct {
+	Delegator  string `json:"delegator"`
+	Validator  string `json:"validator"`
+	Amount     uint64 `json:"amount"`
+	CompleteAt int64  `json:"complete_at"`
+}
+
+// GenesisStake is stake bonded to a validator at genesis. Stake without an owner account can never be unstaked.
+type GenesisStake struct {
+	Owner  string `json:"owner,omitempty"`
+	Amount uint64 `json:"amount"`
+}
+
+// stakingTx reports whether a transaction bonds or unbonds stake
+func stakingTx(tx core.Transaction) bool {
+	return bondingTx(tx) || tx.Type == core.TxUnstake
+}
+
+// bondingTx reports whether a transaction takes tokens out of the sender's account to bond them
+func bondingTx(tx core.Transaction) bool {
+	return tx.Type == core.TxStake || tx.Type == core.TxDelegate
+}
+
+// AddGenesisStake bonds stake to a validator in the app_state of a genesis document and returns the updated
+// app_state with the voting power the stake gives the validator
+func AddGenesisStake(appState json.RawMessage, validatorAddr, owner string, amount uint64) (json.RawMessage, int64, error) {
+	genesis := GenesisState{}
+	if len(appState) > 0 {
+		if err := json.Unmarshal(appState, &genesis); err != nil {
+			return nil, 0, fmt.Errorf("invalid genesis app state: %v", err)
+		}
+	}
+
+	params := DefaultStakingParams()
+	if genesis.Staking != nil {
+		params = *genesis.Staking
+	}
+	power := params.power(amount)
+	if power == 0 {
+		return nil, 0, fmt.Errorf("stake of %d is below the %d tokens of one unit of voting power", amount, params.reduction())
+	}
+
+	if genesis.Stakes == nil {
+		genesis.Stakes = make(map[string]GenesisStake)
+	}
+	genesis.Stakes[validatorAddr] = GenesisStake{Owner: owner, Amount: amount}
+	encoded, err := json.Marshal(genesis)
+	if err != nil {
+		return nil, 0, err
+	}
+	return encoded, power, nil
+}
+
+// bondGenesisStakes records the genesis validators with their genesis stake. A validator without a stake in the
+// app_state, or whose stake is worth no voting power, is bonded as much as its genesis power is worth, owned by no
+// account. Callers hold stateMu.
+func (app *Application) bondGenesisStakes(stakes map[string]GenesisStake, validators []types.ValidatorUpdate) {
+	params := app.state.StakingParams
+	for _, val := range validators {
+		pubKey := val.PubKey.GetEd25519()
+		address := ed25519.PubKey(pubKey).Address().String()
+
+		stake, staked := stakes[address]
+		if !staked || params.power(stake.Amount) == 0 {
+			if staked {
+				log.Printf("Genesis stake of validator %s is worth no voting power, bonding its genesis power instead", address)
+			}
+			stake = GenesisStake{Amount: uint64(val.Power) * params.reduction()}
+		}
+		owner := stake.Owner
+		if owner == "" {
+			owner = address
+		}
+
+		app.state.Validators[address] = StakedValidator{PubKey: pubKey, Bonded: stake.Amount, Power: params.power(stake.Amount)}
+		app.state.Delegations[address] = map[string]uint64{owner: stake.Amount}
+	}
+}
+
+// bondedValidators returns the validator set as the bonded stake makes it up
+func (app *Application) bondedValidators() []types.ValidatorUpdate {
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+
+	addresses := make([]string, 0, len(app.state.Validators))
+	for address := range app.state.Validators {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	var validators []types.ValidatorUpdate
+	for _, address := range addresses {
+		if validator := app.state.Validators[address]; validator.Power > 0 {
+			validators = append(validators, types.Ed25519ValidatorUpdate(validator.PubKey, validator.Power))
+		}
+	}
+	return validators
+}
+
+// checkStaking validates a staking transaction against the committed stake: tokens are bonded to a registered
+// validator, stake only by the validator's reward address, and only bonded tokens can be unstaked
+func (app *Application) checkStaking(tx core.Transaction) error {
+	if !stakingTx(tx) {
+		return nil
+	}
+	sender, err := tx.Sender()
+	if err != nil {
+		return err
+	}
+	amount, err := tokenAmount(tx)
+	if err != nil {
+		return err
+	}
+
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+
+	if _, exists := app.state.Validators[tx.To]; !exists {
+		return fmt.Errorf("no validator %s is registered", tx.To)
+	}
+	switch tx.Type {
+	case core.TxStake:
+		if binding, bound := app.state.AgentBindings[tx.To]; !bound || binding.RewardAddress != sender {
+			return fmt.Errorf("only the reward address of validator %s can stake to it, others delegate", tx.To)
+		}
+	case core.TxUnstake:
+		if bonded := app.state.Delegations[tx.To][sender]; bonded < amount {
+			return fmt.Errorf("account %s has %d bonded to validator %s, cannot unstake %d", sender, bonded, tx.To, amount)
+		}
+	}
+	return nil
+}
+
+// applyStaking bonds the tokens of a paid stake or delegation to its validator, or starts unbonding them. The
+// voting power follows in EndBlock.
+func (app *Application) applyStaking(tx core.Transaction, height int64) StakedValidator {
+	amount := uint64(tx.Amount)
+
+	app.stateMu.Lock()
+	validator := app.state.Validators[tx.To]
+	delegations := app.state.Delegations[tx.To]
+	if delegations == nil {
+		delegations = make(map[string]uint64)
+		app.state.Delegations[tx.To] = delegations
+	}
+
+	if tx.Type == core.TxUnstake {
+		validator.Bonded -= amount
+		delegations[tx.From] -= amount
+		if delegations[tx.From] == 0 {
+			delete(delegations, tx.From)
+		}
+		app.state.Unbondings = append(app.state.Unbondings, Unbonding{
+			Delegator:  tx.From,
+			Validator:  tx.To,
+			Amount:     amount,
+			CompleteAt: height + app.state.StakingParams.UnbondingBlocks,
+		})
+	} else {
+		validator.Bonded += amount
+		delegations[tx.From] += amount
+	}
+	app.state.Validators[tx.To] = validator
+	app.stateMu.Unlock()
+
+	communication.BroadcastEvent(communication.EventStakeChanged, map[string]interface{}{
+		"chain_id":  app.chainID,
+		"height":    height,
+		"type":      tx.Type,
+		"validator": tx.To,
+		"delegator": tx.From,
+		"amount":    amount,
+		"bonded":    validator.Bonded,
+	})
+	return validator
+}
+
+// endBlockStaking returns matured unbondings to their owners and the validator updates that bring voting power in
+// line with bonded stake. Updates that would leave the set without voting power are held back until stake returns.
+func (app *Application) endBlockStaking(height int64) []types.ValidatorUpdate {
+	app.stateMu.Lock()
+	defer app.stateMu.Unlock()
+
+	var pending []Unbonding
+	for _, unbonding := range app.state.Unbondings {
+		if unbonding.CompleteAt > height {
+			pending = append(pending, unbonding)
+			continue
+		}
+		account := app.state.Accounts[unbonding.Delegator]
+		account.Balance += unbonding.Amount
+		app.state.Accounts[unbonding.Delegator] = account
+		log.Printf("Unbonded %d from validator %s to %s", unbonding.Amount, unbonding.Validator, unbonding.Delegator)
+	}
+	app.state.Unbondings = pending
+
+	params := app.state.StakingParams
+	addresses := make([]string, 0, len(app.state.Validators))
+	var total int64
+	for address, validator := range app.state.Validators {
+		addresses = append(addresses, address)
+		total += params.power(validator.Bonded)
+	}
+	sort.Strings(addresses)
+	if total == 0 {
+		log.Printf("Keeping the validator set at height %d, bonded stake would leave it without voting power", height)
+		return nil
+	}
+
+	var updates []types.ValidatorUpdate
+	for _, address := range addresses {
+		validator := app.state.Validators[address]
+		power := params.power(validator.Bonded)
+		if power == validator.Power {
+			continue
+		}
+		log.Printf("Voting power of validator %s changes from %d to %d", address, validator.Power, power)
+		updates = append(updates, types.Ed25519ValidatorUpdate(validator.PubKey, power))
+		validator.Power = power
+		app.state.Validators[address] = validator
+	}
+	return updates
+}
+
+// queryStakes answers the stake, stakes and delegations queries
+func (app *Application) queryStakes(path string, data []byte) ([]byte, error) {
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+
+	switch path {
+	case "stakes":
+		return json.Marshal(app.state.Validators)
+	case "delegations":
+		delegator := string(data)
+		bonded := make(map[string]uint64)
+		for validator, delegations := range app.state.Delegations {
+			if amount, exists := delegations[delegator]; exists {
+				bonded[validator] = amount
+			}
+		}
+		unbondings := []Unbonding{}
+		for _, unbonding := range app.state.Unbondings {
+			if unbonding.Delegator == delegator {
+				unbondings = append(unbondings, unbonding)
+			}
+		}
+		return json.Marshal(map[string]interface{}{"bonded": bonded, "unbonding": unbondings})
+	}
+
+	address := string(data)
+	validator, exists := app.state.Validators[address]
+	if !exists {
+		return nil, fmt.Errorf("no validator %s is registered", address)
+	}
+	unbondings := []Unbonding{}
+	for _, unbonding := range app.state.Unbondings {
+		if unbonding.Validator == address {
+			unbondings = append(unbondings, unbonding)
+		}
+	}
+	return json.Marshal(map[string]interface{}{
+		"validator":   validator,
+		"delegations": app.state.Delegations[address],
+		"unbonding":   unbondings,
+	})
+}
@@ -0,0 +1,121 @@
+package abci
+
+import (
+	"testing"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestUnbondingMatures(t *testing.T) {
+	const unstakedAt = 5
+
+	tests := []struct {
+		name        string
+		after       int64 // blocks after the unstake that EndBlock runs
+		wantBalance uint64
+		wantPending int
+	}{
+		{"same block", 0, 0, 1},
+		{"one block before maturity", 99, 0, 1},
+		{"at maturity", 100, 40, 0},
+		{"after maturity", 150, 40, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, ed25519.GenPrivKey().PubKey().Address().String(), nil)
+			app.state.StakingParams = StakingParams{UnbondingBlocks: 100, PowerReduction: 1}
+			validator := ed25519.GenPrivKey().PubKey()
+			address := validator.Address().String()
+			_, delegator := newTestAccount(t)
+			app.state.Validators[address] = StakedValidator{PubKey: validator.Bytes(), Bonded: 100, Power: 100}
+			app.state.Delegations[address] = map[string]uint64{delegator: 100}
+
+			app.applyStaking(core.Transaction{Type: core.TxUnstake, From: delegator, To: address, Amount: 40}, unstakedAt)
+			app.endBlockStaking(unstakedAt + tt.after)
+
+			if got := app.state.Accounts[delegator].Balance; got != tt.wantBalance {
+				t.Errorf("delegator holds %d, want %d", got, tt.wantBalance)
+			}
+			if got := len(app.state.Unbondings); got != tt.wantPending {
+				t.Errorf("%d unbondings pending, want %d", got, tt.wantPending)
+			}
+			if got := app.state.Delegations[address][delegator]; got != 60 {
+				t.Errorf("delegator has %d bonded, want 60", got)
+			}
+			if got := app.state.Validators[address].Power; got != 60 {
+				t.Errorf("validator power = %d, want 60", got)
+			}
+		})
+	}
+}
+
+func TestEndBlockStakingHoldsBackAnEmptySet(t *testing.T) {
+	tests := []struct {
+		name        string
+		validators  []StakedValidator // PubKey is filled in
+		wantUpdates map[int]int64     // new power by validator index
+		wantPower   []int64
+	}{
+		{
+			name:        "last validator unbonds everything",
+			validators:  []StakedValidator{{Bonded: 0, Power: 10}},
+			wantUpdates: map[int]int64{},
+			wantPower:   []int64{10},
+		},
+		{
+			name:        "every validator unbonds everything",
+			validators:  []StakedValidator{{Bonded: 0, Power: 10}, {Bonded: 0, Power: 5}},
+			wantUpdates: map[int]int64{},
+			wantPower:   []int64{10, 5},
+		},
+		{
+			name:        "another validator keeps power",
+			validators:  []StakedValidator{{Bonded: 0, Power: 10}, {Bonded: 5, Power: 5}},
+			wantUpdates: map[int]int64{0: 0},
+			wantPower:   []int64{0, 5},
+		},
+		{
+			name:        "stake grows",
+			validators:  []StakedValidator{{Bonded: 12, Power: 10}, {Bonded: 5, Power: 5}},
+			wantUpdates: map[int]int64{0: 12},
+			wantPower:   []int64{12, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, ed25519.GenPrivKey().PubKey().Address().String(), nil)
+			addresses := make([]string, len(tt.validators))
+			index := make(map[string]int)
+			for i, validator := range tt.validators {
+				pubKey := ed25519.GenPrivKey().PubKey()
+				validator.PubKey = pubKey.Bytes()
+				addresses[i] = pubKey.Address().String()
+				index[string(pubKey.Bytes())] = i
+				app.state.Validators[addresses[i]] = validator
+			}
+
+			updates := app.endBlockStaking(10)
+
+			got := make(map[int]int64)
+			for _, update := range updates {
+				got[index[string(update.PubKey.GetEd25519())]] = update.Power
+			}
+			if len(got) != len(tt.wantUpdates) {
+				t.Errorf("updates = %v, want %v", got, tt.wantUpdates)
+			}
+			for i, power := range tt.wantUpdates {
+				if updated, exists := got[i]; !exists || updated != power {
+					t.Errorf("update of validator %d = %v, want power %d", i, updated, power)
+				}
+			}
+			for i, address := range addresses {
+				if power := app.state.Validators[address].Power; power != tt.wantPower[i] {
+					t.Errorf("validator %d has power %d, want %d", i, power, tt.wantPower[i])
+				}
+			}
+		})
+	}
+}
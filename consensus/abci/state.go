@@ -0,0 +1,57 @@
+package abci
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"log"
+)
+
+// appState is the replicated state every node derives from the committed transactions. It is serialized to
+// compute the app hash, so it must only change in InitChain, DeliverTx and EndBlock and hold nothing node-local.
+type appState struct {
+	AgentBindings map[string]AgentBinding `json:"agent_bindings"`
+	Accounts      map[string]Account      `json:"accounts"`
+	Fees          map[string]FeeRule      `json:"fees"`
+	// FeePool holds the fees paid in committed blocks. Fees of deliberated proposals move into escrow and are
+	// shared among the agents that deliberated them; everything else stays here and is out of circulation for good.
+	FeePool       uint64                  `json:"fee_pool"`
+	Deliberations map[string]Deliberation `json:"deliberations"`
+	Rewards       map[string][]Reward     `json:"rewards"`
+	RewardParams  RewardParams            `json:"reward_params"`
+	// Validators are the registered validator keys by address, with the stake bonded to them
+	Validators map[string]StakedValidator `json:"validators"`
+	// Delegations are the tokens each account bonded to a validator, by validator address
+	Delegations   map[string]map[string]uint64 `json:"delegations"`
+	Unbondings    []Unbonding                  `json:"unbondings"`
+	StakingParams StakingParams                `json:"staking_params"`
+	// Submitters are the accounts that signed each committed proposal
+	Submitters map[string]string `json:"submitters"`
+	// Moderators are the accounts that may comment as moderators
+	Moderators []string `json:"moderators"`
+}
+
+func newAppState() *appState {
+	return &appState{
+		AgentBindings: make(map[string]AgentBinding),
+		Accounts:      make(map[string]Account),
+		Fees:          DefaultFeeSchedule(),
+		Deliberations: make(map[string]Deliberation),
+		Rewards:       make(map[string][]Reward),
+		RewardParams:  DefaultRewardParams(),
+		Validators:    make(map[string]StakedValidator),
+		Delegations:   make(map[string]map[string]uint64),
+		StakingParams: DefaultStakingParams(),
+		Submitters:    make(map[string]string),
+	}
+}
+
+// hash commits to the whole state. Maps serialize with sorted keys, so every node computes the same hash.
+func (s *appState) hash() []byte {
+	data, err := json.Marshal(s)
+	if err != nil {
+		log.Printf("Failed to marshal app state: %v", err)
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// TxTransfer moves tokens from the sender's account to the account in To
+const TxTransfer = "transfer"
+
+// Staking transactions bond the sender's tokens to the validator whose address is in To, or start unbonding them
+const (
+	// TxStake bonds the operator's own tokens to its validator; the sender must be the validator's reward address
+	TxStake = "stake"
+	// TxDelegate bonds the sender's tokens to a validator whose agent it trusts
+	TxDelegate = "delegate"
+	// TxUnstake starts unbonding Amount of the sender's bond to a validator
+	TxUnstake = "unstake"
+)
+
+// AccountAddress derives the address of the account owned by a compressed P-256 public key, given in hex
+func AccountAddress(publicKey string) (string, error) {
+	pubBytes, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key encoding: %v", err)
+	}
+	if x, _ := elliptic.UnmarshalCompressed(elliptic.P256(), pubBytes); x == nil {
+		return "", fmt.Errorf("invalid public key")
+	}
+	sum := sha256.Sum256(pubBytes)
+	return "0x" + hex.EncodeToString(sum[:20]), nil
+}
+
+// AccountAddressOf returns the address of the account owned by a private key
+func AccountAddressOf(privateKey *ecdsa.PrivateKey) string {
+	pubBytes := elliptic.MarshalCompressed(privateKey.PublicKey.Curve, privateKey.PublicKey.X, privateKey.PublicKey.Y)
+	address, _ := AccountAddress(hex.EncodeToString(pubBytes))
+	return address
+}
+
+// Sender verifies the transaction signature and returns the account that signed it, which must be the one in From
+func (tx *Transaction) Sender() (string, error) {
+	if !tx.VerifySignature() {
+		return "", fmt.Errorf("signature verification failed")
+	}
+	address, err := AccountAddress(tx.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	if tx.From != address {
+		return "", fmt.Errorf("sender %s does not own the signing key of account %s", tx.From, address)
+	}
+	return address, nil
+}
@@ -7,5 +7,7 @@ type Agent struct {
 	Role             string                 `json:"role"`
 	ValidatorAddress string                 `json:"validator_address,omitempty"`
 	IsValidator      bool                   `json:"is_validator"`
+	Disabled         bool                   `json:"disabled,omitempty"`
+	PersonaVersion   int                    `json:"persona_version,omitempty"`
 	Metadata         map[string]interface{} `json:"metadata"`
 }
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cometbft/cometbft/crypto"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+// TxRegisterAgent binds an agent persona to a validator key on chain
+const TxRegisterAgent = "register_agent"
+
+// AgentRegistration is the content of a register_agent transaction. It is signed with the validator key it
+// binds, so only the validator's operator can choose which agent deliberates for it. The nonce must grow with
+// every registration of the same validator, so an old registration cannot be replayed over a newer one.
+type AgentRegistration struct {
+	Persona         Persona `json:"persona"`
+	PersonaHash     string  `json:"persona_hash"`
+	ValidatorPubKey []byte  `json:"validator_pub_key"`
+	Nonce           uint64  `json:"nonce"`
+	// RewardAddress is the account credited with the agent's deliberation rewards
+	RewardAddress string `json:"reward_address,omitempty"`
+	Signature     []byte `json:"signature"`
+}
+
+// Hash identifies a persona's spec. Settings are node-local configuration, such as endpoints and keys, and are
+// not part of it.
+func (p Persona) Hash() string {
+	p.Settings = nil
+	data, _ := json.Marshal(p)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidatorAddress returns the address of the validator the registration binds
+func (r AgentRegistration) ValidatorAddress() string {
+	return ed25519.PubKey(r.ValidatorPubKey).Address().String()
+}
+
+// signBytes is what the validator key signs: the chain, the agent, its persona hash, the key, the nonce and the
+// reward address
+func (r AgentRegistration) signBytes(chainID string) []byte {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%X|%d|%s", chainID, r.Persona.ID, r.PersonaHash, r.ValidatorPubKey, r.Nonce, r.RewardAddress)))
+	return sum[:]
+}
+
+// NewAgentRegistration builds a register_agent transaction binding a persona to the validator key that signs it
+func NewAgentRegistration(chainID string, persona Persona, rewardAddress string, privKey crypto.PrivKey, nonce uint64) (Transaction, error) {
+	persona.Settings = nil
+	if err := persona.Validate(); err != nil {
+		return Transaction{}, err
+	}
+
+	reg := AgentRegistration{
+		Persona:         persona,
+		PersonaHash:     persona.Hash(),
+		ValidatorPubKey: privKey.PubKey().Bytes(),
+		Nonce:           nonce,
+		RewardAddress:   rewardAddress,
+	}
+	signature, err := privKey.Sign(reg.signBytes(chainID))
+	if err != nil {
+		return Transaction{}, fmt.Errorf("failed to sign agent registration: %v", err)
+	}
+	reg.Signature = signature
+
+	content, err := json.Marshal(reg)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("failed to encode agent registration: %v", err)
+	}
+	return Transaction{
+		Type:      TxRegisterAgent,
+		From:      reg.ValidatorAddress(),
+		Content:   string(content),
+		Timestamp: time.Now().Unix(),
+		ChainID:   chainID,
+	}, nil
+}
+
+// ParseAgentRegistration decodes a register_agent transaction and checks the persona, its hash and the validator
+// signature
+func ParseAgentRegistration(tx Transaction) (AgentRegistration, error) {
+	var reg AgentRegistration
+	if err := json.Unmarshal([]byte(tx.Content), &reg); err != nil {
+		return AgentRegistration{}, fmt.Errorf("invalid registration format: %v", err)
+	}
+	if len(reg.ValidatorPubKey) != ed25519.PubKeySize {
+		return AgentRegistration{}, fmt.Errorf("validator public key must be %d bytes", ed25519.PubKeySize)
+	}
+	if len(reg.Persona.Settings) > 0 {
+		return AgentRegistration{}, fmt.Errorf("persona settings are node-local and must not be registered on chain")
+	}
+	if err := reg.Persona.Validate(); err != nil {
+		return AgentRegistration{}, err
+	}
+	if reg.PersonaHash != reg.Persona.Hash() {
+		return AgentRegistration{}, fmt.Errorf("persona hash does not match the persona")
+	}
+	if tx.From != reg.ValidatorAddress() {
+		return AgentRegistration{}, fmt.Errorf("sender %s is not the registering validator %s", tx.From, reg.ValidatorAddress())
+	}
+	if !ed25519.PubKey(reg.ValidatorPubKey).VerifySignature(reg.signBytes(tx.ChainID), reg.Signature) {
+		return AgentRegistration{}, fmt.Errorf("signature verification failed")
+	}
+	return reg, nil
+}
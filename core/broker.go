@@ -1,7 +1,9 @@
 package core
 
 import (
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/nats-io/nats-server/v2/server"
@@ -10,34 +12,123 @@ import (
 
 var NatsBrokerInstance *nats.Conn
 var natsServer *server.Server
+var jetStreamInstance nats.JetStreamContext
+var natsNodeName string
+
+// NATSConfig controls how the node connects to NATS and how the fallback embedded server is started
+type NATSConfig struct {
+	URL          string
+	EmbeddedHost string
+	EmbeddedPort int
+	JetStream    bool
+	StoreDir     string
+	// Node names this node's durable consumers; it must differ between nodes sharing a NATS server
+	Node string
+}
+
+// StreamConfig describes a JetStream stream the node relies on
+type StreamConfig struct {
+	Name     string
+	Subjects []string
+	MaxAge   time.Duration
+}
+
+// DurableStreams are the JetStream streams created when JetStream is enabled
+var DurableStreams = []StreamConfig{
+	{Name: "DELIBERATION", Subjects: []string{"deliberation.>"}, MaxAge: 7 * 24 * time.Hour},
+	{Name: "VERDICTS", Subjects: []string{"verdict.>"}, MaxAge: 30 * 24 * time.Hour},
+	{Name: "EVENTS", Subjects: []string{"events.>"}, MaxAge: 24 * time.Hour},
+	{Name: "COMMENTS", Subjects: []string{"comments.>"}, MaxAge: 7 * 24 * time.Hour},
+}
+
+// DefaultNATSConfig returns the configuration used when no flags are supplied
+func DefaultNATSConfig() NATSConfig {
+	return NATSConfig{
+		URL:          "nats://localhost:4222",
+		EmbeddedHost: "localhost",
+		EmbeddedPort: 4222,
+		JetStream:    false,
+		StoreDir:     "data/jetstream",
+	}
+}
 
 // SetupNATS establishes a connection to NATS server or starts an embedded one if connection fails
-func SetupNATS(natsURL string) {
+func SetupNATS(config NATSConfig) {
+	natsNodeName = config.Node
 	var err error
-	NatsBrokerInstance, err = nats.Connect(natsURL)
+	NatsBrokerInstance, err = nats.Connect(config.URL)
 	if err != nil {
-		log.Printf("Could not connect to NATS at %s, starting embedded server...", natsURL)
+		log.Printf("Could not connect to NATS at %s, starting embedded server...", config.URL)
 		opts := &server.Options{
-			Port:   4222,
-			Host:   "localhost",
-			NoLog:  false,
-			NoSigs: true,
+			Port:      config.EmbeddedPort,
+			Host:      config.EmbeddedHost,
+			NoLog:     false,
+			NoSigs:    true,
+			JetStream: config.JetStream,
+			StoreDir:  config.StoreDir,
 		}
 
-		natsServer, _ = server.NewServer(opts)
+		natsServer, err = server.NewServer(opts)
+		if err != nil {
+			log.Fatalf("Failed to configure embedded NATS: %v", err)
+		}
 		go natsServer.Start()
 
 		if !natsServer.ReadyForConnections(4 * time.Second) {
 			log.Fatal("NATS server failed to start")
 		}
-		log.Println("Started embedded NATS server on port 4222")
+		log.Printf("Started embedded NATS server on %s:%d", config.EmbeddedHost, config.EmbeddedPort)
 
-		NatsBrokerInstance, err = nats.Connect("nats://localhost:4222")
+		NatsBrokerInstance, err = nats.Connect(natsServer.ClientURL())
 		if err != nil {
 			log.Fatalf("Failed to connect to embedded NATS: %v", err)
 		}
 	}
-	log.Printf("Connected to NATS at %s", natsURL)
+	log.Printf("Connected to NATS at %s", NatsBrokerInstance.ConnectedUrl())
+
+	if config.JetStream {
+		js, err := NatsBrokerInstance.JetStream()
+		if err != nil {
+			log.Printf("JetStream unavailable, continuing with core NATS: %v", err)
+			return
+		}
+		if err := EnsureStreams(js); err != nil {
+			log.Printf("Failed to create JetStream streams, continuing with core NATS: %v", err)
+			return
+		}
+		jetStreamInstance = js
+		log.Printf("JetStream enabled with %d durable streams", len(DurableStreams))
+	}
+}
+
+// EnsureStreams creates the durable streams or updates them if they already exist
+func EnsureStreams(js nats.JetStreamContext) error {
+	for _, stream := range DurableStreams {
+		cfg := &nats.StreamConfig{
+			Name:     stream.Name,
+			Subjects: stream.Subjects,
+			Storage:  nats.FileStorage,
+			MaxAge:   stream.MaxAge,
+		}
+		if _, err := js.StreamInfo(stream.Name); err == nil {
+			if _, err := js.UpdateStream(cfg); err != nil {
+				return fmt.Errorf("failed to update stream %s: %v", stream.Name, err)
+			}
+			continue
+		}
+		if _, err := js.AddStream(cfg); err != nil {
+			return fmt.Errorf("failed to add stream %s: %v", stream.Name, err)
+		}
+	}
+	return nil
+}
+
+// DefaultBroker wraps the node's shared NATS connection, or returns nil if NATS has not been set up
+func DefaultBroker() *NATSBroker {
+	if NatsBrokerInstance == nil {
+		return nil
+	}
+	return &NATSBroker{Conn: NatsBrokerInstance, JS: jetStreamInstance, Node: natsNodeName}
 }
 
 // CloseNATS closes the NATS connection and shuts down the server if it was embedded
@@ -52,6 +143,9 @@ func CloseNATS() {
 
 type NATSBroker struct {
 	Conn *nats.Conn
+	JS   nats.JetStreamContext
+	// Node names the durable consumers of SubscribeNode
+	Node string
 }
 
 // NewNATSBroker creates a new NATS broker instance with the specified URL
@@ -65,16 +159,115 @@ func NewNATSBroker(url string) (*NATSBroker, error) {
 	return &NATSBroker{Conn: nc}, nil
 }
 
-// Publish sends data to the specified NATS subject
+// EnableJetStream switches the broker to durable publishing for subjects covered by DurableStreams
+func (b *NATSBroker) EnableJetStream() error {
+	js, err := b.Conn.JetStream()
+	if err != nil {
+		return err
+	}
+	if err := EnsureStreams(js); err != nil {
+		return err
+	}
+	b.JS = js
+	return nil
+}
+
+// Durable reports whether subject is persisted in a JetStream stream on this broker
+func (b *NATSBroker) Durable(subject string) bool {
+	return b.JS != nil && streamFor(subject) != ""
+}
+
+// streamFor returns the name of the durable stream capturing subject, if any
+func streamFor(subject string) string {
+	for _, stream := range DurableStreams {
+		for _, pattern := range stream.Subjects {
+			if strings.HasSuffix(pattern, ">") && strings.HasPrefix(subject, strings.TrimSuffix(pattern, ">")) {
+				return stream.Name
+			}
+		}
+	}
+	return ""
+}
+
+// Publish sends data to the specified NATS subject, persisting it when the subject is durable
 func (b *NATSBroker) Publish(subject string, data []byte) error {
 	log.Printf("Sending data to %s", subject)
+	if b.Durable(subject) {
+		_, err := b.JS.Publish(subject, data)
+		return err
+	}
 	return b.Conn.Publish(subject, data)
 }
 
+// PublishDurable stores data in its JetStream stream and returns the assigned stream sequence
+func (b *NATSBroker) PublishDurable(subject string, data []byte) (uint64, error) {
+	if !b.Durable(subject) {
+		return 0, fmt.Errorf("subject %s is not backed by a durable stream", subject)
+	}
+	ack, err := b.JS.Publish(subject, data)
+	if err != nil {
+		return 0, err
+	}
+	return ack.Sequence, nil
+}
+
 // Subscribe registers a message handler for the specified subject
-func (b *NATSBroker) Subscribe(subject string, cb nats.MsgHandler) error {
-	_, err := b.Conn.Subscribe(subject, cb)
-	return err
+func (b *NATSBroker) Subscribe(subject string, cb nats.MsgHandler) (*nats.Subscription, error) {
+	return b.Conn.Subscribe(subject, cb)
+}
+
+// SubscribeDurable attaches a named durable consumer so messages published while offline are delivered on reconnect
+func (b *NATSBroker) SubscribeDurable(subject, durable string, cb nats.MsgHandler) (*nats.Subscription, error) {
+	if !b.Durable(subject) {
+		return nil, fmt.Errorf("subject %s is not backed by a durable stream", subject)
+	}
+	return b.JS.Subscribe(subject, func(msg *nats.Msg) {
+		cb(msg)
+		msg.Ack()
+	}, nats.Durable(durable), nats.ManualAck(), nats.AckExplicit(), nats.DeliverAll())
+}
+
+// SubscribeNode subscribes to subject through this node's durable consumer called name, so messages published
+// while the node was offline are delivered once it is back. Without JetStream or a node name it subscribes live.
+func (b *NATSBroker) SubscribeNode(subject, name string, cb nats.MsgHandler) (*nats.Subscription, error) {
+	if b.Node == "" || !b.Durable(subject) {
+		return b.Subscribe(subject, cb)
+	}
+	return b.SubscribeDurable(subject, consumerName(b.Node, name), cb)
+}
+
+// consumerName builds a durable consumer name, which may not contain dots, wildcards or whitespace
+func consumerName(node, name string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(node + "-" + name)
+}
+
+// ReplayFrom delivers every stored message on subject starting at the given stream sequence, then continues live
+func (b *NATSBroker) ReplayFrom(subject string, seq uint64, cb nats.MsgHandler) (*nats.Subscription, error) {
+	if !b.Durable(subject) {
+		return nil, fmt.Errorf("subject %s is not backed by a durable stream", subject)
+	}
+	if seq == 0 {
+		return b.JS.Subscribe(subject, cb, nats.OrderedConsumer(), nats.DeliverAll())
+	}
+	return b.JS.Subscribe(subject, cb, nats.OrderedConsumer(), nats.StartSequence(seq))
+}
+
+// Request sends data on subject and waits for a single reply
+func (b *NATSBroker) Request(subject string, data []byte, timeout time.Duration) ([]byte, error) {
+	msg, err := b.Conn.Request(subject, data, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Data, nil
+}
+
+// Reply answers every request on subject with the handler's result
+func (b *NATSBroker) Reply(subject string, handler func(data []byte) []byte) (*nats.Subscription, error) {
+	return b.Conn.Subscribe(subject, func(msg *nats.Msg) {
+		if err := msg.Respond(handler(msg.Data)); err != nil {
+			log.Printf("Failed to respond on %s: %v", subject, err)
+		}
+	})
 }
 
 // Close terminates the NATS connection
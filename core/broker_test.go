@@ -0,0 +1,106 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// startJetStream runs an embedded NATS server with JetStream on a free port for the duration of the test
+func startJetStream(t *testing.T) *server.Server {
+	t.Helper()
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		NoLog:     true,
+		NoSigs:    true,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("failed to configure NATS: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(4 * time.Second) {
+		t.Fatal("NATS server failed to start")
+	}
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+// connectNode connects a node to the server with JetStream enabled
+func connectNode(t *testing.T, srv *server.Server, node string) *NATSBroker {
+	t.Helper()
+	conn, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect to NATS: %v", err)
+	}
+	broker := &NATSBroker{Conn: conn, Node: node}
+	if err := broker.EnableJetStream(); err != nil {
+		t.Fatalf("failed to enable JetStream: %v", err)
+	}
+	t.Cleanup(broker.Close)
+	return broker
+}
+
+// receive waits for the next message delivered on messages
+func receive(t *testing.T, messages <-chan string) string {
+	t.Helper()
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message was delivered")
+		return ""
+	}
+}
+
+func TestSubscribeNodeDeliversMessagesMissedWhileOffline(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		filter  string
+	}{
+		{"verdicts", "verdict.test-chain.proposal", "verdict.test-chain.*"},
+		{"comments", "comments.test-chain.proposal", "comments.test-chain.*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startJetStream(t)
+			publisher := connectNode(t, srv, "publisher")
+
+			messages := make(chan string, 10)
+			handle := func(msg *nats.Msg) { messages <- string(msg.Data) }
+
+			node := connectNode(t, srv, "node-a")
+			if _, err := node.SubscribeNode(tt.filter, tt.name+"-test-chain", handle); err != nil {
+				t.Fatalf("failed to subscribe: %v", err)
+			}
+			if err := publisher.Publish(tt.subject, []byte("while online")); err != nil {
+				t.Fatalf("failed to publish: %v", err)
+			}
+			if got := receive(t, messages); got != "while online" {
+				t.Fatalf("received %q, want %q", got, "while online")
+			}
+			if err := node.Conn.Flush(); err != nil {
+				t.Fatalf("failed to flush the ack: %v", err)
+			}
+			node.Close()
+
+			if err := publisher.Publish(tt.subject, []byte("while offline")); err != nil {
+				t.Fatalf("failed to publish: %v", err)
+			}
+
+			restarted := connectNode(t, srv, "node-a")
+			if _, err := restarted.SubscribeNode(tt.filter, tt.name+"-test-chain", handle); err != nil {
+				t.Fatalf("failed to subscribe again: %v", err)
+			}
+			if got := receive(t, messages); got != "while offline" {
+				t.Errorf("received %q after coming back, want %q", got, "while offline")
+			}
+		})
+	}
+}
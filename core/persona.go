@@ -0,0 +1,249 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	RoleValidator = "validator"
+	RoleProducer  = "producer"
+)
+
+const (
+	MinPersonaTraits = 1
+	MaxPersonaTraits = 8
+)
+
+// personaIDRegex keeps agent IDs safe to use in file paths and NATS subjects
+var personaIDRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Persona is the declarative description of an agent: who it is and how it reasons. Settings holds any
+// further agent metadata, such as sampling or endpoint configuration, that is not part of the persona itself.
+type Persona struct {
+	ID             string                 `json:"id"`
+	Name           string                 `json:"name"`
+	Role           string                 `json:"role"`
+	Traits         []string               `json:"traits"`
+	Style          string                 `json:"style,omitempty"`
+	Influences     []string               `json:"influences,omitempty"`
+	Mood           string                 `json:"mood,omitempty"`
+	Specialization string                 `json:"specialization,omitempty"`
+	Language       string                 `json:"language,omitempty"`
+	Settings       map[string]interface{} `json:"settings,omitempty"`
+}
+
+// personaFields are the metadata keys that belong to the persona rather than its settings
+var personaFields = map[string]bool{
+	"id": true, "name": true, "role": true, "traits": true, "style": true, "influences": true,
+	"mood": true, "specialization": true, "language": true, "settings": true, "metadata": true,
+	"is_validator": true, "validator_address": true, "disabled": true, "persona_version": true,
+}
+
+// UnmarshalJSON accepts the persona schema as well as the capitalized keys of older generated files and the
+// registry's agent form, where persona fields are nested under "metadata"
+func (p *Persona) UnmarshalJSON(data []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{})
+	settings := make(map[string]interface{})
+	for key, value := range raw {
+		fields[strings.ToLower(key)] = value
+	}
+	for _, nested := range []string{"metadata", "settings"} {
+		if m, ok := fields[nested].(map[string]interface{}); ok {
+			for key, value := range m {
+				key = strings.ToLower(key)
+				if personaFields[key] {
+					if _, set := fields[key]; !set {
+						fields[key] = value
+					}
+				} else {
+					settings[key] = value
+				}
+			}
+		}
+	}
+	for key, value := range fields {
+		if !personaFields[key] {
+			settings[key] = value
+		}
+	}
+
+	*p = Persona{
+		ID:             stringField(fields["id"]),
+		Name:           stringField(fields["name"]),
+		Role:           stringField(fields["role"]),
+		Traits:         stringsField(fields["traits"]),
+		Style:          stringField(fields["style"]),
+		Influences:     stringsField(fields["influences"]),
+		Mood:           stringField(fields["mood"]),
+		Specialization: stringField(fields["specialization"]),
+		Language:       stringField(fields["language"]),
+	}
+	if len(settings) > 0 {
+		p.Settings = settings
+	}
+	return nil
+}
+
+func stringField(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v", v))
+}
+
+func stringsField(v interface{}) []string {
+	var out []string
+	switch items := v.(type) {
+	case []interface{}:
+		for _, item := range items {
+			out = append(out, stringField(item))
+		}
+	case []string:
+		for _, item := range items {
+			out = append(out, strings.TrimSpace(item))
+		}
+	case string:
+		for _, item := range strings.Split(items, ",") {
+			out = append(out, strings.TrimSpace(item))
+		}
+	}
+	return out
+}
+
+// Validate checks a single persona against the schema
+func (p Persona) Validate() error {
+	var errs []error
+	if !personaIDRegex.MatchString(p.ID) {
+		errs = append(errs, fmt.Errorf("id %q must be 1-64 letters, digits, dots, dashes or underscores", p.ID))
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		errs = append(errs, fmt.Errorf("name must not be empty"))
+	}
+	if p.Role != RoleValidator && p.Role != RoleProducer {
+		errs = append(errs, fmt.Errorf("role must be %q or %q, got %q", RoleValidator, RoleProducer, p.Role))
+	}
+	if len(p.Traits) < MinPersonaTraits || len(p.Traits) > MaxPersonaTraits {
+		errs = append(errs, fmt.Errorf("must have %d to %d traits, got %d", MinPersonaTraits, MaxPersonaTraits, len(p.Traits)))
+	}
+	seen := make(map[string]bool)
+	for _, trait := range p.Traits {
+		key := strings.ToLower(strings.TrimSpace(trait))
+		if key == "" {
+			errs = append(errs, fmt.Errorf("traits must not be empty"))
+		} else if seen[key] {
+			errs = append(errs, fmt.Errorf("trait %q is listed twice", trait))
+		}
+		seen[key] = true
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	label := p.ID
+	if label == "" {
+		label = p.Name
+	}
+	return fmt.Errorf("persona %q: %w", label, errors.Join(errs...))
+}
+
+// ValidatePersonas checks every persona and that IDs and names are unique across the set
+func ValidatePersonas(personas []Persona) error {
+	var errs []error
+	ids := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, p := range personas {
+		if err := p.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if ids[p.ID] {
+			errs = append(errs, fmt.Errorf("id %q is used by more than one persona", p.ID))
+		}
+		ids[p.ID] = true
+		name := strings.ToLower(strings.TrimSpace(p.Name))
+		if names[name] {
+			errs = append(errs, fmt.Errorf("name %q is used by more than one persona", p.Name))
+		}
+		names[name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// Agent converts the persona to the agent form stored in the registry. Lists are stored as []interface{},
+// the same shape they take when an agent is decoded from JSON.
+func (p Persona) Agent() Agent {
+	metadata := make(map[string]interface{})
+	for key, value := range p.Settings {
+		metadata[key] = value
+	}
+	metadata["traits"] = toInterfaces(p.Traits)
+	if len(p.Influences) > 0 {
+		metadata["influences"] = toInterfaces(p.Influences)
+	}
+	for key, value := range map[string]string{"style": p.Style, "mood": p.Mood, "specialization": p.Specialization, "language": p.Language} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+	return Agent{
+		ID:          p.ID,
+		Name:        p.Name,
+		Role:        p.Role,
+		IsValidator: p.Role == RoleValidator,
+		Metadata:    metadata,
+	}
+}
+
+func toInterfaces(items []string) []interface{} {
+	out := make([]interface{}, len(items))
+	for i, item := range items {
+		out[i] = item
+	}
+	return out
+}
+
+// PersonaFromAgent recovers the persona of a registered agent
+func PersonaFromAgent(agent Agent) Persona {
+	data, _ := json.Marshal(agent)
+	var p Persona
+	_ = json.Unmarshal(data, &p)
+	return p
+}
+
+// ParsePersonas decodes a JSON array of personas and validates the set
+func ParsePersonas(data []byte) ([]Persona, error) {
+	var personas []Persona
+	if err := json.Unmarshal(data, &personas); err != nil {
+		return nil, fmt.Errorf("invalid persona file: %v", err)
+	}
+	return personas, ValidatePersonas(personas)
+}
+
+// LoadPersonas reads and validates a persona file
+func LoadPersonas(path string) ([]Persona, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return ParsePersonas(data)
+}
+
+// SavePersonas validates personas and writes them to path in the persona schema
+func SavePersonas(path string, personas []Persona) error {
+	if err := ValidatePersonas(personas); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(personas, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal personas: %v", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
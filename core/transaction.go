@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/big"
 )
 
 // Transaction represents a basic transaction structure
@@ -24,6 +25,7 @@ type Transaction struct {
 	ChainID   string  `json:"chainID" amino:"bytes"`
 	Hash      []byte  `json:"hash" amino:"bytes"` // Transaction hash
 	Data      []byte  `json:"data" amino:"bytes"`
+	Nonce     uint64  `json:"nonce,omitempty" amino:"varint"` // Must grow with every paid transaction of the sender
 }
 
 // GenerateKeyPair creates a new key pair for signing transactions
@@ -34,7 +36,7 @@ func GenerateKeyPair() (*ecdsa.PrivateKey, error) {
 // SignTransaction signs a transaction with the given private key
 func (tx *Transaction) SignTransaction(privateKey *ecdsa.PrivateKey) error {
 	// Create hash of transaction data
-	hash := sha256.Sum256([]byte(fmt.Sprintf("%s%s%.8f%d%s%d%s", tx.From, tx.To, tx.Amount, tx.Fee, tx.Content, tx.Timestamp, tx.ChainID)))
+	hash := tx.signingHash()
 
 	// Sign the hash
 	r, s, err := ecdsa.Sign(rand.Reader, privateKey, hash[:])
@@ -42,8 +44,11 @@ func (tx *Transaction) SignTransaction(privateKey *ecdsa.PrivateKey) error {
 		return err
 	}
 
-	// Store signature and public key
-	tx.Signature = hex.EncodeToString(append(r.Bytes(), s.Bytes()...))
+	// Store signature as fixed-width r||s so it can be split again on verification
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	tx.Signature = hex.EncodeToString(sig)
 	tx.PublicKey = hex.EncodeToString(elliptic.MarshalCompressed(privateKey.PublicKey.Curve, privateKey.PublicKey.X, privateKey.PublicKey.Y))
 
 	return nil
@@ -60,6 +65,47 @@ func (tx *Transaction) VerifyTransaction(from string) bool {
 	return tx.From == from
 }
 
+// VerifySignature checks the ECDSA signature against the public key carried in the transaction
+func (tx *Transaction) VerifySignature() bool {
+	pubBytes, err := hex.DecodeString(tx.PublicKey)
+	if err != nil {
+		return false
+	}
+	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), pubBytes)
+	if x == nil {
+		return false
+	}
+
+	sig, err := hex.DecodeString(tx.Signature)
+	if err != nil || len(sig) != 64 {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+
+	hash := tx.signingHash()
+	return ecdsa.Verify(&ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, hash[:], r, s)
+}
+
+// signingHash returns the digest covered by the transaction signature: every field but the signature, the public
+// key and the hash, encoded as JSON so no field can run into the next. The type and data are always covered, so a
+// signed transaction cannot be turned into another kind or carry another payload.
+func (tx *Transaction) signingHash() [32]byte {
+	signed, _ := json.Marshal(struct {
+		Type      string  `json:"type"`
+		From      string  `json:"from"`
+		To        string  `json:"to"`
+		Amount    float64 `json:"amount"`
+		Fee       uint64  `json:"fee"`
+		Content   string  `json:"content"`
+		Timestamp int64   `json:"timestamp"`
+		ChainID   string  `json:"chainID"`
+		Data      []byte  `json:"data"`
+		Nonce     uint64  `json:"nonce"`
+	}{tx.Type, tx.From, tx.To, tx.Amount, tx.Fee, tx.Content, tx.Timestamp, tx.ChainID, tx.Data, tx.Nonce})
+	return sha256.Sum256(signed)
+}
+
 func (tx *Transaction) GetHash() []byte {
 	if len(tx.Hash) == 0 {
 		// Calculate hash excluding the signature fields
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSignatureCoversEveryField(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(tx *Transaction)
+	}{
+		{"type", func(tx *Transaction) { tx.Type = "register_validator" }},
+		{"data", func(tx *Transaction) { tx.Data = []byte("another validator key") }},
+		{"content", func(tx *Transaction) { tx.Content = "something else" }},
+		{"recipient", func(tx *Transaction) { tx.To = "0xattacker" }},
+		{"amount", func(tx *Transaction) { tx.Amount = 1000 }},
+		{"fee", func(tx *Transaction) { tx.Fee = 0 }},
+		{"nonce", func(tx *Transaction) { tx.Nonce++ }},
+		{"chain", func(tx *Transaction) { tx.ChainID = "other-chain" }},
+		{"content into recipient", func(tx *Transaction) { tx.To, tx.Content = tx.To+"h", tx.Content[1:] }},
+	}
+
+	key, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	for _, nonce := range []uint64{0, 1} {
+		signed := Transaction{
+			Type:      "discuss_transaction",
+			From:      AccountAddressOf(key),
+			To:        "0xrecipient",
+			Amount:    5,
+			Fee:       1,
+			Content:   "hello validators",
+			Timestamp: 1700000000,
+			ChainID:   "test-chain",
+			Data:      []byte("payload"),
+			Nonce:     nonce,
+		}
+		if err := signed.SignTransaction(key); err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		if !signed.VerifySignature() {
+			t.Fatalf("untouched transaction with nonce %d does not verify", nonce)
+		}
+
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s with nonce %d", tt.name, nonce), func(t *testing.T) {
+				tx := signed
+				tx.Data = append([]byte{}, signed.Data...)
+				tt.tamper(&tx)
+				if tx.VerifySignature() {
+					t.Errorf("signature still verifies after changing the %s", tt.name)
+				}
+			})
+		}
+	}
+}
@@ -3,6 +3,8 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+
+	cmttypes "github.com/cometbft/cometbft/types"
 )
 
 // EncodeTx converts a transaction to bytes
@@ -30,3 +32,8 @@ func ValidateTx(tx Transaction) error {
 	}
 	return nil
 }
+
+// ProposalID returns the identifier of a proposal, which is the CometBFT hash of its raw transaction bytes
+func ProposalID(txBytes []byte) string {
+	return fmt.Sprintf("%X", cmttypes.Tx(txBytes).Hash())
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cometbft/cometbft/crypto"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+// TxAgentVerdict puts a validator's agent verdict on a committed proposal on chain, where it earns a share of the
+// proposal's fee
+const TxAgentVerdict = "agent_verdict"
+
+// VerdictAttestation is the content of an agent_verdict transaction. It is signed with the validator key, so a
+// validator can only attest the verdict of the agent bound to it.
+type VerdictAttestation struct {
+	ProposalID      string  `json:"proposal_id"`
+	AgentID         string  `json:"agent_id"`
+	Decision        string  `json:"decision"`
+	Confidence      float64 `json:"confidence"`
+	ValidatorPubKey []byte  `json:"validator_pub_key"`
+	Signature       []byte  `json:"signature"`
+}
+
+// ValidatorAddress returns the address of the validator attesting the verdict
+func (a VerdictAttestation) ValidatorAddress() string {
+	return ed25519.PubKey(a.ValidatorPubKey).Address().String()
+}
+
+// signBytes is what the validator key signs: the chain, the proposal, the agent and its decision
+func (a VerdictAttestation) signBytes(chainID string) []byte {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%.4f|%X", chainID, a.ProposalID, a.AgentID, a.Decision, a.Confidence, a.ValidatorPubKey)))
+	return sum[:]
+}
+
+// NewVerdictAttestation builds an agent_verdict transaction signed with the validator key
+func NewVerdictAttestation(chainID, proposalID, agentID, decision string, confidence float64, privKey crypto.PrivKey) (Transaction, error) {
+	attestation := VerdictAttestation{
+		ProposalID:      proposalID,
+		AgentID:         agentID,
+		Decision:        decision,
+		Confidence:      confidence,
+		ValidatorPubKey: privKey.PubKey().Bytes(),
+	}
+	signature, err := privKey.Sign(attestation.signBytes(chainID))
+	if err != nil {
+		return Transaction{}, fmt.Errorf("failed to sign verdict attestation: %v", err)
+	}
+	attestation.Signature = signature
+
+	content, err := json.Marshal(attestation)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("failed to encode verdict attestation: %v", err)
+	}
+	return Transaction{
+		Type:      TxAgentVerdict,
+		From:      attestation.ValidatorAddress(),
+		Content:   string(content),
+		Timestamp: time.Now().Unix(),
+		ChainID:   chainID,
+	}, nil
+}
+
+// ParseVerdictAttestation decodes an agent_verdict transaction and checks the validator signature
+func ParseVerdictAttestation(tx Transaction) (VerdictAttestation, error) {
+	var attestation VerdictAttestation
+	if err := json.Unmarshal([]byte(tx.Content), &attestation); err != nil {
+		return VerdictAttestation{}, fmt.Errorf("invalid attestation format: %v", err)
+	}
+	if len(attestation.ValidatorPubKey) != ed25519.PubKeySize {
+		return VerdictAttestation{}, fmt.Errorf("validator public key must be %d bytes", ed25519.PubKeySize)
+	}
+	if attestation.ProposalID == "" || attestation.AgentID == "" || attestation.Decision == "" {
+		return VerdictAttestation{}, fmt.Errorf("proposal_id, agent_id and decision are required")
+	}
+	if tx.From != attestation.ValidatorAddress() {
+		return VerdictAttestation{}, fmt.Errorf("sender %s is not the attesting validator %s", tx.From, attestation.ValidatorAddress())
+	}
+	if !ed25519.PubKey(attestation.ValidatorPubKey).VerifySignature(attestation.signBytes(tx.ChainID), attestation.Signature) {
+		return VerdictAttestation{}, fmt.Errorf("signature verification failed")
+	}
+	return attestation, nil
+}
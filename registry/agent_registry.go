@@ -8,78 +8,140 @@ import (
 	"sync"
 
 	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/secrets"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 var (
 	agentMutex   sync.RWMutex
 	registryFile = "data/agent_registry.json"
-	registry     *AgentRegistry
 )
 
+// AgentRegistry is the layout of the JSON registry file used before the registry moved to LevelDB. It is only
+// read to migrate that file.
 type AgentRegistry struct {
 	Agents       map[string]map[string]core.Agent
 	ValidatorMap map[string]map[string]string
+	Versions     map[string]map[string][]PersonaVersion
 }
 
-// Initializes or loads the registry from file
+// Opens the registry database, migrating the legacy JSON registry on first use
 func InitRegistry() {
 	agentMutex.Lock()
 	defer agentMutex.Unlock()
 
-	if err := os.MkdirAll(filepath.Dir(registryFile), 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(registryDBPath), 0755); err != nil {
 		log.Printf("Failed to create registry directory: %v", err)
-		return
 	}
+	if db != nil {
+		db.Close()
+	}
+	db = openStore()
+	migrateLegacyRegistry()
+
+	agents := 0
+	iter := db.NewIterator(util.BytesPrefix([]byte(agentPrefix)), nil)
+	for iter.Next() {
+		agents++
+	}
+	iter.Release()
+	log.Printf("Registry initialized with %d agents", agents)
+}
+
+// CloseRegistry flushes and closes the registry database
+func CloseRegistry() error {
+	agentMutex.Lock()
+	defer agentMutex.Unlock()
 
-	registry = loadRegistry()
-	log.Printf("Registry initialized with %d agents", len(registry.Agents))
+	if db == nil {
+		return nil
+	}
+	err := db.Close()
+	db = nil
+	return err
 }
 
-// Loads registry from file or creates new one if file doesn't exist
+// Loads the legacy JSON registry file
 func loadRegistry() *AgentRegistry {
+	r := &AgentRegistry{
+		Agents:       make(map[string]map[string]core.Agent),
+		ValidatorMap: make(map[string]map[string]string),
+		Versions:     make(map[string]map[string][]PersonaVersion),
+	}
+
 	data, err := os.ReadFile(registryFile)
 	if err != nil {
-		return &AgentRegistry{
-			Agents:       make(map[string]map[string]core.Agent),
-			ValidatorMap: make(map[string]map[string]string),
-		}
+		return r
 	}
 
-	var r AgentRegistry
-	if err := json.Unmarshal(data, &r); err != nil {
+	if err := json.Unmarshal(data, r); err != nil {
 		log.Printf("Failed to unmarshal registry: %v", err)
 		return &AgentRegistry{
 			Agents:       make(map[string]map[string]core.Agent),
 			ValidatorMap: make(map[string]map[string]string),
+			Versions:     make(map[string]map[string][]PersonaVersion),
 		}
 	}
+	if r.Versions == nil {
+		r.Versions = make(map[string]map[string][]PersonaVersion)
+	}
+	seedVersions(r)
 
-	return &r
+	return r
 }
 
-// Saves current registry state to file
-func saveRegistry() {
-	data, err := json.MarshalIndent(registry, "", "  ")
-	if err != nil {
-		log.Printf("Failed to marshal registry: %v", err)
-		return
-	}
-
-	if err := os.WriteFile(registryFile, data, 0644); err != nil {
-		log.Printf("Failed to save registry: %v", err)
+// sealRegistry encrypts plaintext secrets left in a registry written before secrets were sealed, and strips them
+// from its persona history
+func sealRegistry(r *AgentRegistry) {
+	for chainID, agents := range r.Agents {
+		for agentID, agent := range agents {
+			if err := secrets.SealMetadata(agent.Metadata); err != nil {
+				log.Printf("Failed to seal secrets of agent %s: %v", agentID, err)
+			}
+		}
+		for _, versions := range r.Versions[chainID] {
+			for i := range versions {
+				versions[i].Persona = secrets.RedactPersona(versions[i].Persona)
+			}
+		}
 	}
 }
 
-// Registers a new agent in the registry for a specific chain
+// Registers a new agent in the registry for a specific chain. Re-registering an agent with a changed persona
+// records a new persona version effective from the next block instead of overwriting the old one.
 func RegisterAgent(chainID string, agent core.Agent) {
 	agentMutex.Lock()
 	defer agentMutex.Unlock()
 
-	if registry.Agents[chainID] == nil {
-		registry.Agents[chainID] = make(map[string]core.Agent)
+	if err := secrets.SealMetadata(agent.Metadata); err != nil {
+		log.Printf("Failed to seal secrets of agent %s: %v", agent.ID, err)
+	}
+	existing, exists := loadAgent(chainID, agent.ID)
+	var previous *core.Agent
+	if exists {
+		previous = &existing
+		agent.ValidatorAddress = existing.ValidatorAddress
+		agent.IsValidator = agent.IsValidator || existing.IsValidator
+		agent.Disabled = existing.Disabled
+		for key, value := range existing.Metadata {
+			if _, replaced := agent.Metadata[key]; !replaced && secrets.IsSecretKey(key) {
+				agent.Metadata[key] = value
+			}
+		}
+	}
+
+	batch := new(leveldb.Batch)
+	if err := putAgent(batch, chainID, previous, agent); err != nil {
+		log.Printf("Failed to register agent %s: %v", agent.ID, err)
+		return
+	}
+	if _, err := addVersion(batch, chainID, agent.ID, core.PersonaFromAgent(agent), 0); err != nil {
+		log.Printf("Failed to record persona version of agent %s: %v", agent.ID, err)
+	}
+	if err := writeBatch(batch); err != nil {
+		log.Printf("Failed to save agent %s: %v", agent.ID, err)
 	}
-	registry.Agents[chainID][agent.ID] = agent
-	saveRegistry()
 }
 
 // Links an agent to a validator address and updates its status
@@ -87,36 +149,34 @@ func LinkAgentToValidator(chainID string, agentID string, validatorAddr string)
 	agentMutex.Lock()
 	defer agentMutex.Unlock()
 
-	if registry.ValidatorMap[chainID] == nil {
-		registry.ValidatorMap[chainID] = make(map[string]string)
-	}
-
-	registry.ValidatorMap[chainID][validatorAddr] = agentID
+	batch := new(leveldb.Batch)
+	batch.Put(validatorKey(chainID, validatorAddr), []byte(agentID))
 
-	if agents, exists := registry.Agents[chainID]; exists {
-		if agent, exists := agents[agentID]; exists {
-			agent.IsValidator = true
-			agent.ValidatorAddress = validatorAddr
-			agents[agentID] = agent
+	if agent, exists := loadAgent(chainID, agentID); exists {
+		previous := agent
+		agent.IsValidator = true
+		agent.ValidatorAddress = validatorAddr
+		if err := putAgent(batch, chainID, &previous, agent); err != nil {
+			log.Printf("Failed to link agent %s: %v", agentID, err)
+			return false
 		}
 	}
 
-	saveRegistry()
+	if err := writeBatch(batch); err != nil {
+		log.Printf("Failed to link agent %s to validator %s: %v", agentID, validatorAddr, err)
+		return false
+	}
 	return true
 }
 
-// Retrieves agent information for a given validator address
+// Retrieves agent information for a given validator address. Disabled agents are not returned.
 func GetAgentByValidator(chainID string, validatorAddr string) (core.Agent, bool) {
 	agentMutex.RLock()
 	defer agentMutex.RUnlock()
 
-	if validatorMap, exists := registry.ValidatorMap[chainID]; exists {
-		if agentID, exists := validatorMap[validatorAddr]; exists {
-			if agents, exists := registry.Agents[chainID]; exists {
-				if agent, exists := agents[agentID]; exists {
-					return agent, true
-				}
-			}
+	if agentID, exists := validatorAgent(chainID, validatorAddr); exists {
+		if agent, exists := loadAgent(chainID, agentID); exists && !agent.Disabled {
+			return effectiveAgent(chainID, agent), true
 		}
 	}
 	return core.Agent{}, false
@@ -128,24 +188,40 @@ func GetAllAgents(chainID string) []core.Agent {
 	defer agentMutex.RUnlock()
 
 	agents := make([]core.Agent, 0)
-	if chainAgents, exists := registry.Agents[chainID]; exists {
-		for _, agent := range chainAgents {
-			agents = append(agents, agent)
+	for _, agent := range scanJSON[core.Agent](agentPrefix + chainID + "/") {
+		agents = append(agents, effectiveAgent(chainID, agent))
+	}
+	return agents
+}
+
+// Returns the agents of a chain that have a role, using the role index
+func GetAgentsByRole(chainID string, role string) []core.Agent {
+	agentMutex.RLock()
+	defer agentMutex.RUnlock()
+
+	prefix := string(roleKey(chainID, role, ""))
+	agents := make([]core.Agent, 0)
+	iter := db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		if agent, exists := loadAgent(chainID, string(iter.Key()[len(prefix):])); exists {
+			agents = append(agents, effectiveAgent(chainID, agent))
 		}
 	}
 	return agents
 }
 
-// Returns all validator-agent mappings for a specific chain
+// Returns all validator-agent mappings for a specific chain, keyed by validator address
 func GetAllValidatorAgentMappings(chainID string) map[string]string {
 	agentMutex.RLock()
 	defer agentMutex.RUnlock()
 
+	prefix := string(validatorKey(chainID, ""))
 	result := make(map[string]string)
-	if chainAgents, exists := registry.Agents[chainID]; exists {
-		for valAddr, agent := range chainAgents {
-			result[valAddr] = agent.ID
-		}
+	iter := db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		result[string(iter.Key()[len(prefix):])] = string(iter.Value())
 	}
 	return result
 }
@@ -0,0 +1,272 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/secrets"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// ErrAgentNotFound is returned when an agent is not registered on a chain
+var ErrAgentNotFound = errors.New("agent not found")
+
+// ErrNoSecret is returned by AgentSecret for agents that do not carry the requested secret
+var ErrNoSecret = errors.New("agent has no such secret")
+
+// PersonaVersion is one revision of an agent's persona and the block height from which it applies. Secret settings
+// are redacted from the history; the agent's current secrets apply to every version.
+type PersonaVersion struct {
+	Version         int          `json:"version"`
+	Persona         core.Persona `json:"persona"`
+	EffectiveHeight int64        `json:"effective_height"`
+	CreatedAt       int64        `json:"created_at"`
+}
+
+// chainHeights tracks the height each chain is proposing or executing, so persona versions can be resolved against it
+var chainHeights = make(map[string]int64)
+
+// SetChainHeight records the height of the block a chain is proposing or executing
+func SetChainHeight(chainID string, height int64) {
+	agentMutex.Lock()
+	defer agentMutex.Unlock()
+	chainHeights[chainID] = height
+}
+
+// ChainHeight returns the height of the block a chain is executing, or 0 before the first block
+func ChainHeight(chainID string) int64 {
+	agentMutex.RLock()
+	defer agentMutex.RUnlock()
+	return chainHeights[chainID]
+}
+
+// seedVersions gives every agent of a registry written before persona versioning its current persona as version 1
+func seedVersions(r *AgentRegistry) {
+	for chainID, agents := range r.Agents {
+		if r.Versions[chainID] == nil {
+			r.Versions[chainID] = make(map[string][]PersonaVersion)
+		}
+		for agentID, agent := range agents {
+			if len(r.Versions[chainID][agentID]) == 0 {
+				r.Versions[chainID][agentID] = []PersonaVersion{{Version: 1, Persona: secrets.RedactPersona(core.PersonaFromAgent(agent)), CreatedAt: time.Now().Unix()}}
+			}
+		}
+	}
+}
+
+// addVersion stages a new persona version unless the persona is unchanged. An effective height of 0 means the
+// first version applies at once and later versions from the next block. Callers must hold agentMutex.
+func addVersion(batch *leveldb.Batch, chainID, agentID string, persona core.Persona, effectiveHeight int64) (PersonaVersion, error) {
+	versions := loadVersions(chainID, agentID)
+	persona.ID = agentID
+	persona = secrets.RedactPersona(persona)
+
+	if len(versions) > 0 {
+		latest := versions[len(versions)-1]
+		if samePersona(latest.Persona, persona) {
+			return latest, nil
+		}
+		if effectiveHeight == 0 {
+			effectiveHeight = chainHeights[chainID] + 1
+		}
+		if effectiveHeight <= chainHeights[chainID] {
+			return PersonaVersion{}, fmt.Errorf("effective height %d has already been reached", effectiveHeight)
+		}
+		if effectiveHeight < latest.EffectiveHeight {
+			return PersonaVersion{}, fmt.Errorf("effective height %d precedes version %d at height %d", effectiveHeight, latest.Version, latest.EffectiveHeight)
+		}
+	} else if effectiveHeight == 0 {
+		effectiveHeight = chainHeights[chainID]
+	}
+
+	version := PersonaVersion{
+		Version:         len(versions) + 1,
+		Persona:         persona,
+		EffectiveHeight: effectiveHeight,
+		CreatedAt:       time.Now().Unix(),
+	}
+	if err := putVersion(batch, chainID, agentID, version); err != nil {
+		return PersonaVersion{}, err
+	}
+	return version, nil
+}
+
+// samePersona compares personas by their serialized form
+func samePersona(a, b core.Persona) bool {
+	left, _ := json.Marshal(a)
+	right, _ := json.Marshal(b)
+	return string(left) == string(right)
+}
+
+// effectiveAgent applies the persona version in effect at the chain's current height to a stored agent.
+// Callers must hold agentMutex.
+func effectiveAgent(chainID string, agent core.Agent) core.Agent {
+	versions := loadVersions(chainID, agent.ID)
+	if len(versions) == 0 {
+		return agent
+	}
+
+	current := versions[0]
+	for _, version := range versions {
+		if version.EffectiveHeight <= chainHeights[chainID] {
+			current = version
+		}
+	}
+
+	resolved := current.Persona.Agent()
+	resolved.ID = agent.ID
+	resolved.ValidatorAddress = agent.ValidatorAddress
+	resolved.IsValidator = resolved.IsValidator || agent.IsValidator
+	resolved.Disabled = agent.Disabled
+	resolved.PersonaVersion = current.Version
+	for key, value := range agent.Metadata {
+		if secrets.IsSecretKey(key) {
+			resolved.Metadata[key] = value
+		}
+	}
+	return resolved
+}
+
+// GetAgent returns an agent with the persona version in effect at the chain's current height
+func GetAgent(chainID string, agentID string) (core.Agent, bool) {
+	agentMutex.RLock()
+	defer agentMutex.RUnlock()
+
+	agent, exists := loadAgent(chainID, agentID)
+	if !exists {
+		return core.Agent{}, false
+	}
+	return effectiveAgent(chainID, agent), true
+}
+
+// UpdateAgentPersona records a new persona version for an agent, effective from the given height or, when 0,
+// from the next block
+func UpdateAgentPersona(chainID string, agentID string, persona core.Persona, effectiveHeight int64) (PersonaVersion, error) {
+	agentMutex.Lock()
+	defer agentMutex.Unlock()
+
+	agent, exists := loadAgent(chainID, agentID)
+	if !exists {
+		return PersonaVersion{}, ErrAgentNotFound
+	}
+	persona.ID = agentID
+	if err := persona.Validate(); err != nil {
+		return PersonaVersion{}, err
+	}
+	if err := secrets.SealMetadata(persona.Settings); err != nil {
+		return PersonaVersion{}, fmt.Errorf("failed to seal secrets: %v", err)
+	}
+
+	batch := new(leveldb.Batch)
+	version, err := addVersion(batch, chainID, agentID, persona, effectiveHeight)
+	if err != nil {
+		return PersonaVersion{}, err
+	}
+	updated := persona.Agent()
+	updated.ValidatorAddress = agent.ValidatorAddress
+	updated.IsValidator = updated.IsValidator || agent.IsValidator
+	updated.Disabled = agent.Disabled
+	for key, value := range agent.Metadata {
+		if _, replaced := updated.Metadata[key]; !replaced && secrets.IsSecretKey(key) {
+			updated.Metadata[key] = value
+		}
+	}
+	if err := putAgent(batch, chainID, &agent, updated); err != nil {
+		return PersonaVersion{}, err
+	}
+	if err := writeBatch(batch); err != nil {
+		return PersonaVersion{}, fmt.Errorf("failed to save agent %s: %v", agentID, err)
+	}
+	return version, nil
+}
+
+// SetAgentDisabled disables or re-enables an agent. A disabled agent keeps its registration and history but no
+// longer deliberates for its validator.
+func SetAgentDisabled(chainID string, agentID string, disabled bool) error {
+	agentMutex.Lock()
+	defer agentMutex.Unlock()
+
+	agent, exists := loadAgent(chainID, agentID)
+	if !exists {
+		return ErrAgentNotFound
+	}
+	previous := agent
+	agent.Disabled = disabled
+
+	batch := new(leveldb.Batch)
+	if err := putAgent(batch, chainID, &previous, agent); err != nil {
+		return err
+	}
+	return writeBatch(batch)
+}
+
+// DeleteAgent removes an agent, its role index entry and its validator links. Its persona versions are kept so
+// that verdicts it produced can still be traced to the persona behind them.
+func DeleteAgent(chainID string, agentID string) error {
+	agentMutex.Lock()
+	defer agentMutex.Unlock()
+
+	agent, exists := loadAgent(chainID, agentID)
+	if !exists {
+		return ErrAgentNotFound
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Delete(agentKey(chainID, agentID))
+	batch.Delete(roleKey(chainID, agent.Role, agentID))
+	prefix := validatorKey(chainID, "")
+	iter := db.NewIterator(util.BytesPrefix(prefix), nil)
+	for iter.Next() {
+		if string(iter.Value()) == agentID {
+			batch.Delete(append([]byte(nil), iter.Key()...))
+		}
+	}
+	iter.Release()
+	return writeBatch(batch)
+}
+
+// AgentSecret resolves a secret from an agent's metadata, such as the api_key its LLM calls authenticate with.
+// Secrets are never returned by the other registry accessors in a usable form.
+func AgentSecret(chainID string, agentID string, key string) (string, error) {
+	agentMutex.RLock()
+	var agent core.Agent
+	exists := false
+	if db != nil {
+		agent, exists = loadAgent(chainID, agentID)
+	}
+	agentMutex.RUnlock()
+	if !exists {
+		return "", ErrAgentNotFound
+	}
+
+	value, ok := agent.Metadata[key].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: %s of agent %s", ErrNoSecret, key, agentID)
+	}
+	return secrets.Resolve(value)
+}
+
+// GetPersonaVersions returns every persona version recorded for an agent, oldest first
+func GetPersonaVersions(chainID string, agentID string) []PersonaVersion {
+	agentMutex.RLock()
+	defer agentMutex.RUnlock()
+	return loadVersions(chainID, agentID)
+}
+
+// GetPersonaVersion returns one persona version of an agent
+func GetPersonaVersion(chainID string, agentID string, version int) (PersonaVersion, bool) {
+	agentMutex.RLock()
+	defer agentMutex.RUnlock()
+
+	var v PersonaVersion
+	exists, err := getJSON(versionKey(chainID, agentID, version), &v)
+	if err != nil {
+		log.Printf("Failed to read persona version %d of agent %s: %v", version, agentID, err)
+	}
+	return v, exists && err == nil
+}
@@ -0,0 +1,205 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// The registry lives in an embedded LevelDB under these key spaces. Every mutation is written as one synced
+// batch, so an agent, its indexes and its persona history never disagree on disk.
+//
+//	agent/<chain>/<agent>                agent JSON
+//	role/<chain>/<role>/<agent>          index of agents by role
+//	validator/<chain>/<validator addr>   agent linked to a validator address
+//	version/<chain>/<agent>/<version>    persona versions, zero-padded so they sort in order
+//	meta/migrated                        the legacy JSON file the registry was migrated from
+const (
+	agentPrefix     = "agent/"
+	rolePrefix      = "role/"
+	validatorPrefix = "validator/"
+	versionPrefix   = "version/"
+	migratedKey     = "meta/migrated"
+)
+
+var (
+	registryDBPath = filepath.Join("data", "registry.db")
+	db             *leveldb.DB
+)
+
+func agentKey(chainID, agentID string) []byte {
+	return []byte(agentPrefix + chainID + "/" + agentID)
+}
+
+func roleKey(chainID, role, agentID string) []byte {
+	return []byte(rolePrefix + chainID + "/" + role + "/" + agentID)
+}
+
+func validatorKey(chainID, validatorAddr string) []byte {
+	return []byte(validatorPrefix + chainID + "/" + validatorAddr)
+}
+
+func versionKey(chainID, agentID string, version int) []byte {
+	return []byte(fmt.Sprintf("%s%s/%s/%010d", versionPrefix, chainID, agentID, version))
+}
+
+// openStore opens the registry database. When it cannot be opened, most often because another process holds
+// it, the registry runs in memory so that the node still starts.
+func openStore() *leveldb.DB {
+	store, err := leveldb.OpenFile(registryDBPath, nil)
+	if err == nil {
+		return store
+	}
+	if errors.Is(err, storage.ErrLocked) {
+		log.Printf("Registry database %s is in use by another process, using an in-memory registry", registryDBPath)
+	} else {
+		log.Printf("Failed to open registry database %s, using an in-memory registry: %v", registryDBPath, err)
+	}
+	store, err = leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		log.Fatalf("Failed to open in-memory registry: %v", err)
+	}
+	return store
+}
+
+// writeBatch applies a batch atomically and durably
+func writeBatch(batch *leveldb.Batch) error {
+	return db.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+// getJSON decodes the value under key into v, reporting whether the key exists
+func getJSON(key []byte, v interface{}) (bool, error) {
+	data, err := db.Get(key, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// scanJSON decodes every value under a key prefix in key order
+func scanJSON[T any](prefix string) []T {
+	var out []T
+	iter := db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		var v T
+		if err := json.Unmarshal(iter.Value(), &v); err != nil {
+			log.Printf("Skipping malformed registry entry %s: %v", iter.Key(), err)
+			continue
+		}
+		out = append(out, v)
+	}
+	if err := iter.Error(); err != nil {
+		log.Printf("Failed to scan registry entries under %s: %v", prefix, err)
+	}
+	return out
+}
+
+// loadAgent reads a stored agent
+func loadAgent(chainID, agentID string) (core.Agent, bool) {
+	var agent core.Agent
+	exists, err := getJSON(agentKey(chainID, agentID), &agent)
+	if err != nil {
+		log.Printf("Failed to read agent %s: %v", agentID, err)
+		return core.Agent{}, false
+	}
+	return agent, exists
+}
+
+// putAgent stages an agent and its role index, dropping the index entry of the role it had before
+func putAgent(batch *leveldb.Batch, chainID string, previous *core.Agent, agent core.Agent) error {
+	data, err := json.Marshal(agent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent %s: %v", agent.ID, err)
+	}
+	if previous != nil && previous.Role != agent.Role {
+		batch.Delete(roleKey(chainID, previous.Role, agent.ID))
+	}
+	batch.Put(agentKey(chainID, agent.ID), data)
+	batch.Put(roleKey(chainID, agent.Role, agent.ID), nil)
+	return nil
+}
+
+// loadVersions reads an agent's persona history, oldest first
+func loadVersions(chainID, agentID string) []PersonaVersion {
+	return scanJSON[PersonaVersion](versionPrefix + chainID + "/" + agentID + "/")
+}
+
+// putVersion stages a persona version
+func putVersion(batch *leveldb.Batch, chainID, agentID string, version PersonaVersion) error {
+	data, err := json.Marshal(version)
+	if err != nil {
+		return fmt.Errorf("failed to marshal persona version: %v", err)
+	}
+	batch.Put(versionKey(chainID, agentID, version.Version), data)
+	return nil
+}
+
+// validatorAgent returns the agent linked to a validator address
+func validatorAgent(chainID, validatorAddr string) (string, bool) {
+	data, err := db.Get(validatorKey(chainID, validatorAddr), nil)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// migrateLegacyRegistry imports the JSON registry written by earlier releases in a single batch and renames the
+// file so that it is not imported again
+func migrateLegacyRegistry() {
+	if done, _ := db.Has([]byte(migratedKey), nil); done {
+		return
+	}
+	if _, err := os.Stat(registryFile); err != nil {
+		return
+	}
+
+	legacy := loadRegistry()
+	sealRegistry(legacy)
+
+	batch := new(leveldb.Batch)
+	agents := 0
+	for chainID, chainAgents := range legacy.Agents {
+		for _, agent := range chainAgents {
+			if err := putAgent(batch, chainID, nil, agent); err != nil {
+				log.Printf("Skipping agent %s during migration: %v", agent.ID, err)
+				continue
+			}
+			agents++
+		}
+		for agentID, versions := range legacy.Versions[chainID] {
+			for _, version := range versions {
+				if err := putVersion(batch, chainID, agentID, version); err != nil {
+					log.Printf("Skipping persona version of agent %s during migration: %v", agentID, err)
+				}
+			}
+		}
+	}
+	for chainID, validators := range legacy.ValidatorMap {
+		for validatorAddr, agentID := range validators {
+			batch.Put(validatorKey(chainID, validatorAddr), []byte(agentID))
+		}
+	}
+	batch.Put([]byte(migratedKey), []byte(registryFile))
+
+	if err := writeBatch(batch); err != nil {
+		log.Printf("Failed to migrate %s: %v", registryFile, err)
+		return
+	}
+	if err := os.Rename(registryFile, registryFile+".migrated"); err != nil {
+		log.Printf("Migrated %s but could not rename it: %v", registryFile, err)
+	}
+	log.Printf("Migrated %d agents from %s", agents, registryFile)
+}
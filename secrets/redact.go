@@ -0,0 +1,128 @@
+package secrets
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/core"
+)
+
+// minRegisteredLength keeps short values, which would match ordinary text, out of the known secrets
+const minRegisteredLength = 8
+
+var (
+	knownMu sync.RWMutex
+	known   = make(map[string]bool)
+
+	// keyPatterns match API keys by their well-known shapes, for keys that were never registered
+	keyPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`sk-[A-Za-z0-9_-]{16,}`),
+		regexp.MustCompile(`(?i)("?(?:api_?key|secret|token|password)"?\s*[:=]\s*"?)([^\s",}]{8,})`),
+	}
+)
+
+// Register adds a plaintext secret to the values scrubbed from logs and redacted from prompts
+func Register(value string) {
+	if len(value) < minRegisteredLength {
+		return
+	}
+	knownMu.Lock()
+	defer knownMu.Unlock()
+	known[value] = true
+}
+
+// Contains reports whether text includes a known secret or an OpenAI style key. Unlike RedactText it ignores
+// key=value shapes, which ordinary proposal text can take.
+func Contains(text string) bool {
+	knownMu.RLock()
+	defer knownMu.RUnlock()
+	for value := range known {
+		if strings.Contains(text, value) {
+			return true
+		}
+	}
+	return keyPatterns[0].MatchString(text)
+}
+
+// RedactKeys replaces what Contains looks for: known secrets and OpenAI style keys
+func RedactKeys(text string) string {
+	knownMu.RLock()
+	for value := range known {
+		text = strings.ReplaceAll(text, value, Redacted)
+	}
+	knownMu.RUnlock()
+
+	return keyPatterns[0].ReplaceAllString(text, Redacted)
+}
+
+// RedactText replaces known secrets and anything shaped like an API key in text
+func RedactText(text string) string {
+	text = RedactKeys(text)
+	return keyPatterns[1].ReplaceAllStringFunc(text, func(match string) string {
+		parts := keyPatterns[1].FindStringSubmatch(match)
+		if parts[2] == Redacted || IsReference(parts[2]) || strings.HasPrefix(parts[2], "[") {
+			return match
+		}
+		return parts[1] + Redacted
+	})
+}
+
+// RedactAgent returns a copy of an agent that is safe to return from the API or broadcast
+func RedactAgent(agent core.Agent) core.Agent {
+	agent.Metadata = RedactMetadata(agent.Metadata)
+	return agent
+}
+
+// RedactAgents redacts every agent of a list
+func RedactAgents(agents []core.Agent) []core.Agent {
+	redacted := make([]core.Agent, len(agents))
+	for i, agent := range agents {
+		redacted[i] = RedactAgent(agent)
+	}
+	return redacted
+}
+
+// RedactPersona returns a copy of a persona with its secret settings redacted
+func RedactPersona(persona core.Persona) core.Persona {
+	persona.Settings = RedactMetadata(persona.Settings)
+	return persona
+}
+
+// maxPendingLine bounds how much of an unfinished line the redacting writer holds back
+const maxPendingLine = 64 * 1024
+
+// redactingWriter scrubs secrets from everything written through it. Output is redacted a line at a time, so a
+// secret split across writes is still caught.
+type redactingWriter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	pending []byte
+}
+
+// NewRedactingWriter wraps a log destination so that secrets never reach it
+func NewRedactingWriter(out io.Writer) io.Writer {
+	return &redactingWriter{out: out}
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, p...)
+	end := bytes.LastIndexByte(w.pending, '\n') + 1
+	if end == 0 && len(w.pending) > maxPendingLine {
+		end = len(w.pending)
+	}
+	if end == 0 {
+		return len(p), nil
+	}
+	lines := string(w.pending[:end])
+	w.pending = append(w.pending[:0], w.pending[end:]...)
+	if _, err := io.WriteString(w.out, RedactText(lines)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactingWriter(t *testing.T) {
+	registered := "registered-agent-secret-0123"
+	Register(registered)
+
+	tests := []struct {
+		name   string
+		writes []string
+		secret string
+	}{
+		{"registered secret in one write", []string{"calling model with " + registered + "\n"}, registered},
+		{"registered secret split across writes", []string{"calling model with regist", "ered-agent-secret-0123 done\n"}, registered},
+		{"openai key split across writes", []string{"key sk-abcdefgh", "ijklmnopqrstuvwx loaded\n"}, "sk-abcdefghijklmnopqrstuvwx"},
+		{"key value pair split across writes", []string{`config {"api_key": "hunter2-`, `hunter2-hunter2"}` + "\n"}, "hunter2-hunter2-hunter2"},
+		{"secret split over many writes", strings.SplitAfter("with "+registered+"\n", ""), registered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			w := NewRedactingWriter(&out)
+			for _, chunk := range tt.writes {
+				n, err := w.Write([]byte(chunk))
+				if err != nil || n != len(chunk) {
+					t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(chunk))
+				}
+			}
+
+			if strings.Contains(out.String(), tt.secret) {
+				t.Errorf("secret reached the output: %q", out.String())
+			}
+			if !strings.Contains(out.String(), Redacted) {
+				t.Errorf("output was not redacted: %q", out.String())
+			}
+			if !strings.HasSuffix(out.String(), "\n") {
+				t.Errorf("the line was not written out whole: %q", out.String())
+			}
+		})
+	}
+}
+
+func TestRedactingWriterHoldsUnfinishedLines(t *testing.T) {
+	var out strings.Builder
+	w := NewRedactingWriter(&out)
+
+	w.Write([]byte("first line\nsecond "))
+	if out.String() != "first line\n" {
+		t.Fatalf("output = %q, want only the finished line", out.String())
+	}
+	w.Write([]byte("line\n"))
+	if out.String() != "first line\nsecond line\n" {
+		t.Errorf("output = %q, want both lines", out.String())
+	}
+}
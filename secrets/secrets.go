@@ -0,0 +1,228 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Secret values in agent metadata are stored in one of these forms. Plaintext values are sealed before they are
+// persisted, so only references and ciphertext reach disk.
+const (
+	EnvPrefix       = "env:"  // env:NAME reads the environment variable NAME
+	FilePrefix      = "file:" // file:path reads a key file, file:path#name one entry of a JSON keyring
+	EncryptedPrefix = "enc:"  // enc:... is AES-GCM ciphertext under the master key
+)
+
+// Redacted replaces secret values in API responses, events and logs
+const Redacted = "[REDACTED]"
+
+// MasterKeyEnv holds a base64 encoded 32 byte master key. Without it a key is generated under MasterKeyFile.
+const MasterKeyEnv = "SECRETS_MASTER_KEY"
+
+var (
+	MasterKeyFile = filepath.Join("data", "secrets", "master.key")
+
+	masterMu  sync.Mutex
+	masterKey []byte
+)
+
+// secretKeys are metadata keys, or key suffixes, whose values are secrets
+var secretKeys = []string{"api_key", "apikey", "secret", "token", "password", "private_key"}
+
+// IsSecretKey reports whether a metadata key holds a secret
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if key == secret || strings.HasSuffix(key, "_"+secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReference reports whether a value points to a secret kept elsewhere instead of holding it
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, EnvPrefix) || strings.HasPrefix(value, FilePrefix)
+}
+
+// Seal encrypts a plaintext secret under the master key. References and sealed values are returned unchanged.
+func Seal(value string) (string, error) {
+	if value == "" || IsReference(value) || strings.HasPrefix(value, EncryptedPrefix) {
+		return value, nil
+	}
+	gcm, err := masterCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	Register(value)
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Resolve returns the plaintext of a secret value, reading references and decrypting sealed values
+func Resolve(value string) (string, error) {
+	var plaintext string
+	switch {
+	case strings.HasPrefix(value, EnvPrefix):
+		name := strings.TrimPrefix(value, EnvPrefix)
+		plaintext = os.Getenv(name)
+		if plaintext == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+	case strings.HasPrefix(value, FilePrefix):
+		var err error
+		if plaintext, err = readKeyFile(strings.TrimPrefix(value, FilePrefix)); err != nil {
+			return "", err
+		}
+	case strings.HasPrefix(value, EncryptedPrefix):
+		gcm, err := masterCipher()
+		if err != nil {
+			return "", err
+		}
+		sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPrefix))
+		if err != nil || len(sealed) < gcm.NonceSize() {
+			return "", errors.New("malformed sealed secret")
+		}
+		opened, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+		if err != nil {
+			return "", errors.New("failed to decrypt secret, the master key may have changed")
+		}
+		plaintext = string(opened)
+	default:
+		plaintext = value
+	}
+	Register(plaintext)
+	return plaintext, nil
+}
+
+// readKeyFile reads a key file, or one named entry of a JSON keyring when the path ends in #name
+func readKeyFile(ref string) (string, error) {
+	path, name, keyring := strings.Cut(ref, "#")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key file %s: %v", path, err)
+	}
+	if !keyring {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	var keys map[string]string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return "", fmt.Errorf("failed to parse keyring %s: %v", path, err)
+	}
+	key, ok := keys[name]
+	if !ok || key == "" {
+		return "", fmt.Errorf("keyring %s has no key %s", path, name)
+	}
+	return key, nil
+}
+
+// masterCipher loads the master key from the environment or the key file, generating the file on first use
+func masterCipher() (cipher.AEAD, error) {
+	masterMu.Lock()
+	defer masterMu.Unlock()
+
+	if masterKey == nil {
+		key, err := loadMasterKey()
+		if err != nil {
+			return nil, err
+		}
+		masterKey = key
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func loadMasterKey() ([]byte, error) {
+	encoded := os.Getenv(MasterKeyEnv)
+	if encoded == "" {
+		data, err := os.ReadFile(MasterKeyFile)
+		if err == nil {
+			encoded = strings.TrimSpace(string(data))
+		} else if os.IsNotExist(err) {
+			return generateMasterKey()
+		} else {
+			return nil, fmt.Errorf("failed to read master key: %v", err)
+		}
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("master key must be 32 bytes, base64 encoded")
+	}
+	return key, nil
+}
+
+func generateMasterKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate master key: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(MasterKeyFile), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create secrets directory: %v", err)
+	}
+	if err := os.WriteFile(MasterKeyFile, []byte(base64.StdEncoding.EncodeToString(key)), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write master key: %v", err)
+	}
+	return key, nil
+}
+
+// SealMetadata seals the plaintext secrets of an agent's metadata in place, including those nested in settings
+func SealMetadata(metadata map[string]interface{}) error {
+	var errs []error
+	for key, value := range metadata {
+		switch v := value.(type) {
+		case string:
+			if IsSecretKey(key) {
+				sealed, err := Seal(v)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %v", key, err))
+					continue
+				}
+				metadata[key] = sealed
+			}
+		case map[string]interface{}:
+			if err := SealMetadata(v); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RedactMetadata returns a copy of metadata with secret values replaced. References are kept, since they name
+// where a key lives without revealing it.
+func RedactMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		switch v := value.(type) {
+		case string:
+			if IsSecretKey(key) && v != "" && !IsReference(v) {
+				value = Redacted
+			}
+		case map[string]interface{}:
+			value = RedactMetadata(v)
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
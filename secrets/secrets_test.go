@@ -0,0 +1,126 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useMasterKey seals and resolves secrets under a fresh master key for the rest of the test
+func useMasterKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate master key: %v", err)
+	}
+	masterMu.Lock()
+	previous := masterKey
+	masterKey = key
+	masterMu.Unlock()
+	t.Cleanup(func() {
+		masterMu.Lock()
+		masterKey = previous
+		masterMu.Unlock()
+	})
+	return key
+}
+
+func TestSealResolveRoundTrip(t *testing.T) {
+	useMasterKey(t)
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "openai.key")
+	keyring := filepath.Join(dir, "keyring.json")
+	if err := os.WriteFile(keyFile, []byte("sk-from-a-key-file-0123456789\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyring, []byte(`{"reviewer": "sk-from-a-keyring-0123456789"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_AGENT_KEY", "sk-from-the-environment-0123456789")
+
+	tests := []struct {
+		name   string
+		value  string
+		want   string
+		sealed bool // whether Seal encrypts the value rather than keeping it
+	}{
+		{"plaintext key", "sk-plaintext-0123456789abcdef", "sk-plaintext-0123456789abcdef", true},
+		{"unicode secret", "pässwörd-🔑-long-enough", "pässwörd-🔑-long-enough", true},
+		{"environment reference", "env:TEST_AGENT_KEY", "sk-from-the-environment-0123456789", false},
+		{"key file reference", "file:" + keyFile, "sk-from-a-key-file-0123456789", false},
+		{"keyring reference", "file:" + keyring + "#reviewer", "sk-from-a-keyring-0123456789", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := Seal(tt.value)
+			if err != nil {
+				t.Fatalf("Seal() error = %v", err)
+			}
+			if tt.sealed {
+				if !strings.HasPrefix(sealed, EncryptedPrefix) || strings.Contains(sealed, tt.value) {
+					t.Errorf("Seal() = %q, want ciphertext", sealed)
+				}
+			} else if sealed != tt.value {
+				t.Errorf("Seal() = %q, want the value unchanged", sealed)
+			}
+			if again, _ := Seal(sealed); again != sealed {
+				t.Errorf("sealing a sealed value changed it")
+			}
+
+			resolved, err := Resolve(sealed)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if resolved != tt.want {
+				t.Errorf("Resolve() = %q, want %q", resolved, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRejectsUnusableSecrets(t *testing.T) {
+	useMasterKey(t)
+	sealed, err := Seal("sk-plaintext-0123456789abcdef")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	ciphertext, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, EncryptedPrefix))
+	tampered := append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 0x01
+	keyring := filepath.Join(t.TempDir(), "keyring.json")
+	if err := os.WriteFile(keyring, []byte(`{"reviewer": "sk-from-a-keyring-0123456789"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		rotate  bool // resolve under another master key
+		wantErr string
+	}{
+		{"wrong master key", sealed, true, "failed to decrypt"},
+		{"tampered ciphertext", EncryptedPrefix + base64.StdEncoding.EncodeToString(tampered), false, "failed to decrypt"},
+		{"truncated ciphertext", EncryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext[:4]), false, "malformed"},
+		{"not base64", EncryptedPrefix + "not base64!", false, "malformed"},
+		{"unset environment variable", "env:TEST_UNSET_AGENT_KEY", false, "not set"},
+		{"missing key file", "file:" + filepath.Join(t.TempDir(), "missing.key"), false, "failed to read"},
+		{"missing keyring entry", "file:" + keyring + "#lender", false, "has no key lender"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.rotate {
+				useMasterKey(t)
+			}
+			resolved, err := Resolve(tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Resolve() = %q, %v, want an error containing %q", resolved, err, tt.wantErr)
+			}
+		})
+	}
+}
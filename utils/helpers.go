@@ -7,6 +7,8 @@ import (
 	"os"
 	"strconv"
 	"time"
+
+	"github.com/Deeptanshu-sankhwar/agentic_consensus/secrets"
 )
 
 // FileExists returns true if the specified file exists
@@ -54,7 +56,7 @@ func LogDiscussion(agentName, message, chainID string, isProposer bool) {
 	}
 	defer f.Close()
 
-	if _, err := f.WriteString(logEntry); err != nil {
+	if _, err := f.WriteString(secrets.RedactText(logEntry)); err != nil {
 		log.Printf("Failed to write to log file: %v", err)
 	}
 }
@@ -133,7 +135,7 @@ func AppendDiscussionLog(chainID, message string) {
 	}
 	defer f.Close()
 
-	if _, err := f.WriteString(message + "\n"); err != nil {
+	if _, err := f.WriteString(secrets.RedactText(message) + "\n"); err != nil {
 		log.Printf("Warning: Failed to append to discussion log: %v", err)
 	}
 }